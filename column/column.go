@@ -1,5 +1,7 @@
 package column
 
+import "github.com/caravan/db/value"
+
 type (
 	// Name identifies a Column
 	Name string
@@ -14,6 +16,18 @@ type (
 		Name() Name
 	}
 
+	// Typed is a Column whose Values store the Native Go type T. A Table
+	// refuses to store Values of any other type in a Typed Column
+	Typed[T value.Native] struct {
+		name Name
+	}
+
+	// Type names the kind of Value that a Typed Column stores
+	Type string
+
+	// Types associates the Names of Typed Columns with their Types
+	Types map[Name]Type
+
 	// Columns are a set of Column
 	Columns []Column
 
@@ -32,6 +46,18 @@ type (
 	}
 )
 
+// Types
+const (
+	// AnyType is the Type of a Column that may store any Value
+	AnyType Type = ""
+
+	BytesType   Type = "bytes"
+	BoolType    Type = "bool"
+	StringType  Type = "string"
+	IntegerType Type = "integer"
+	FloatType   Type = "float"
+)
+
 // Make instantiates a new column instance
 func Make(n Name) Column {
 	return &column{
@@ -43,6 +69,99 @@ func (c *column) Name() Name {
 	return c.name
 }
 
+// Of instantiates a new Column whose Values store the Go type T
+func Of[T value.Native](n Name) Typed[T] {
+	return Typed[T]{name: n}
+}
+
+// Name returns the name of the Typed Column
+func (c Typed[T]) Name() Name {
+	return c.name
+}
+
+// Type returns the Type of the Values that the Typed Column stores
+func (c Typed[T]) Type() Type {
+	var zero T
+	switch any(zero).(type) {
+	case []byte:
+		return BytesType
+	case bool:
+		return BoolType
+	case string:
+		return StringType
+	case int64:
+		return IntegerType
+	default:
+		return FloatType
+	}
+}
+
+// TypeOf returns the Type of a Column, which is AnyType unless the Column
+// is Typed
+func TypeOf(c Column) Type {
+	if t, ok := c.(interface{ Type() Type }); ok {
+		return t.Type()
+	}
+	return AnyType
+}
+
+// MakeTyped instantiates a Column of the provided Type
+func MakeTyped(n Name, t Type) Column {
+	switch t {
+	case BytesType:
+		return Of[[]byte](n)
+	case BoolType:
+		return Of[bool](n)
+	case StringType:
+		return Of[string](n)
+	case IntegerType:
+		return Of[int64](n)
+	case FloatType:
+		return Of[float64](n)
+	default:
+		return Make(n)
+	}
+}
+
+// Accepts returns whether a Column of this Type can store the Value. Any
+// Column can store nil
+func (t Type) Accepts(v value.Value) bool {
+	switch v.(type) {
+	case nil:
+		return true
+	case value.Key:
+		return t == AnyType || t == BytesType
+	case value.Bool:
+		return t == AnyType || t == BoolType
+	case value.String:
+		return t == AnyType || t == StringType
+	case value.Integer:
+		return t == AnyType || t == IntegerType
+	case value.Float:
+		return t == AnyType || t == FloatType
+	default:
+		return t == AnyType
+	}
+}
+
+// With returns a copy of the Types in which the named Column has the
+// provided Type. Assigning AnyType removes the Column
+func (t Types) With(n Name, typ Type) Types {
+	res := make(Types, len(t)+1)
+	for k, v := range t {
+		res[k] = v
+	}
+	if typ == AnyType {
+		delete(res, n)
+	} else {
+		res[n] = typ
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
 // MakeNamedOffsets takes a set of Columns and returns its NamedOffsets
 func MakeNamedOffsets(cols ...Column) NamedOffsets {
 	res := make(NamedOffsets, len(cols))
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/caravan/db/column"
+	"github.com/caravan/db/value"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,3 +31,27 @@ func TestMakeNamedOffsets(t *testing.T) {
 	as.Equal(column.Offset(1), off["second"])
 	as.Equal(column.Offset(0), off["first"])
 }
+
+func TestTypedColumn(t *testing.T) {
+	as := assert.New(t)
+
+	age := column.Of[int64]("age")
+	as.Equal(column.Name("age"), age.Name())
+
+	off := column.MakeNamedOffsets(column.Make("name"), age)
+	as.Equal(column.Offset(1), off["age"])
+
+	as.Equal(column.IntegerType, age.Type())
+	as.Equal(column.AnyType, column.TypeOf(column.Make("name")))
+	as.Equal(column.StringType,
+		column.TypeOf(column.MakeTyped("name", column.StringType)),
+	)
+	as.True(column.IntegerType.Accepts(value.Integer(42)))
+	as.True(column.IntegerType.Accepts(nil))
+	as.False(column.IntegerType.Accepts(value.Float(4.2)))
+	as.True(column.AnyType.Accepts(value.Float(4.2)))
+
+	types := column.Types{}.With("age", column.IntegerType)
+	as.Equal(column.Types{"age": column.IntegerType}, types)
+	as.Nil(types.With("age", column.AnyType))
+}
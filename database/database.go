@@ -18,5 +18,7 @@ type (
 		Tables() table.Names
 		Table(table.Name) (table.Table, bool)
 		CreateTable(table.Name, ...column.Column) (table.Table, error)
+		Schema() Schema
+		Version() uint64
 	}
 )
@@ -0,0 +1,17 @@
+package database
+
+import (
+	"encoding/json"
+
+	"github.com/caravan/db/table"
+)
+
+// Schema is the serializable description of a Database's Tables
+type Schema struct {
+	Tables table.Definitions `json:"tables"`
+}
+
+// JSON renders the Schema as indented JSON
+func (s Schema) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
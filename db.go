@@ -10,10 +10,22 @@ func NewDatabase() database.Transactor {
 	return internal.NewDatabase()
 }
 
-var (
-	// UniqueIndex is an index.Type that allows only unique associations
+const (
+	// UniqueIndex names the index.Type that allows only unique associations
 	UniqueIndex = internal.UniqueIndex
 
-	// StandardIndex is an index.Type that allows multiple associations
+	// StandardIndex names the index.Type that allows multiple associations
 	StandardIndex = internal.StandardIndex
+
+	// FullTextIndex names the index.Type that searches the text of its
+	// Columns
+	FullTextIndex = internal.FullTextIndex
+
+	// GeoIndex names the index.Type that finds the rows whose Point Column
+	// lies within an area or near a location
+	GeoIndex = internal.GeoIndex
+
+	// VectorIndex names the index.Type that finds the rows whose Vector
+	// Column is nearest to a query Vector
+	VectorIndex = internal.VectorIndex
 )
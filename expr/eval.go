@@ -0,0 +1,237 @@
+package expr
+
+import (
+	"fmt"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Evaluator computes the Value of a compiled Expr for a Row. A nil
+	// Value represents null
+	Evaluator func(relation.Row) (value.Value, error)
+
+	// Predicate reports whether a compiled Expr is true for a Row
+	Predicate func(relation.Row) (bool, error)
+)
+
+// Error messages
+const (
+	ErrUnknownOp     = "unknown expression op: %s"
+	ErrArgumentCount = "wrong number of arguments: %s"
+	ErrIncomparable  = "values are not comparable: %s"
+	ErrNotBoolean    = "expression is not boolean: %s"
+)
+
+var comparisons = map[Op]func(value.Comparison) bool{
+	EQOp:  func(c value.Comparison) bool { return c == value.EqualTo },
+	NEQOp: func(c value.Comparison) bool { return c != value.EqualTo },
+	LTOp:  func(c value.Comparison) bool { return c == value.LessThan },
+	LTEOp: func(c value.Comparison) bool { return c != value.GreaterThan },
+	GTOp:  func(c value.Comparison) bool { return c == value.GreaterThan },
+	GTEOp: func(c value.Comparison) bool { return c != value.LessThan },
+}
+
+// Compile resolves the Expr's Column references against the provided
+// Columns and returns an Evaluator for it
+func Compile(e *Expr, cols column.Columns) (Evaluator, error) {
+	return compile(e, column.MakeNamedOffsets(cols...))
+}
+
+// CompilePredicate compiles the Expr into a Predicate. The Predicate is
+// only satisfied when the Expr evaluates to true, so a null result does
+// not satisfy it
+func CompilePredicate(e *Expr, cols column.Columns) (Predicate, error) {
+	eval, err := Compile(e, cols)
+	if err != nil {
+		return nil, err
+	}
+	return func(r relation.Row) (bool, error) {
+		v, err := eval(r)
+		if err != nil {
+			return false, err
+		}
+		return isTrue(e, v)
+	}, nil
+}
+
+func compile(e *Expr, off column.NamedOffsets) (Evaluator, error) {
+	switch e.Op {
+	case ColumnOp:
+		return compileColumn(e, off)
+	case LiteralOp:
+		return compileLiteral(e)
+	case CallOp:
+		return compileCall(e, off)
+	case AndOp, OrOp:
+		return compileLogical(e, off)
+	case NotOp:
+		return compileNot(e, off)
+	case IsNullOp:
+		return compileIsNull(e, off)
+	default:
+		if _, ok := comparisons[e.Op]; ok {
+			return compileComparison(e, off)
+		}
+		return nil, fmt.Errorf(ErrUnknownOp, e.Op)
+	}
+}
+
+func compileArgs(e *Expr, off column.NamedOffsets) ([]Evaluator, error) {
+	res := make([]Evaluator, len(e.Args))
+	for i, a := range e.Args {
+		eval, err := compile(a, off)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = eval
+	}
+	return res, nil
+}
+
+func compileColumn(e *Expr, off column.NamedOffsets) (Evaluator, error) {
+	o, ok := off[e.Column]
+	if !ok {
+		return nil, fmt.Errorf(relation.ErrColumnNotFound, e.Column)
+	}
+	return func(r relation.Row) (value.Value, error) {
+		return r[o], nil
+	}, nil
+}
+
+func compileLiteral(e *Expr) (Evaluator, error) {
+	var v value.Value
+	if e.Value != nil {
+		v = e.Value.Value
+	}
+	return func(relation.Row) (value.Value, error) {
+		return v, nil
+	}, nil
+}
+
+func compileCall(e *Expr, off column.NamedOffsets) (Evaluator, error) {
+	fn, ok := LookupFunc(e.Func)
+	if !ok {
+		return nil, fmt.Errorf(ErrFuncNotRegistered, e.Func)
+	}
+	args, err := compileArgs(e, off)
+	if err != nil {
+		return nil, err
+	}
+	return func(r relation.Row) (value.Value, error) {
+		in := make([]value.Value, len(args))
+		for i, a := range args {
+			v, err := a(r)
+			if err != nil {
+				return nil, err
+			}
+			in[i] = v
+		}
+		return fn(in...)
+	}, nil
+}
+
+func compileComparison(e *Expr, off column.NamedOffsets) (Evaluator, error) {
+	if len(e.Args) != 2 {
+		return nil, fmt.Errorf(ErrArgumentCount, e)
+	}
+	args, err := compileArgs(e, off)
+	if err != nil {
+		return nil, err
+	}
+	test := comparisons[e.Op]
+	return func(r relation.Row) (value.Value, error) {
+		l, err := args[0](r)
+		if err != nil {
+			return nil, err
+		}
+		rv, err := args[1](r)
+		if err != nil || l == nil || rv == nil {
+			return nil, err
+		}
+		c := l.Compare(rv)
+		if c == value.Incomparable {
+			return nil, fmt.Errorf(ErrIncomparable, e)
+		}
+		return value.Bool(test(c)), nil
+	}, nil
+}
+
+// compileLogical implements three-valued AND and OR, where a null operand
+// yields null unless another operand decides the result
+func compileLogical(e *Expr, off column.NamedOffsets) (Evaluator, error) {
+	args, err := compileArgs(e, off)
+	if err != nil {
+		return nil, err
+	}
+	decisive := value.Bool(e.Op == OrOp)
+	return func(r relation.Row) (value.Value, error) {
+		var res value.Value = !decisive
+		for i, a := range args {
+			v, err := a(r)
+			if err != nil {
+				return nil, err
+			}
+			switch v {
+			case nil:
+				res = nil
+			case decisive:
+				return decisive, nil
+			case !decisive:
+			default:
+				return nil, fmt.Errorf(ErrNotBoolean, e.Args[i])
+			}
+		}
+		return res, nil
+	}, nil
+}
+
+func compileNot(e *Expr, off column.NamedOffsets) (Evaluator, error) {
+	if len(e.Args) != 1 {
+		return nil, fmt.Errorf(ErrArgumentCount, e)
+	}
+	arg, err := compile(e.Args[0], off)
+	if err != nil {
+		return nil, err
+	}
+	return func(r relation.Row) (value.Value, error) {
+		v, err := arg(r)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		if b, ok := v.(value.Bool); ok {
+			return !b, nil
+		}
+		return nil, fmt.Errorf(ErrNotBoolean, e.Args[0])
+	}, nil
+}
+
+func compileIsNull(e *Expr, off column.NamedOffsets) (Evaluator, error) {
+	if len(e.Args) != 1 {
+		return nil, fmt.Errorf(ErrArgumentCount, e)
+	}
+	arg, err := compile(e.Args[0], off)
+	if err != nil {
+		return nil, err
+	}
+	return func(r relation.Row) (value.Value, error) {
+		v, err := arg(r)
+		if err != nil {
+			return nil, err
+		}
+		return value.Bool(v == nil), nil
+	}, nil
+}
+
+func isTrue(e *Expr, v value.Value) (bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case value.Bool:
+		return bool(v), nil
+	default:
+		return false, fmt.Errorf(ErrNotBoolean, e)
+	}
+}
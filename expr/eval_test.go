@@ -0,0 +1,172 @@
+package expr_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	evalColumns = column.Columns{
+		column.Make("name"),
+		column.Make("price"),
+		column.Make("discount"),
+	}
+	evalRow = relation.Row{
+		value.String("Widget"), value.Integer(10), nil,
+	}
+)
+
+func evaluate(e *expr.Expr) (value.Value, error) {
+	eval, err := expr.Compile(e, evalColumns)
+	if err != nil {
+		return nil, err
+	}
+	return eval(evalRow)
+}
+
+func TestCompare(t *testing.T) {
+	as := assert.New(t)
+
+	price := expr.Col("price")
+	ten := expr.Lit(value.Integer(10))
+	for _, tc := range []struct {
+		expr *expr.Expr
+		res  value.Value
+	}{
+		{expr.EQ(price, ten), value.Bool(true)},
+		{expr.NEQ(price, ten), value.Bool(false)},
+		{expr.LT(price, ten), value.Bool(false)},
+		{expr.LTE(price, ten), value.Bool(true)},
+		{expr.GT(price, ten), value.Bool(false)},
+		{expr.GTE(price, ten), value.Bool(true)},
+		{expr.EQ(expr.Col("discount"), ten), nil},
+		{expr.IsNull(expr.Col("discount")), value.Bool(true)},
+		{expr.Not(expr.EQ(price, ten)), value.Bool(false)},
+		{expr.Not(expr.EQ(expr.Col("discount"), ten)), nil},
+	} {
+		res, err := evaluate(tc.expr)
+		as.Nil(err)
+		as.Equal(tc.res, res, tc.expr.String())
+	}
+
+	_, err := evaluate(expr.EQ(price, expr.Lit(value.String("10"))))
+	as.EqualError(err, fmt.Sprintf(expr.ErrIncomparable, `price = "10"`))
+}
+
+func TestLogical(t *testing.T) {
+	as := assert.New(t)
+
+	yes := expr.Lit(value.Bool(true))
+	no := expr.Lit(value.Bool(false))
+	null := expr.Lit(nil)
+	for _, tc := range []struct {
+		expr *expr.Expr
+		res  value.Value
+	}{
+		{expr.And(yes, yes), value.Bool(true)},
+		{expr.And(yes, no), value.Bool(false)},
+		{expr.And(yes, null), nil},
+		{expr.And(null, no), value.Bool(false)},
+		{expr.Or(no, no), value.Bool(false)},
+		{expr.Or(no, yes), value.Bool(true)},
+		{expr.Or(no, null), nil},
+		{expr.Or(null, yes), value.Bool(true)},
+	} {
+		res, err := evaluate(tc.expr)
+		as.Nil(err)
+		as.Equal(tc.res, res, tc.expr.String())
+	}
+
+	_, err := evaluate(expr.And(yes, expr.Col("price")))
+	as.EqualError(err, fmt.Sprintf(expr.ErrNotBoolean, "price"))
+	_, err = evaluate(expr.Not(expr.Col("price")))
+	as.EqualError(err, fmt.Sprintf(expr.ErrNotBoolean, "price"))
+}
+
+func TestCall(t *testing.T) {
+	as := assert.New(t)
+
+	res, err := evaluate(expr.Call("lower", expr.Col("name")))
+	as.Nil(err)
+	as.Equal(value.String("widget"), res)
+
+	res, err = evaluate(expr.Call("upper", expr.Col("name")))
+	as.Nil(err)
+	as.Equal(value.String("WIDGET"), res)
+
+	res, err = evaluate(expr.Call("length", expr.Col("name")))
+	as.Nil(err)
+	as.Equal(value.Integer(6), res)
+
+	res, err = evaluate(expr.Call("abs", expr.Lit(value.Integer(-3))))
+	as.Nil(err)
+	as.Equal(value.Integer(3), res)
+
+	res, err = evaluate(expr.Call("abs", expr.Lit(value.Float(-3.5))))
+	as.Nil(err)
+	as.Equal(value.Float(3.5), res)
+
+	res, err = evaluate(expr.Call("lower", expr.Col("discount")))
+	as.Nil(err)
+	as.Nil(res)
+
+	_, err = evaluate(expr.Call("lower", expr.Col("price")))
+	as.EqualError(err, fmt.Sprintf(expr.ErrBadArguments, "lower"))
+
+	_, err = evaluate(expr.Call("missing"))
+	as.EqualError(err, fmt.Sprintf(expr.ErrFuncNotRegistered, "missing"))
+
+	as.Nil(expr.RegisterFunc("fail", func(...value.Value) (value.Value, error) {
+		return nil, errors.New("failed")
+	}))
+	err = expr.RegisterFunc("fail", nil)
+	as.EqualError(err, fmt.Sprintf(expr.ErrFuncAlreadyRegistered, "fail"))
+
+	_, err = evaluate(expr.Call("fail"))
+	as.EqualError(err, "failed")
+}
+
+func TestCompileErrors(t *testing.T) {
+	as := assert.New(t)
+
+	_, err := evaluate(expr.Col("missing"))
+	as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+	_, err = evaluate(&expr.Expr{Op: "explode"})
+	as.EqualError(err, fmt.Sprintf(expr.ErrUnknownOp, "explode"))
+
+	_, err = evaluate(&expr.Expr{Op: expr.EQOp})
+	as.EqualError(err, fmt.Sprintf(expr.ErrArgumentCount, "eq()"))
+}
+
+func TestCompilePredicate(t *testing.T) {
+	as := assert.New(t)
+
+	pred, err := expr.CompilePredicate(
+		expr.GT(expr.Col("price"), expr.Lit(value.Integer(5))), evalColumns,
+	)
+	as.Nil(err)
+	ok, err := pred(evalRow)
+	as.True(ok)
+	as.Nil(err)
+
+	pred, err = expr.CompilePredicate(
+		expr.GT(expr.Col("discount"), expr.Lit(value.Integer(5))), evalColumns,
+	)
+	as.Nil(err)
+	ok, err = pred(evalRow)
+	as.False(ok)
+	as.Nil(err)
+
+	pred, err = expr.CompilePredicate(expr.Col("price"), evalColumns)
+	as.Nil(err)
+	_, err = pred(evalRow)
+	as.EqualError(err, fmt.Sprintf(expr.ErrNotBoolean, "price"))
+}
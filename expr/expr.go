@@ -0,0 +1,191 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Op identifies the operation performed by an Expr
+	Op string
+
+	// Expr is a serializable expression that can be evaluated against a
+	// Row. Which of its fields are used depends on its Op
+	Expr struct {
+		Op     Op          `json:"op"`
+		Column column.Name `json:"column,omitempty"`
+		Value  *Literal    `json:"value,omitempty"`
+		Func   FuncName    `json:"func,omitempty"`
+		Args   []*Expr     `json:"args,omitempty"`
+	}
+)
+
+// Expression operations
+const (
+	ColumnOp  Op = "column"
+	LiteralOp Op = "literal"
+	CallOp    Op = "call"
+	EQOp      Op = "eq"
+	NEQOp     Op = "neq"
+	LTOp      Op = "lt"
+	LTEOp     Op = "lte"
+	GTOp      Op = "gt"
+	GTEOp     Op = "gte"
+	AndOp     Op = "and"
+	OrOp      Op = "or"
+	NotOp     Op = "not"
+	IsNullOp  Op = "isNull"
+)
+
+var opSymbols = map[Op]string{
+	EQOp:  "=",
+	NEQOp: "<>",
+	LTOp:  "<",
+	LTEOp: "<=",
+	GTOp:  ">",
+	GTEOp: ">=",
+	AndOp: "AND",
+	OrOp:  "OR",
+}
+
+// Col returns an Expr that evaluates to the value of the named Column
+func Col(n column.Name) *Expr {
+	return &Expr{Op: ColumnOp, Column: n}
+}
+
+// Lit returns an Expr that evaluates to the provided Value
+func Lit(v value.Value) *Expr {
+	return &Expr{Op: LiteralOp, Value: &Literal{Value: v}}
+}
+
+// Call returns an Expr that invokes a registered Func
+func Call(n FuncName, args ...*Expr) *Expr {
+	return &Expr{Op: CallOp, Func: n, Args: args}
+}
+
+// EQ returns an Expr that tests whether two Exprs are equal
+func EQ(l, r *Expr) *Expr {
+	return binary(EQOp, l, r)
+}
+
+// NEQ returns an Expr that tests whether two Exprs are not equal
+func NEQ(l, r *Expr) *Expr {
+	return binary(NEQOp, l, r)
+}
+
+// LT returns an Expr that tests whether one Expr is less than another
+func LT(l, r *Expr) *Expr {
+	return binary(LTOp, l, r)
+}
+
+// LTE returns an Expr that tests whether one Expr is less than or equal to
+// another
+func LTE(l, r *Expr) *Expr {
+	return binary(LTEOp, l, r)
+}
+
+// GT returns an Expr that tests whether one Expr is greater than another
+func GT(l, r *Expr) *Expr {
+	return binary(GTOp, l, r)
+}
+
+// GTE returns an Expr that tests whether one Expr is greater than or equal
+// to another
+func GTE(l, r *Expr) *Expr {
+	return binary(GTEOp, l, r)
+}
+
+// And returns an Expr that is true if all of the provided Exprs are true
+func And(e ...*Expr) *Expr {
+	return &Expr{Op: AndOp, Args: e}
+}
+
+// Or returns an Expr that is true if any of the provided Exprs are true
+func Or(e ...*Expr) *Expr {
+	return &Expr{Op: OrOp, Args: e}
+}
+
+// Not returns an Expr that negates the provided Expr
+func Not(e *Expr) *Expr {
+	return &Expr{Op: NotOp, Args: []*Expr{e}}
+}
+
+// IsNull returns an Expr that tests whether the provided Expr is nil
+func IsNull(e *Expr) *Expr {
+	return &Expr{Op: IsNullOp, Args: []*Expr{e}}
+}
+
+func binary(op Op, l, r *Expr) *Expr {
+	return &Expr{Op: op, Args: []*Expr{l, r}}
+}
+
+// Columns returns the distinct Names of the Columns that the Expr refers
+// to, in the order they are first encountered
+func (e *Expr) Columns() column.Names {
+	var res column.Names
+	seen := map[column.Name]bool{}
+	e.walk(func(e *Expr) {
+		if e.Op == ColumnOp && !seen[e.Column] {
+			seen[e.Column] = true
+			res = append(res, e.Column)
+		}
+	})
+	return res
+}
+
+// RenameColumn returns a copy of the Expr in which references to one
+// Column have been replaced with references to another
+func (e *Expr) RenameColumn(from, to column.Name) *Expr {
+	res := *e
+	if res.Op == ColumnOp && res.Column == from {
+		res.Column = to
+	}
+	if e.Args != nil {
+		res.Args = make([]*Expr, len(e.Args))
+		for i, a := range e.Args {
+			res.Args[i] = a.RenameColumn(from, to)
+		}
+	}
+	return &res
+}
+
+func (e *Expr) walk(fn func(*Expr)) {
+	fn(e)
+	for _, a := range e.Args {
+		a.walk(fn)
+	}
+}
+
+// String returns a human-readable representation of the Expr
+func (e *Expr) String() string {
+	switch e.Op {
+	case ColumnOp:
+		return string(e.Column)
+	case LiteralOp:
+		return e.Value.String()
+	case CallOp:
+		return fmt.Sprintf("%s(%s)", e.Func, joinArgs(e.Args, ", "))
+	case NotOp:
+		return fmt.Sprintf("NOT %s", e.Args[0])
+	case IsNullOp:
+		return fmt.Sprintf("%s IS NULL", e.Args[0])
+	case AndOp, OrOp:
+		return fmt.Sprintf("(%s)", joinArgs(e.Args, " "+opSymbols[e.Op]+" "))
+	default:
+		if sym, ok := opSymbols[e.Op]; ok && len(e.Args) == 2 {
+			return fmt.Sprintf("%s %s %s", e.Args[0], sym, e.Args[1])
+		}
+		return fmt.Sprintf("%s(%s)", e.Op, joinArgs(e.Args, ", "))
+	}
+}
+
+func joinArgs(args []*Expr, sep string) string {
+	res := make([]string, len(args))
+	for i, a := range args {
+		res[i] = a.String()
+	}
+	return strings.Join(res, sep)
+}
@@ -0,0 +1,73 @@
+package expr_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestString(t *testing.T) {
+	as := assert.New(t)
+
+	e := expr.And(
+		expr.GTE(expr.Col("price"), expr.Lit(value.Integer(0))),
+		expr.Not(expr.IsNull(expr.Col("name"))),
+		expr.EQ(
+			expr.Call("lower", expr.Col("name")),
+			expr.Lit(value.String("widget")),
+		),
+	)
+	as.Equal(
+		`(price >= 0 AND NOT name IS NULL AND lower(name) = "widget")`,
+		e.String(),
+	)
+	as.Equal("NULL", expr.Lit(nil).String())
+}
+
+func TestColumns(t *testing.T) {
+	as := assert.New(t)
+
+	e := expr.Or(
+		expr.GT(expr.Col("end"), expr.Col("start")),
+		expr.IsNull(expr.Col("end")),
+	)
+	as.Equal(column.Names{"end", "start"}, e.Columns())
+
+	r := e.RenameColumn("end", "finish")
+	as.Equal(column.Names{"finish", "start"}, r.Columns())
+	as.Equal(column.Names{"end", "start"}, e.Columns())
+}
+
+func TestJSON(t *testing.T) {
+	as := assert.New(t)
+
+	e := expr.And(
+		expr.EQ(expr.Col("b"), expr.Lit(value.Bool(true))),
+		expr.EQ(expr.Col("s"), expr.Lit(value.String("str"))),
+		expr.EQ(expr.Col("i"), expr.Lit(value.Integer(42))),
+		expr.EQ(expr.Col("f"), expr.Lit(value.Float(9.5))),
+		expr.EQ(expr.Col("k"), expr.Lit(value.Key{1, 2, 3})),
+		expr.EQ(expr.Col("p"), expr.Lit(value.Point{Lat: 51.5, Lon: -0.1})),
+		expr.EQ(expr.Col("v"), expr.Lit(value.Vector{0.5, -1, 2})),
+		expr.EQ(expr.Col("n"), expr.Lit(nil)),
+	)
+	b, err := json.Marshal(e)
+	as.Nil(err)
+
+	var res *expr.Expr
+	as.Nil(json.Unmarshal(b, &res))
+	as.Equal(e, res)
+
+	err = json.Unmarshal([]byte(`{"type":"blob"}`), &expr.Literal{})
+	as.EqualError(err, fmt.Sprintf(expr.ErrUnknownLiteralType, "blob"))
+
+	_, err = json.Marshal(expr.Lit(unsupported{}))
+	as.NotNil(err)
+}
+
+type unsupported struct{ value.Value }
@@ -0,0 +1,109 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/caravan/db/value"
+)
+
+type (
+	// FuncName identifies a registered Func
+	FuncName string
+
+	// Func computes a Value from a set of argument Values
+	Func func(...value.Value) (value.Value, error)
+)
+
+// Error messages
+const (
+	ErrFuncAlreadyRegistered = "function already registered: %s"
+	ErrFuncNotRegistered     = "function not registered: %s"
+	ErrBadArguments          = "invalid arguments to function: %s"
+)
+
+var funcs = struct {
+	sync.RWMutex
+	registered map[FuncName]Func
+}{
+	registered: map[FuncName]Func{
+		"lower":  stringFunc("lower", strings.ToLower),
+		"upper":  stringFunc("upper", strings.ToUpper),
+		"length": length,
+		"abs":    abs,
+	},
+}
+
+// RegisterFunc associates a Func with the provided FuncName so that Exprs
+// can call it
+func RegisterFunc(n FuncName, f Func) error {
+	funcs.Lock()
+	defer funcs.Unlock()
+	if _, ok := funcs.registered[n]; ok {
+		return fmt.Errorf(ErrFuncAlreadyRegistered, n)
+	}
+	funcs.registered[n] = f
+	return nil
+}
+
+// LookupFunc returns the Func that was registered with the provided
+// FuncName
+func LookupFunc(n FuncName) (Func, bool) {
+	funcs.RLock()
+	defer funcs.RUnlock()
+	f, ok := funcs.registered[n]
+	return f, ok
+}
+
+func stringFunc(n FuncName, fn func(string) string) Func {
+	return func(args ...value.Value) (value.Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf(ErrBadArguments, n)
+		}
+		switch s := args[0].(type) {
+		case nil:
+			return nil, nil
+		case value.String:
+			return value.String(fn(string(s))), nil
+		default:
+			return nil, fmt.Errorf(ErrBadArguments, n)
+		}
+	}
+}
+
+func length(args ...value.Value) (value.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf(ErrBadArguments, "length")
+	}
+	switch s := args[0].(type) {
+	case nil:
+		return nil, nil
+	case value.String:
+		return value.Integer(len([]rune(s))), nil
+	default:
+		return nil, fmt.Errorf(ErrBadArguments, "length")
+	}
+}
+
+func abs(args ...value.Value) (value.Value, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf(ErrBadArguments, "abs")
+	}
+	switch n := args[0].(type) {
+	case nil:
+		return nil, nil
+	case value.Integer:
+		if n < 0 {
+			return -n, nil
+		}
+		return n, nil
+	case value.Float:
+		if n < 0 {
+			return -n, nil
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf(ErrBadArguments, "abs")
+	}
+}
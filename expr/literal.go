@@ -0,0 +1,136 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Literal holds a constant Value so that it can be serialized along
+	// with its type. A Literal holding a nil Value represents null
+	Literal struct {
+		value.Value
+	}
+
+	// LiteralType identifies the type of Value held by a serialized Literal
+	LiteralType string
+
+	literalJSON struct {
+		Type  LiteralType     `json:"type"`
+		Value json.RawMessage `json:"value,omitempty"`
+	}
+)
+
+// Literal types
+const (
+	NullType    LiteralType = "null"
+	BoolType    LiteralType = "bool"
+	StringType  LiteralType = "string"
+	IntegerType LiteralType = "integer"
+	FloatType   LiteralType = "float"
+	KeyType     LiteralType = "key"
+	PointType   LiteralType = "point"
+	VectorType  LiteralType = "vector"
+)
+
+// Error messages
+const (
+	ErrUnknownLiteralType = "unknown literal type: %s"
+	ErrUnsupportedValue   = "value can't be serialized: %T"
+)
+
+// MarshalJSON encodes the Literal along with the type of its Value
+func (l *Literal) MarshalJSON() ([]byte, error) {
+	var typ LiteralType
+	var v any
+	switch lv := l.Value.(type) {
+	case nil:
+		typ = NullType
+	case value.Bool:
+		typ, v = BoolType, bool(lv)
+	case value.String:
+		typ, v = StringType, string(lv)
+	case value.Integer:
+		typ, v = IntegerType, int64(lv)
+	case value.Float:
+		typ, v = FloatType, float64(lv)
+	case value.Key:
+		typ, v = KeyType, []byte(lv)
+	case value.Point:
+		typ, v = PointType, [2]float64{lv.Lat, lv.Lon}
+	case value.Vector:
+		typ, v = VectorType, []float32(lv)
+	default:
+		return nil, fmt.Errorf(ErrUnsupportedValue, l.Value)
+	}
+	res := literalJSON{Type: typ}
+	if v != nil {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		res.Value = raw
+	}
+	return json.Marshal(res)
+}
+
+// UnmarshalJSON decodes a Literal that was encoded by MarshalJSON
+func (l *Literal) UnmarshalJSON(b []byte) error {
+	var raw literalJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	var err error
+	switch raw.Type {
+	case NullType:
+		l.Value = nil
+	case BoolType:
+		var v bool
+		err = json.Unmarshal(raw.Value, &v)
+		l.Value = value.Bool(v)
+	case StringType:
+		var v string
+		err = json.Unmarshal(raw.Value, &v)
+		l.Value = value.String(v)
+	case IntegerType:
+		var v int64
+		err = json.Unmarshal(raw.Value, &v)
+		l.Value = value.Integer(v)
+	case FloatType:
+		var v float64
+		err = json.Unmarshal(raw.Value, &v)
+		l.Value = value.Float(v)
+	case KeyType:
+		var v []byte
+		err = json.Unmarshal(raw.Value, &v)
+		l.Value = value.Key(v)
+	case PointType:
+		var v [2]float64
+		err = json.Unmarshal(raw.Value, &v)
+		l.Value = value.Point{Lat: v[0], Lon: v[1]}
+	case VectorType:
+		var v []float32
+		err = json.Unmarshal(raw.Value, &v)
+		l.Value = value.Vector(v)
+	default:
+		err = fmt.Errorf(ErrUnknownLiteralType, raw.Type)
+	}
+	return err
+}
+
+// String returns a human-readable representation of the Literal
+func (l *Literal) String() string {
+	switch v := l.Value.(type) {
+	case nil:
+		return "NULL"
+	case value.String:
+		return strconv.Quote(string(v))
+	case value.Key:
+		return fmt.Sprintf("%x", []byte(v))
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,130 @@
+// Package geo provides the search interface of geospatial Indexes, which
+// find the rows whose Point Column lies within an area or near a location
+package geo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Searcher is implemented by geospatial Indexes
+	Searcher interface {
+		// Within finds the rows whose Point lies within the box between a
+		// south-western and a north-eastern corner, ordered by Key. If
+		// the western corner has the greater longitude, the box crosses
+		// the antimeridian
+		Within(sw, ne value.Point) Matches
+
+		// Radius finds the rows whose Point is within a distance of the
+		// center, in meters, nearest first
+		Radius(center value.Point, meters float64) Matches
+
+		// Nearest finds up to k rows whose Points are nearest to the
+		// center, nearest first
+		Nearest(center value.Point, k int) Matches
+	}
+
+	// Match is a row found by a Searcher, identified by its Key. Distance
+	// is measured from the searched center, in meters
+	Match struct {
+		Key      value.Key
+		Point    value.Point
+		Distance float64
+	}
+
+	// Matches are a set of Match
+	Matches []Match
+)
+
+// EarthRadius is the mean radius of the Earth, in meters
+const EarthRadius = 6371008.8
+
+// Error messages
+const (
+	ErrNotSpatial = "index is not a geospatial index: %s"
+)
+
+// Search returns the Searcher of a Table's geospatial Index
+func Search(tbl table.Table, n index.Name) (Searcher, error) {
+	idx, err := tbl.Index(n)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := idx.(Searcher); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf(ErrNotSpatial, n)
+}
+
+// Distance returns the great-circle distance between two Points, in meters
+func Distance(a, b value.Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := radians(b.Lon - a.Lon)
+	h := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(math.Min(h, 1)))
+}
+
+// Bounds returns the corners of the smallest box that contains every
+// Point within a distance of the center, in meters. If the box crosses
+// the antimeridian, the western corner has the greater longitude
+func Bounds(center value.Point, meters float64) (sw, ne value.Point) {
+	arc := meters / EarthRadius
+	dLat := degrees(arc)
+	sw.Lat = math.Max(center.Lat-dLat, -90)
+	ne.Lat = math.Min(center.Lat+dLat, 90)
+	cos := math.Cos(radians(center.Lat))
+	if sw.Lat == -90 || ne.Lat == 90 || math.Sin(arc) >= cos {
+		sw.Lon, ne.Lon = -180, 180
+		return sw, ne
+	}
+	dLon := degrees(math.Asin(math.Sin(arc) / cos))
+	sw.Lon = wrapLongitude(center.Lon - dLon)
+	ne.Lon = wrapLongitude(center.Lon + dLon)
+	return sw, ne
+}
+
+// Contains returns whether a Point lies within the box between two corners
+func Contains(sw, ne, p value.Point) bool {
+	if p.Lat < sw.Lat || p.Lat > ne.Lat {
+		return false
+	}
+	if sw.Lon > ne.Lon {
+		return p.Lon >= sw.Lon || p.Lon <= ne.Lon
+	}
+	return p.Lon >= sw.Lon && p.Lon <= ne.Lon
+}
+
+// Keys returns the Keys of the Matches, in order
+func (m Matches) Keys() []value.Key {
+	res := make([]value.Key, len(m))
+	for i, e := range m {
+		res[i] = e.Key
+	}
+	return res
+}
+
+func wrapLongitude(lon float64) float64 {
+	switch {
+	case lon < -180:
+		return lon + 360
+	case lon > 180:
+		return lon - 360
+	default:
+		return lon
+	}
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,44 @@
+package geo_test
+
+import (
+	"testing"
+
+	"github.com/caravan/db/geo"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	london = value.Point{Lat: 51.5074, Lon: -0.1278}
+	paris  = value.Point{Lat: 48.8566, Lon: 2.3522}
+)
+
+func TestDistance(t *testing.T) {
+	as := assert.New(t)
+
+	as.InDelta(343500, geo.Distance(london, paris), 1000)
+	as.Equal(0.0, geo.Distance(paris, paris))
+	as.InDelta(20015000, geo.Distance(
+		value.Point{Lat: 0, Lon: 0}, value.Point{Lat: 0, Lon: 180},
+	), 1000)
+}
+
+func TestBounds(t *testing.T) {
+	as := assert.New(t)
+
+	sw, ne := geo.Bounds(london, 10000)
+	as.True(geo.Contains(sw, ne, london))
+	as.False(geo.Contains(sw, ne, paris))
+	as.InDelta(london.Lat-0.09, sw.Lat, 0.001)
+	as.InDelta(london.Lon+0.144, ne.Lon, 0.001)
+
+	sw, ne = geo.Bounds(value.Point{Lat: 0, Lon: 179.99}, 10000)
+	as.Greater(sw.Lon, ne.Lon)
+	as.True(geo.Contains(sw, ne, value.Point{Lat: 0, Lon: -179.99}))
+	as.False(geo.Contains(sw, ne, value.Point{Lat: 0, Lon: 0}))
+
+	sw, ne = geo.Bounds(value.Point{Lat: 89.99, Lon: 0}, 10000)
+	as.Equal(90.0, ne.Lat)
+	as.Equal(-180.0, sw.Lon)
+	as.Equal(180.0, ne.Lon)
+}
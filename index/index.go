@@ -1,6 +1,10 @@
 package index
 
 import (
+	"fmt"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/expr"
 	"github.com/caravan/db/prefix"
 	"github.com/caravan/db/relation"
 	"github.com/caravan/db/transaction"
@@ -14,6 +18,50 @@ type (
 	// Names is a set of Name
 	Names []Name
 
+	// TypeName identifies a registered Type
+	TypeName string
+
+	// TypeNames is a set of TypeName
+	TypeNames []TypeName
+
+	// Options are additional Type-specific settings for an Index
+	Options map[string]string
+
+	// Definition is the serializable description of an Index. It names
+	// the registered Type of the Index rather than holding on to it, so
+	// that it can be stored, exported, and later reconstructed. Columns
+	// listed in Descending are ordered from highest to lowest. Columns
+	// named in Computed aren't read from the Table, but are computed from
+	// each row by their Expr. The Values of Include Columns are stored
+	// in each entry, so that lookups can be answered without reading the
+	// rows. If Where is provided, only the rows that satisfy it are
+	// indexed
+	Definition struct {
+		Name       Name         `json:"name"`
+		Type       TypeName     `json:"type"`
+		Columns    column.Names `json:"columns"`
+		Descending column.Names `json:"descending,omitempty"`
+		Computed   Computed     `json:"computed,omitempty"`
+		Include    column.Names `json:"include,omitempty"`
+		Where      *expr.Expr   `json:"where,omitempty"`
+		Options    Options      `json:"options,omitempty"`
+	}
+
+	// Computed associates the Name of an Index Column with the Expr that
+	// computes its Value
+	Computed map[column.Name]*expr.Expr
+
+	// Definitions are a set of Definition
+	Definitions []Definition
+
+	// Entry is produced by the lookups of a Covering Query. It holds the
+	// primary Key of a row and, if the Index includes Columns, the Values
+	// of the Index's Columns followed by those of its Include Columns
+	Entry struct {
+		Key    value.Key
+		Values relation.Relation
+	}
+
 	// Index describes a lookup structure associated with a Table
 	Index interface {
 		Mutate
@@ -26,11 +74,23 @@ type (
 		Truncate()
 	}
 
+	// Query looks up the primary Keys of rows by comparing the leading
+	// Columns of the Index to a Relation. Entries are produced in the
+	// order of the Index. Descending returns a Query that produces its
+	// entries in reverse order. After returns a Query that only produces
+	// the entries following an entry's Key, so that a lookup can be
+	// resumed. Covering returns a Query that produces an Entry for each
+	// of its entries rather than a primary Key
 	Query interface {
+		Descending() Query
+		After(value.Key) Query
+		Covering() Query
 		EQ(relation.Relation) transaction.Iterator
 		NEQ(relation.Relation) transaction.Iterator
 		GT(relation.Relation) transaction.Iterator
+		GTE(relation.Relation) transaction.Iterator
 		LT(relation.Relation) transaction.Iterator
+		LTE(relation.Relation) transaction.Iterator
 	}
 
 	// Constructors are a set of Index Constructor
@@ -39,6 +99,122 @@ type (
 	// Constructor instantiates an Index for the specified transaction.Txn
 	Constructor func(txn transaction.Txn) Index
 
-	// Type configures a Constructor for Index instances
-	Type func(prefix.Prefixed, Name, relation.Selector) Constructor
+	// Type configures a Constructor for Index instances. Its Selector
+	// produces the Values of the Index's Columns from a row, followed by
+	// those of its Include Columns
+	Type func(prefix.Prefixed, Definition, relation.Selector) Constructor
+)
+
+// Error messages
+const (
+	ErrDescendingColumn = "descending column is not indexed: %s"
+	ErrComputedColumn   = "computed column is not indexed: %s"
 )
+
+// IsDescending returns whether the named Column is ordered from highest to
+// lowest within the Index
+func (d Definition) IsDescending(n column.Name) bool {
+	for _, c := range d.Descending {
+		if c == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the Definition's Descending and Computed Columns
+// are indexed
+func (d Definition) Validate() error {
+	for _, c := range d.Descending {
+		if !d.hasColumn(c) {
+			return fmt.Errorf(ErrDescendingColumn, c)
+		}
+	}
+	for c := range d.Computed {
+		if !d.hasColumn(c) {
+			return fmt.Errorf(ErrComputedColumn, c)
+		}
+	}
+	return nil
+}
+
+// Expr returns the Expr that produces the Value of the named Index Column.
+// Unless the Column is computed, this is a reference to a Table Column
+func (d Definition) Expr(n column.Name) *expr.Expr {
+	if e, ok := d.Computed[n]; ok {
+		return e
+	}
+	return expr.Col(n)
+}
+
+// Exprs returns the Exprs that produce the Values of the Index's Columns
+func (d Definition) Exprs() []*expr.Expr {
+	res := make([]*expr.Expr, len(d.Columns))
+	for i, c := range d.Columns {
+		res[i] = d.Expr(c)
+	}
+	return res
+}
+
+// Selector returns a Selector that produces the Values of the named Index
+// Columns from the Rows of a Table with the provided Columns. A computed
+// Column whose Expr fails to evaluate produces a null Value
+func (d Definition) Selector(
+	cols column.Columns, names ...column.Name,
+) (relation.Selector, error) {
+	offsets := column.MakeNamedOffsets(cols...)
+	res := make([]relation.Computation, len(names))
+	for i, n := range names {
+		e, ok := d.Computed[n]
+		if !ok {
+			off, ok := offsets[n]
+			if !ok {
+				return nil, fmt.Errorf(relation.ErrColumnNotFound, n)
+			}
+			res[i] = relation.OffsetComputation(off)
+			continue
+		}
+		eval, err := expr.Compile(e, cols)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = func(r relation.Row) value.Value {
+			v, _ := eval(r)
+			return v
+		}
+	}
+	return relation.MakeComputedSelector(res...), nil
+}
+
+// IsComputed returns whether any of the Index's Columns are computed
+func (d Definition) IsComputed() bool {
+	return len(d.Computed) != 0
+}
+
+// IsCovering returns whether the Index stores the Values of its Include
+// Columns in its entries
+func (d Definition) IsCovering() bool {
+	return len(d.Include) != 0
+}
+
+// Covered returns the Names of the Columns whose Values are stored in the
+// Index's entries: its own Columns followed by its Include Columns
+func (d Definition) Covered() column.Names {
+	res := make(column.Names, 0, len(d.Columns)+len(d.Include))
+	return append(append(res, d.Columns...), d.Include...)
+}
+
+// IsPartial returns whether the Index only includes the rows that satisfy
+// its Where predicate
+func (d Definition) IsPartial() bool {
+	return d.Where != nil
+}
+
+func (d Definition) hasColumn(n column.Name) bool {
+	for _, c := range d.Columns {
+		if c == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package index
+
+import "github.com/caravan/db/value"
+
+// Tags that begin the encoding of each Value in an Index Key, so that NULL
+// never shares an encoding with a Value and sorts before every Value
+const (
+	NullTag byte = iota
+	ValueTag
+)
+
+// KeyForValue encodes a Value as it appears in the Key of an Index entry
+func KeyForValue(v value.Value) value.Key {
+	if v == nil {
+		return value.Key{NullTag}
+	}
+	return append(value.Key{ValueTag}, v.Bytes()...)
+}
+
+// KeyForValues encodes a set of Values as they appear, in order, in the
+// Key of an Index entry
+func KeyForValues(v ...value.Value) value.Key {
+	keys := make([]value.Key, len(v))
+	for i, e := range v {
+		keys[i] = KeyForValue(e)
+	}
+	return value.JoinKeys(keys...)
+}
@@ -0,0 +1,24 @@
+package index_test
+
+import (
+	"testing"
+
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyForValue(t *testing.T) {
+	as := assert.New(t)
+
+	as.Equal(value.Key{index.NullTag}, index.KeyForValue(nil))
+	as.Equal(value.Key{index.ValueTag}, index.KeyForValue(value.String("")))
+	as.NotEqual(index.KeyForValue(nil), index.KeyForValue(value.Key{}))
+	as.Equal(value.LessThan, index.KeyForValue(nil).Compare(
+		index.KeyForValue(value.Bool(false)),
+	))
+	as.Equal(
+		value.JoinKeys(value.Key{index.NullTag}, value.Key{index.ValueTag, 1}),
+		index.KeyForValues(nil, value.Key{1}),
+	)
+}
@@ -0,0 +1,54 @@
+package index
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Error messages
+const (
+	ErrTypeAlreadyRegistered = "index type already registered: %s"
+	ErrTypeNotRegistered     = "index type not registered: %s"
+)
+
+var registry = struct {
+	sync.RWMutex
+	types map[TypeName]Type
+}{
+	types: map[TypeName]Type{},
+}
+
+// Register associates a Type with the provided TypeName so that any
+// Definition referring to that name can be reconstructed
+func Register(n TypeName, t Type) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.types[n]; ok {
+		return fmt.Errorf(ErrTypeAlreadyRegistered, n)
+	}
+	registry.types[n] = t
+	return nil
+}
+
+// Lookup returns the Type that was registered with the provided TypeName
+func Lookup(n TypeName) (Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	t, ok := registry.types[n]
+	return t, ok
+}
+
+// Types returns the sorted names of all registered Types
+func Types() TypeNames {
+	registry.RLock()
+	defer registry.RUnlock()
+	res := make(TypeNames, 0, len(registry.types))
+	for n := range registry.types {
+		res = append(res, n)
+	}
+	sort.Slice(res, func(l, r int) bool {
+		return res[l] < res[r]
+	})
+	return res
+}
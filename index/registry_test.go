@@ -0,0 +1,38 @@
+package index_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/prefix"
+	"github.com/caravan/db/relation"
+	"github.com/stretchr/testify/assert"
+)
+
+var testType = index.Type(
+	func(prefix.Prefixed, index.Definition, relation.Selector) index.Constructor {
+		return nil
+	},
+)
+
+func TestRegister(t *testing.T) {
+	as := assert.New(t)
+
+	as.Nil(index.Register("registry-test", testType))
+	err := index.Register("registry-test", testType)
+	as.NotNil(err)
+	as.EqualError(err,
+		fmt.Sprintf(index.ErrTypeAlreadyRegistered, "registry-test"),
+	)
+
+	typ, ok := index.Lookup("registry-test")
+	as.True(ok)
+	as.NotNil(typ)
+
+	typ, ok = index.Lookup("missing-type")
+	as.False(ok)
+	as.Nil(typ)
+
+	as.Contains(index.Types(), index.TypeName("registry-test"))
+}
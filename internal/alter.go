@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/value"
+)
+
+type rowMigrator func(relation.Row) relation.Row
+
+// Error messages
+const (
+	ErrColumnAlreadyExists = "column already exists in table: %s"
+	ErrColumnIndexed       = "column is used by index: %s"
+)
+
+// AddColumn appends a Column to the table, assigning the provided Value to
+// that Column in every existing row
+func (t *tableTxr) AddColumn(c column.Column, v value.Value) error {
+	n := c.Name()
+	if _, ok := t.offsets[n]; ok {
+		return fmt.Errorf(ErrColumnAlreadyExists, n)
+	}
+	typ := column.TypeOf(c)
+	if !typ.Accepts(v) {
+		return fmt.Errorf(ErrColumnType, n)
+	}
+
+	def := t.tableDef
+	def.Columns = append(t.copyColumnNames(), n)
+	def.Types = def.Types.With(n, typ)
+	t.migrateRows(func(r relation.Row) relation.Row {
+		res := make(relation.Row, len(r), len(r)+1)
+		copy(res, r)
+		return append(res, v)
+	})
+	t.storeTableDef(def)
+	return nil
+}
+
+// DropColumn removes a Column from the table and from every existing row.
+// A Column that is used by one of the table's Indexes can't be dropped
+func (t *tableTxr) DropColumn(n column.Name) error {
+	off, ok := t.offsets[n]
+	if !ok {
+		return fmt.Errorf(relation.ErrColumnNotFound, n)
+	}
+	for _, def := range t.indexDefs() {
+		for _, c := range indexedColumns(def.Definition) {
+			if c == n {
+				return fmt.Errorf(ErrColumnIndexed, def.Name)
+			}
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		for _, c := range fk.Columns {
+			if c == n {
+				return fmt.Errorf(ErrColumnReferenced, fk.Table)
+			}
+		}
+	}
+	for _, c := range t.Checks {
+		for _, col := range c.Expr.Columns() {
+			if col == n {
+				return fmt.Errorf(ErrColumnChecked, c.Name)
+			}
+		}
+	}
+	for _, c := range t.Keys.Columns {
+		if c == n {
+			return fmt.Errorf(ErrColumnKeyed, t.tableDef.Name)
+		}
+	}
+
+	def := t.tableDef
+	def.Columns = append(t.copyColumnNames()[:off], def.Columns[off+1:]...)
+	def.Types = def.Types.With(n, column.AnyType)
+	t.migrateRows(func(r relation.Row) relation.Row {
+		res := make(relation.Row, 0, len(r)-1)
+		res = append(res, r[:off]...)
+		return append(res, r[off+1:]...)
+	})
+	t.storeTableDef(def)
+	return nil
+}
+
+// RenameColumn changes the Name of a Column, including in the Definitions
+// of any Indexes and constraints that use it
+func (t *tableTxr) RenameColumn(from, to column.Name) error {
+	if _, ok := t.offsets[from]; !ok {
+		return fmt.Errorf(relation.ErrColumnNotFound, from)
+	}
+	if _, ok := t.offsets[to]; ok {
+		return fmt.Errorf(ErrColumnAlreadyExists, to)
+	}
+
+	indexes := t.txn.For(t.IndexData)
+	for _, def := range t.indexDefs() {
+		def.Definition = renameIndexColumn(def.Definition, from, to)
+		indexes.Insert(value.Key(def.Name), def)
+	}
+
+	def := t.tableDef
+	def.Columns = renameColumn(def.Columns, from, to)
+	def.Types = def.Types.With(from, column.AnyType).With(to, def.Types[from])
+	def.ForeignKeys = nil
+	for _, fk := range t.ForeignKeys {
+		fk.Columns = renameColumn(fk.Columns, from, to)
+		def.ForeignKeys = append(def.ForeignKeys, fk)
+	}
+	def.Keys.Columns = renameColumn(def.Keys.Columns, from, to)
+	def.Checks = nil
+	for _, c := range t.Checks {
+		c.Expr = c.Expr.RenameColumn(from, to)
+		def.Checks = append(def.Checks, c)
+	}
+	t.storeTableDef(def)
+	return nil
+}
+
+// indexedColumns returns the Names of the Table Columns that an Index
+// reads, including those used by its computed Columns, its Include
+// Columns, and its predicate
+func indexedColumns(d index.Definition) column.Names {
+	var res column.Names
+	for _, e := range d.Exprs() {
+		res = append(res, e.Columns()...)
+	}
+	res = append(res, d.Include...)
+	if d.IsPartial() {
+		res = append(res, d.Where.Columns()...)
+	}
+	return res
+}
+
+// renameIndexColumn replaces references to a Table Column in an Index
+// Definition. The Names of computed Columns belong to the Index, and so
+// are left alone
+func renameIndexColumn(
+	d index.Definition, from, to column.Name,
+) index.Definition {
+	if _, ok := d.Computed[from]; !ok {
+		d.Columns = renameColumn(d.Columns, from, to)
+		d.Descending = renameColumn(d.Descending, from, to)
+	}
+	if d.IsCovering() {
+		d.Include = renameColumn(d.Include, from, to)
+	}
+	if d.IsComputed() {
+		computed := make(index.Computed, len(d.Computed))
+		for n, e := range d.Computed {
+			computed[n] = e.RenameColumn(from, to)
+		}
+		d.Computed = computed
+	}
+	if d.IsPartial() {
+		d.Where = d.Where.RenameColumn(from, to)
+	}
+	return d
+}
+
+func renameColumn(names column.Names, from, to column.Name) column.Names {
+	res := make(column.Names, len(names))
+	for i, n := range names {
+		if n == from {
+			n = to
+		}
+		res[i] = n
+	}
+	return res
+}
+
+func (t *tableTxr) copyColumnNames() column.Names {
+	res := make(column.Names, len(t.tableDef.Columns))
+	copy(res, t.tableDef.Columns)
+	return res
+}
+
+// migrateRows rewrites every stored row. The values of indexed Columns must
+// be left untouched, as the table's Indexes are not rebuilt
+func (t *tableTxr) migrateRows(fn rowMigrator) {
+	rows := t.txn.For(t.RowData)
+	for _, s := range t.storedRows() {
+		rows.Insert(s.key, fn(s.row))
+	}
+}
+
+// storeTableDef replaces the table's catalog entry and rebinds every
+// transactor of the table to the new Definition
+func (t *tableTxr) storeTableDef(def tableDef) {
+	t.txn.For(t.tables).Insert(value.Key(def.Name), def)
+	t.tableInfo = t.bindTable(def)
+}
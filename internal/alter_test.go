@@ -0,0 +1,144 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/internal"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddColumn(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+
+		err := tbl.AddColumn(column.Make("first"), value.Integer(0))
+		as.EqualError(err, fmt.Sprintf(internal.ErrColumnAlreadyExists, "first"))
+
+		return tbl.AddColumn(column.Make("third"), value.Integer(42))
+	})
+	as.Nil(err)
+
+	d, err = d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		cols := tbl.Columns()
+		as.Equal(3, len(cols))
+		as.Equal(column.Name("third"), cols[2].Name())
+
+		row, ok := tbl.Select(tableKey1)
+		as.True(ok)
+		as.Equal(append(tableRow1[:2:2], value.Integer(42)), row)
+
+		err := tbl.Insert(value.NewKey(), relation.Row{
+			value.String("first str"), value.String("second str"),
+			value.Integer(96),
+		})
+		as.NotNil(err)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestDropColumn(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+
+		err := tbl.DropColumn("missing")
+		as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+		err = tbl.DropColumn("second")
+		as.EqualError(err, fmt.Sprintf(internal.ErrColumnIndexed, "unique-index"))
+
+		as.Nil(tbl.AddColumn(column.Make("third"), value.Integer(42)))
+		as.Nil(tbl.AddColumn(column.Make("fourth"), value.Bool(true)))
+		return tbl.DropColumn("third")
+	})
+	as.Nil(err)
+
+	d, err = d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		cols := tbl.Columns()
+		as.Equal(3, len(cols))
+		as.Equal(column.Name("fourth"), cols[2].Name())
+
+		row, ok := tbl.Select(tableKey2)
+		as.True(ok)
+		as.Equal(append(tableRow2[:2:2], value.Bool(true)), row)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestRenameColumn(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+
+		err := tbl.RenameColumn("missing", "other")
+		as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+		err = tbl.RenameColumn("first", "second")
+		as.EqualError(err, fmt.Sprintf(internal.ErrColumnAlreadyExists, "second"))
+
+		return tbl.RenameColumn("first", "given")
+	})
+	as.Nil(err)
+
+	d, err = d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		cols := tbl.Columns()
+		as.Equal(column.Name("given"), cols[0].Name())
+
+		def, ok := tbl.IndexInfo("unique-index")
+		as.True(ok)
+		as.Equal(column.Names{"given", "second"}, def.Columns)
+
+		err := tbl.Insert(value.NewKey(), tableRow1)
+		as.EqualError(err,
+			fmt.Sprintf(internal.ErrUniqueConstraintFailed, "unique-index"),
+		)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestAlterSharedHandles(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		other, _ := d.Table("test-table")
+
+		as.Nil(tbl.AddColumn(column.Make("third"), value.Integer(42)))
+		cols := other.Columns()
+		as.Equal(3, len(cols))
+		as.Equal(column.Name("third"), cols[2].Name())
+
+		as.Nil(other.RenameColumn("third", "fourth"))
+		as.Equal(column.Name("fourth"), tbl.Columns()[2].Name())
+
+		as.Nil(tbl.DropColumn("fourth"))
+		as.Equal(2, len(other.Columns()))
+		row, ok := other.Select(tableKey1)
+		as.True(ok)
+		as.Equal(tableRow1[:2], row)
+		return nil
+	})
+	as.Nil(err)
+}
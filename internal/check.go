@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+// Error messages
+const (
+	ErrCheckAlreadyExists = "check already exists in table: %s"
+	ErrColumnChecked      = "column is used by check: %s"
+)
+
+// AddCheck adds a Check to the table after validating the table's existing
+// rows against it
+func (t *tableTxr) AddCheck(n table.CheckName, e *expr.Expr) error {
+	for _, c := range t.Checks {
+		if c.Name == n {
+			return fmt.Errorf(ErrCheckAlreadyExists, n)
+		}
+	}
+	check := table.Check{Name: n, Expr: e}
+	eval, err := expr.Compile(e, t.columns)
+	if err != nil {
+		return err
+	}
+	for _, s := range t.storedRows() {
+		if err := t.evaluateCheck(check, eval, s.row); err != nil {
+			return err
+		}
+	}
+
+	def := t.tableDef
+	checks := make(table.Checks, 0, len(t.Checks)+1)
+	def.Checks = append(append(checks, t.Checks...), check)
+	t.storeTableDef(def)
+	return nil
+}
+
+func (t *tableTxr) checkConstraints(r relation.Row) error {
+	for _, c := range t.Checks {
+		eval, err := expr.Compile(c.Expr, t.columns)
+		if err != nil {
+			return err
+		}
+		if err := t.evaluateCheck(c, eval, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tableTxr) evaluateCheck(
+	c table.Check, eval expr.Evaluator, r relation.Row,
+) error {
+	res, err := eval(r)
+	if err != nil {
+		return err
+	}
+	switch res {
+	case nil, value.Bool(true):
+		return nil
+	case value.Bool(false):
+		return &table.CheckViolation{
+			Table: t.tableDef.Name,
+			Check: c.Name,
+		}
+	default:
+		return fmt.Errorf(expr.ErrNotBoolean, c.Expr)
+	}
+}
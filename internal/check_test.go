@@ -0,0 +1,127 @@
+package internal_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/internal"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeProductsDatabase() (database.Transactor, error) {
+	return internal.NewDatabase()(func(d database.Database) error {
+		products, err := d.CreateTable("products",
+			column.Make("name"),
+			column.Make("price"),
+		)
+		if err != nil {
+			return err
+		}
+		return products.Insert(value.NewKey(), relation.Row{
+			value.String("widget"), value.Integer(10),
+		})
+	})
+}
+
+func TestAddCheck(t *testing.T) {
+	as := assert.New(t)
+
+	positive := expr.GTE(expr.Col("price"), expr.Lit(value.Integer(0)))
+	d, _ := makeProductsDatabase()
+	d, err := d(func(d database.Database) error {
+		products, _ := d.Table("products")
+		as.Nil(products.AddCheck("positive-price", positive))
+
+		err := products.AddCheck("positive-price", positive)
+		as.EqualError(err,
+			fmt.Sprintf(internal.ErrCheckAlreadyExists, "positive-price"),
+		)
+
+		err = products.AddCheck("missing", expr.IsNull(expr.Col("missing")))
+		as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+		err = products.AddCheck("expensive",
+			expr.GT(expr.Col("price"), expr.Lit(value.Integer(100))),
+		)
+		var violation *table.CheckViolation
+		as.True(errors.As(err, &violation))
+		as.Equal(table.CheckName("expensive"), violation.Check)
+		as.Equal(table.Name("products"), violation.Table)
+
+		as.Equal(table.Checks{
+			{Name: "positive-price", Expr: positive},
+		}, d.Schema().Tables[0].Checks)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestCheckViolation(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeProductsDatabase()
+	d, err := d(func(d database.Database) error {
+		products, _ := d.Table("products")
+		as.Nil(products.AddCheck("positive-price",
+			expr.GTE(expr.Col("price"), expr.Lit(value.Integer(0))),
+		))
+
+		err := products.Insert(value.NewKey(), relation.Row{
+			value.String("gadget"), value.Integer(-1),
+		})
+		as.EqualError(err,
+			fmt.Sprintf(table.ErrCheckViolation, "products", "positive-price"),
+		)
+
+		key := value.NewKey()
+		as.Nil(products.Insert(key, relation.Row{value.String("gizmo"), nil}))
+
+		_, err = products.Update(key, relation.Row{
+			value.String("gizmo"), value.Integer(-5),
+		})
+		var violation *table.CheckViolation
+		as.True(errors.As(err, &violation))
+
+		err = products.AddCheck("not-boolean", expr.Col("name"))
+		as.EqualError(err, fmt.Sprintf(expr.ErrNotBoolean, "name"))
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestCheckColumns(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeProductsDatabase()
+	d, err := d(func(d database.Database) error {
+		products, _ := d.Table("products")
+		as.Nil(products.AddCheck("positive-price",
+			expr.GTE(expr.Col("price"), expr.Lit(value.Integer(0))),
+		))
+
+		err := products.DropColumn("price")
+		as.EqualError(err,
+			fmt.Sprintf(internal.ErrColumnChecked, "positive-price"),
+		)
+
+		as.Nil(products.RenameColumn("price", "cost"))
+		err = products.Insert(value.NewKey(), relation.Row{
+			value.String("gadget"), value.Integer(-1),
+		})
+		as.EqualError(err,
+			fmt.Sprintf(table.ErrCheckViolation, "products", "positive-price"),
+		)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
@@ -0,0 +1,23 @@
+package internal
+
+import "github.com/caravan/db/value"
+
+var tableCountKey = value.Key("count")
+
+// Count returns the number of rows stored in the table. The count is
+// maintained as rows are written, so it doesn't require a scan
+func (t *tableTxr) Count() int {
+	if c, ok := t.txn.For(t.sequence).Get(t.countKey()); ok {
+		return int(c.(value.Integer))
+	}
+	return 0
+}
+
+func (t *tableTxr) adjustCount(delta int) {
+	next := value.Integer(t.Count() + delta)
+	t.txn.For(t.sequence).Insert(t.countKey(), next)
+}
+
+func (t *tableTxr) countKey() value.Key {
+	return tableCountKey.WithKeys(value.Key(t.tableDef.Name))
+}
@@ -0,0 +1,121 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/internal"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowsCursor(t *testing.T) {
+	as := assert.New(t)
+
+	d := internal.NewDatabase()
+	d, err := d(func(d database.Database) error {
+		as.Equal(uint64(0), d.Version())
+		tbl, err := d.CreateTable("items", column.Make("n"))
+		as.Nil(err)
+		for i := 1; i <= 5; i++ {
+			k := value.Key{byte(i * 2)}
+			as.Nil(tbl.Insert(k, relation.Row{value.Integer(i * 2)}))
+		}
+		return nil
+	})
+	as.Nil(err)
+
+	var token string
+	_, err = d(func(d database.Database) error {
+		tbl, _ := d.Table("items")
+		page, c := iterate.Page(tbl.Rows(), 2, d.Version())
+		as.Equal(2, len(page))
+		as.Equal(value.Key{4}, c.Key)
+		as.Equal(uint64(1), c.Version)
+		as.False(c.Stale(d.Version()))
+		token = c.String()
+		return nil
+	})
+	as.Nil(err)
+
+	// A concurrent write inserts rows before and after the cursor
+	d, err = d(func(d database.Database) error {
+		tbl, _ := d.Table("items")
+		as.Nil(tbl.Insert(value.Key{1}, relation.Row{value.Integer(1)}))
+		as.Nil(tbl.Insert(value.Key{7}, relation.Row{value.Integer(7)}))
+		_, err := tbl.Delete(value.Key{4})
+		return err
+	})
+	as.Nil(err)
+
+	_, err = d(func(d database.Database) error {
+		as.Equal(uint64(2), d.Version())
+		c, err := transaction.ParseCursor(token)
+		as.Nil(err)
+		as.Equal(value.Key{4}, c.Key)
+		as.True(c.Stale(d.Version()))
+
+		tbl, _ := d.Table("items")
+		page, next := iterate.Page(tbl.RowsAfter(c.Key), 10, d.Version())
+		as.Nil(next)
+		var keys []value.Key
+		for _, p := range page {
+			keys = append(keys, p.Key)
+		}
+		as.Equal([]value.Key{{6}, {7}, {8}, {10}}, keys)
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestIndexCursor(t *testing.T) {
+	as := assert.New(t)
+
+	d := internal.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("scores",
+			column.Make("team"), column.Make("score"),
+		)
+		as.Nil(err)
+		as.Nil(tbl.CreateIndex(db.StandardIndex, "by-team", "team", "score"))
+		for i, r := range []relation.Row{
+			{value.String("a"), value.Integer(1)},
+			{value.String("a"), value.Integer(2)},
+			{value.String("a"), value.Integer(3)},
+			{value.String("b"), value.Integer(1)},
+		} {
+			as.Nil(tbl.Insert(value.Key{byte(i)}, r))
+		}
+
+		idx, err := tbl.Index("by-team")
+		as.Nil(err)
+		a := relation.Relation{value.String("a")}
+		page, c := iterate.Page(idx.EQ(a), 2, d.Version())
+		as.Equal(2, len(page))
+		as.Equal(value.Key{1}, page[1].Value)
+
+		rest := iterate.Collect(idx.After(c.Key).EQ(a))
+		as.Equal(1, len(rest))
+		as.Equal(value.Key{2}, rest[0].Value)
+
+		as.Equal(1, iterate.Count(idx.After(c.Key).LT(
+			relation.Relation{value.String("b")},
+		)))
+		as.Equal(2, iterate.Count(idx.After(c.Key).GTE(
+			relation.Relation{value.String("a")},
+		)))
+		return nil
+	})
+	as.Nil(err)
+
+	_, err = transaction.ParseCursor("not a cursor!")
+	as.EqualError(err,
+		fmt.Sprintf(transaction.ErrInvalidCursor, "not a cursor!"),
+	)
+}
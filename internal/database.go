@@ -20,11 +20,16 @@ type (
 		sequence prefix.Prefix
 		tables   prefix.Prefix
 		data     *radix.Tree[any]
+		version  uint64
 	}
 
+	// dbTxr is the Database of a single transaction. It shares the info of
+	// each table among the table's transactors, so that every one of them
+	// sees the table's latest Definition
 	dbTxr struct {
 		*dbInfo
-		txn transaction.Txn
+		txn   transaction.Txn
+		infos map[table.Name]*tableInfo
 	}
 )
 
@@ -33,6 +38,7 @@ var seqKey = value.Key("sequence")
 // Error messages
 const (
 	ErrTableAlreadyExists = "table already exists: %s"
+	ErrTableNotFound      = "table not found: %s"
 )
 
 // NewDatabase returns a new Transactor instance
@@ -55,6 +61,7 @@ func newDatabaseTransactor(db *dbInfo) database.Transactor {
 		if err != nil || !txn.commit() {
 			return newDatabaseTransactor(db), err
 		}
+		dbCopy.version++
 		return newDatabaseTransactor(dbCopy), nil
 	}
 }
@@ -68,9 +75,16 @@ func (db *dbInfo) transactor(txn transaction.Txn) *dbTxr {
 	return &dbTxr{
 		dbInfo: db,
 		txn:    txn,
+		infos:  map[table.Name]*tableInfo{},
 	}
 }
 
+// Version returns the number of transactions that have changed the
+// Database. It identifies the state that the transaction started from
+func (db *dbTxr) Version() uint64 {
+	return db.version
+}
+
 func (db *dbTxr) Tables() table.Names {
 	var res table.Names
 	_ = iterate.ForEach(db.txn.For(db.tables).Ascending().All(),
@@ -84,12 +98,33 @@ func (db *dbTxr) Tables() table.Names {
 }
 
 func (db *dbTxr) Table(n table.Name) (table.Table, bool) {
+	if tbl, ok := db.table(n); ok {
+		return tbl, true
+	}
+	return nil, false
+}
+
+func (db *dbTxr) table(n table.Name) (*tableTxr, bool) {
+	if info, ok := db.infos[n]; ok {
+		return info.transactor(db), true
+	}
 	if tbl, ok := db.txn.For(db.tables).Get(value.Key(n)); ok {
-		return tbl.(*tableInfo).transactor(db), true
+		return db.bindTable(tbl.(tableDef)).transactor(db), true
 	}
 	return nil, false
 }
 
+// bindTable updates the shared info of a table to match its Definition
+func (db *dbTxr) bindTable(def tableDef) *tableInfo {
+	if info, ok := db.infos[def.Name]; ok {
+		*info = *def.info()
+		return info
+	}
+	info := def.info()
+	db.infos[def.Name] = info
+	return info
+}
+
 func (db *dbTxr) CreateTable(
 	n table.Name, cols ...column.Column,
 ) (table.Table, error) {
@@ -99,9 +134,29 @@ func (db *dbTxr) CreateTable(
 		return nil, fmt.Errorf(ErrTableAlreadyExists, n)
 	}
 
-	tbl := makeTable(db, n, cols...)
-	tables.Insert(key, tbl)
-	return tbl.transactor(db), nil
+	def := makeTableDef(db, n, cols...)
+	tables.Insert(key, def)
+	return db.bindTable(def).transactor(db), nil
+}
+
+func (db *dbTxr) Schema() database.Schema {
+	var res database.Schema
+	for _, def := range db.tableDefs() {
+		tbl := def.info().transactor(db)
+		res.Tables = append(res.Tables, tbl.definition())
+	}
+	return res
+}
+
+func (db *dbTxr) tableDefs() []tableDef {
+	var res []tableDef
+	_ = iterate.ForEach(db.txn.For(db.tables).Ascending().All(),
+		func(_ value.Key, v any) error {
+			res = append(res, v.(tableDef))
+			return nil
+		},
+	)
+	return res
 }
 
 func (db *dbTxr) nextPrefix() prefix.Prefix {
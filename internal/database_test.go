@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
 	"github.com/caravan/db/database"
+	"github.com/caravan/db/index"
 	"github.com/caravan/db/internal"
 	"github.com/caravan/db/table"
 	"github.com/stretchr/testify/assert"
@@ -43,3 +46,55 @@ func TestCreateTable(t *testing.T) {
 	as.NotNil(d)
 	as.Nil(err)
 }
+
+func TestSchema(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		s := d.Schema()
+		as.Equal(1, len(s.Tables))
+		as.Equal(table.Definition{
+			Name:    "test-table",
+			Columns: column.Names{"first", "second"},
+			Keys:    table.KeyStrategy{Type: table.RandomKeys},
+			Indexes: index.Definitions{
+				{
+					Name:    "standard-index",
+					Type:    db.StandardIndex,
+					Columns: column.Names{"first"},
+				},
+				{
+					Name:    "unique-index",
+					Type:    db.UniqueIndex,
+					Columns: column.Names{"first", "second"},
+				},
+			},
+		}, s.Tables[0])
+
+		j, err := s.JSON()
+		as.Nil(err)
+		as.JSONEq(`{
+			"tables": [{
+				"name": "test-table",
+				"columns": ["first", "second"],
+				"keys": {"type": "random"},
+				"indexes": [
+					{
+						"name": "standard-index",
+						"type": "standard",
+						"columns": ["first"]
+					},
+					{
+						"name": "unique-index",
+						"type": "unique",
+						"columns": ["first", "second"]
+					}
+				]
+			}]
+		}`, string(j))
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
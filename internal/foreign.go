@@ -0,0 +1,358 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// reference is a ForeignKey as seen from the Table it refers to
+	reference struct {
+		child *tableTxr
+		fk    table.ForeignKey
+	}
+
+	// referencingRows are the Keys of the child rows that refer to a
+	// specific parent row through a reference
+	referencingRows struct {
+		reference
+		keys []value.Key
+	}
+
+	references []referencingRows
+)
+
+// Error messages
+const (
+	ErrIndexNotFound        = "index not found in table: %s"
+	ErrIndexNotUnique       = "foreign key must reference a unique index: %s"
+	ErrForeignKeyColumns    = "foreign key columns don't match index: %s"
+	ErrUnknownAction        = "unknown foreign key action: %s"
+	ErrForeignKeyViolation  = "foreign key references missing row in table: %s"
+	ErrForeignKeyRestricted = "row is referenced by table: %s"
+	ErrColumnReferenced     = "column is used by foreign key to table: %s"
+)
+
+// AddForeignKey requires the values of the specified Columns to match a
+// tuple in the parent Table's unique Index, unless one of them is nil. The
+// table's existing rows are validated before the ForeignKey is added
+func (t *tableTxr) AddForeignKey(
+	cols column.Names, parent table.Name, idx index.Name, onDelete table.Action,
+) error {
+	fk := table.ForeignKey{
+		Columns:  cols,
+		Table:    parent,
+		Index:    idx,
+		OnDelete: onDelete,
+	}
+	if err := t.checkForeignKey(fk); err != nil {
+		return err
+	}
+	for _, s := range t.storedRows() {
+		if err := t.checkReference(fk, s.row); err != nil {
+			return err
+		}
+	}
+
+	def := t.tableDef
+	fks := make(table.ForeignKeys, 0, len(t.ForeignKeys)+1)
+	def.ForeignKeys = append(append(fks, t.ForeignKeys...), fk)
+	t.storeTableDef(def)
+	return nil
+}
+
+func (t *tableTxr) checkForeignKey(fk table.ForeignKey) error {
+	switch fk.OnDelete {
+	case table.Restrict, table.Cascade, table.SetNull:
+	default:
+		return fmt.Errorf(ErrUnknownAction, fk.OnDelete)
+	}
+	if _, err := relation.MakeOffsets(t.columns, fk.Columns...); err != nil {
+		return err
+	}
+	parent, ok := t.table(fk.Table)
+	if !ok {
+		return fmt.Errorf(ErrTableNotFound, fk.Table)
+	}
+	def, ok := parent.IndexInfo(fk.Index)
+	switch {
+	case !ok:
+		return fmt.Errorf(ErrIndexNotFound, fk.Index)
+	case def.Type != UniqueIndex:
+		return fmt.Errorf(ErrIndexNotUnique, fk.Index)
+	case len(def.Columns) != len(fk.Columns):
+		return fmt.Errorf(ErrForeignKeyColumns, fk.Index)
+	}
+	return nil
+}
+
+func (t *tableTxr) checkReferences(r relation.Row) error {
+	for _, fk := range t.ForeignKeys {
+		if err := t.checkReference(fk, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *tableTxr) checkReference(fk table.ForeignKey, r relation.Row) error {
+	values, err := selectColumns(t.columns, r, fk.Columns)
+	if err != nil || hasNil(values) {
+		return err
+	}
+	parent, ok := t.table(fk.Table)
+	if !ok {
+		return fmt.Errorf(ErrTableNotFound, fk.Table)
+	}
+	idx, err := parent.index(fk.Index)
+	if err != nil {
+		return err
+	}
+	if _, _, _, ok := idx.EQ(values)(); !ok {
+		return fmt.Errorf(ErrForeignKeyViolation, fk.Table)
+	}
+	return nil
+}
+
+func (t *tableTxr) index(n index.Name) (index.Index, error) {
+	if def, ok := t.txn.For(t.IndexData).Get(value.Key(n)); ok {
+		return t.makeIndex(def.(indexDef))
+	}
+	return nil, fmt.Errorf(ErrIndexNotFound, n)
+}
+
+// referencingKeys returns every ForeignKey in the Database that refers to
+// this table
+func (t *tableTxr) referencingKeys() []reference {
+	var res []reference
+	for _, def := range t.tableDefs() {
+		for _, fk := range def.ForeignKeys {
+			if fk.Table == t.tableDef.Name {
+				child, _ := t.table(def.Name)
+				res = append(res, reference{child: child, fk: fk})
+			}
+		}
+	}
+	return res
+}
+
+// referencingRows finds the child rows that refer to the row stored under
+// the provided Key, excluding that row itself
+func (t *tableTxr) referencingRows(
+	k value.Key, r relation.Row,
+) (references, error) {
+	var res references
+	for _, ref := range t.referencingKeys() {
+		parent, err := t.referencedValues(ref, r)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := t.referencesTo(ref, k, parent)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rows)
+	}
+	return res, nil
+}
+
+// checkReferenced returns an error if an update changes the Values of a
+// row that other rows refer to
+func (t *tableTxr) checkReferenced(k value.Key, old, r relation.Row) error {
+	for _, ref := range t.referencingKeys() {
+		prev, err := t.referencedValues(ref, old)
+		if err != nil {
+			return err
+		}
+		next, err := t.referencedValues(ref, r)
+		if err != nil {
+			return err
+		}
+		if equalValues(prev, next) {
+			continue
+		}
+		rows, err := t.referencesTo(ref, k, prev)
+		if err != nil {
+			return err
+		}
+		if len(rows.keys) != 0 {
+			return fmt.Errorf(ErrForeignKeyRestricted, ref.child.tableDef.Name)
+		}
+	}
+	return nil
+}
+
+// referencedValues selects the Values of a row that a reference refers to
+func (t *tableTxr) referencedValues(
+	ref reference, r relation.Row,
+) (relation.Relation, error) {
+	def, ok := t.IndexInfo(ref.fk.Index)
+	if !ok {
+		return nil, fmt.Errorf(ErrIndexNotFound, ref.fk.Index)
+	}
+	return selectColumns(t.columns, r, def.Columns)
+}
+
+// referencesTo finds the child rows of a reference whose Values match the
+// parent's. The child's rows are only scanned if none of its Indexes
+// starts with the ForeignKey's Columns
+func (t *tableTxr) referencesTo(
+	ref reference, k value.Key, parent relation.Relation,
+) (referencingRows, error) {
+	res := referencingRows{reference: ref}
+	if hasNil(parent) {
+		return res, nil
+	}
+	off, err := relation.MakeOffsets(ref.child.columns, ref.fk.Columns...)
+	if err != nil {
+		return res, err
+	}
+	sel := relation.MakeOffsetSelector(off...)
+	self := ref.child.tableDef.Name == t.tableDef.Name
+	match := func(ck value.Key, r relation.Row) {
+		if self && ck.Compare(k) == value.EqualTo {
+			return
+		}
+		if equalValues(parent, sel(r)) {
+			res.keys = append(res.keys, ck)
+		}
+	}
+
+	idx, ok, err := ref.child.leadingIndex(ref.fk.Columns)
+	if err != nil {
+		return res, err
+	}
+	if !ok {
+		for _, s := range ref.child.storedRows() {
+			match(s.key, s.row)
+		}
+		return res, nil
+	}
+	var keys []value.Key
+	_ = iterate.ForEach(idx.EQ(parent), func(_ value.Key, v any) error {
+		keys = append(keys, v.(value.Key))
+		return nil
+	})
+	for _, ck := range keys {
+		if r, ok := ref.child.Select(ck); ok {
+			match(ck, r)
+		}
+	}
+	return res, nil
+}
+
+// leadingIndex returns an Index of the table whose leading Columns are the
+// provided Columns and that holds an entry for every row
+func (t *tableTxr) leadingIndex(
+	cols column.Names,
+) (index.Index, bool, error) {
+	for _, def := range t.indexDefs() {
+		switch {
+		case def.Type != UniqueIndex && def.Type != StandardIndex,
+			def.IsComputed(), def.IsPartial(),
+			len(def.Columns) < len(cols),
+			!slices.Equal(def.Columns[:len(cols)], cols):
+			continue
+		}
+		idx, err := t.makeIndex(def)
+		if err != nil {
+			return nil, false, err
+		}
+		return idx, true, nil
+	}
+	return nil, false, nil
+}
+
+func (r references) restrict() error {
+	for _, rows := range r {
+		if rows.fk.OnDelete == table.Restrict && len(rows.keys) != 0 {
+			return fmt.Errorf(ErrForeignKeyRestricted, rows.child.tableDef.Name)
+		}
+	}
+	return nil
+}
+
+func (r references) perform() error {
+	for _, rows := range r {
+		switch rows.fk.OnDelete {
+		case table.Cascade:
+			if err := rows.cascade(); err != nil {
+				return err
+			}
+		case table.SetNull:
+			if err := rows.setNull(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r referencingRows) cascade() error {
+	for _, k := range r.keys {
+		if _, _, err := r.child.delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r referencingRows) setNull() error {
+	off, err := relation.MakeOffsets(r.child.columns, r.fk.Columns...)
+	if err != nil {
+		return err
+	}
+	for _, k := range r.keys {
+		row, ok := r.child.Select(k)
+		if !ok {
+			continue
+		}
+		res := make(relation.Row, len(row))
+		copy(res, row)
+		for _, o := range off {
+			res[o] = nil
+		}
+		if _, err := r.child.Update(k, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func selectColumns(
+	cols column.Columns, r relation.Row, names column.Names,
+) (relation.Relation, error) {
+	sel, err := relation.MakeNamedSelector(cols, names...)
+	if err != nil {
+		return nil, err
+	}
+	return sel(r), nil
+}
+
+func hasNil(r relation.Relation) bool {
+	for _, v := range r {
+		if v == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func equalValues(l, r relation.Relation) bool {
+	if len(l) != len(r) || hasNil(l) || hasNil(r) {
+		return false
+	}
+	for i, v := range l {
+		if v.Compare(r[i]) != value.EqualTo {
+			return false
+		}
+	}
+	return true
+}
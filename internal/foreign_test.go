@@ -0,0 +1,343 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/internal"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	orderKey1 = value.NewKey()
+	orderKey2 = value.NewKey()
+	itemKey1  = value.NewKey()
+	itemKey2  = value.NewKey()
+	itemKey3  = value.NewKey()
+)
+
+func makeOrdersDatabase(onDelete table.Action) (database.Transactor, error) {
+	return internal.NewDatabase()(func(d database.Database) error {
+		orders, err := d.CreateTable("orders",
+			column.Make("id"),
+			column.Make("customer"),
+		)
+		if err != nil {
+			return err
+		}
+		if err := orders.CreateIndex(db.UniqueIndex, "order-id", "id"); err != nil {
+			return err
+		}
+
+		items, err := d.CreateTable("items",
+			column.Make("order_id"),
+			column.Make("product"),
+		)
+		if err != nil {
+			return err
+		}
+		if err := items.CreateIndex(db.StandardIndex, "item-order", "order_id"); err != nil {
+			return err
+		}
+		err = items.AddForeignKey(
+			column.Names{"order_id"}, "orders", "order-id", onDelete,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, o := range []struct {
+			key value.Key
+			row relation.Row
+		}{
+			{orderKey1, relation.Row{value.Integer(1), value.String("bill")}},
+			{orderKey2, relation.Row{value.Integer(2), value.String("ted")}},
+		} {
+			if err := orders.Insert(o.key, o.row); err != nil {
+				return err
+			}
+		}
+		for _, i := range []struct {
+			key value.Key
+			row relation.Row
+		}{
+			{itemKey1, relation.Row{value.Integer(1), value.String("guitar")}},
+			{itemKey2, relation.Row{value.Integer(1), value.String("amp")}},
+			{itemKey3, relation.Row{value.Integer(2), value.String("phone booth")}},
+		} {
+			if err := items.Insert(i.key, i.row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func TestForeignKeyValidation(t *testing.T) {
+	as := assert.New(t)
+
+	d, err := makeOrdersDatabase(table.Restrict)
+	as.Nil(err)
+
+	d, err = d(func(d database.Database) error {
+		items, _ := d.Table("items")
+
+		err := items.Insert(value.NewKey(), relation.Row{
+			value.Integer(3), value.String("nothing"),
+		})
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyViolation, "orders"))
+
+		_, err = items.Update(itemKey1, relation.Row{
+			value.Integer(3), value.String("guitar"),
+		})
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyViolation, "orders"))
+
+		as.Nil(items.Insert(value.NewKey(), relation.Row{
+			nil, value.String("orphan"),
+		}))
+
+		err = items.DropColumn("order_id")
+		as.EqualError(err, fmt.Sprintf(internal.ErrColumnIndexed, "item-order"))
+
+		s := d.Schema()
+		as.Equal(table.ForeignKeys{
+			{
+				Columns:  column.Names{"order_id"},
+				Table:    "orders",
+				Index:    "order-id",
+				OnDelete: table.Restrict,
+			},
+		}, s.Tables[0].ForeignKeys)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestAddForeignKeyErrors(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeOrdersDatabase(table.Restrict)
+	d, err := d(func(d database.Database) error {
+		items, _ := d.Table("items")
+
+		err := items.AddForeignKey(
+			column.Names{"order_id"}, "orders", "order-id", "explode",
+		)
+		as.EqualError(err, fmt.Sprintf(internal.ErrUnknownAction, "explode"))
+
+		err = items.AddForeignKey(
+			column.Names{"missing"}, "orders", "order-id", table.Cascade,
+		)
+		as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+		err = items.AddForeignKey(
+			column.Names{"order_id"}, "missing", "order-id", table.Cascade,
+		)
+		as.EqualError(err, fmt.Sprintf(internal.ErrTableNotFound, "missing"))
+
+		err = items.AddForeignKey(
+			column.Names{"order_id"}, "orders", "missing", table.Cascade,
+		)
+		as.EqualError(err, fmt.Sprintf(internal.ErrIndexNotFound, "missing"))
+
+		err = items.AddForeignKey(
+			column.Names{"order_id"}, "items", "item-order", table.Cascade,
+		)
+		as.EqualError(err, fmt.Sprintf(internal.ErrIndexNotUnique, "item-order"))
+
+		err = items.AddForeignKey(
+			column.Names{"order_id", "product"}, "orders", "order-id",
+			table.Cascade,
+		)
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyColumns, "order-id"))
+
+		orders, _ := d.Table("orders")
+		as.Nil(orders.CreateIndex(db.UniqueIndex, "customer", "customer"))
+		err = items.AddForeignKey(
+			column.Names{"product"}, "orders", "customer", table.Cascade,
+		)
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyViolation, "orders"))
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestForeignKeyRestrict(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeOrdersDatabase(table.Restrict)
+	d, err := d(func(d database.Database) error {
+		orders, _ := d.Table("orders")
+		items, _ := d.Table("items")
+
+		_, err := orders.Delete(orderKey1)
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyRestricted, "items"))
+
+		err = orders.Truncate()
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyRestricted, "items"))
+
+		_, err = orders.Update(orderKey1, relation.Row{
+			value.Integer(3), value.String("bill"),
+		})
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyRestricted, "items"))
+		_, err = orders.Update(orderKey1, relation.Row{
+			value.Integer(1), value.String("rufus"),
+		})
+		as.Nil(err)
+
+		_, err = items.Delete(itemKey3)
+		as.Nil(err)
+		_, err = orders.Update(orderKey2, relation.Row{
+			value.Integer(4), value.String("ted"),
+		})
+		as.Nil(err)
+		_, err = orders.Delete(orderKey2)
+		as.Nil(err)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestForeignKeyCascade(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeOrdersDatabase(table.Cascade)
+	d, err := d(func(d database.Database) error {
+		orders, _ := d.Table("orders")
+		items, _ := d.Table("items")
+
+		_, err := orders.Update(orderKey1, relation.Row{
+			value.Integer(3), value.String("bill"),
+		})
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyRestricted, "items"))
+
+		_, err = orders.Delete(orderKey1)
+		as.Nil(err)
+
+		_, ok := items.Select(itemKey1)
+		as.False(ok)
+		_, ok = items.Select(itemKey2)
+		as.False(ok)
+		_, ok = items.Select(itemKey3)
+		as.True(ok)
+
+		as.Nil(orders.Truncate())
+		_, ok = items.Select(itemKey3)
+		as.False(ok)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestForeignKeySetNull(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeOrdersDatabase(table.SetNull)
+	d, err := d(func(d database.Database) error {
+		orders, _ := d.Table("orders")
+		items, _ := d.Table("items")
+
+		_, err := orders.Delete(orderKey1)
+		as.Nil(err)
+
+		row, ok := items.Select(itemKey1)
+		as.True(ok)
+		as.Equal(relation.Row{nil, value.String("guitar")}, row)
+
+		row, ok = items.Select(itemKey3)
+		as.True(ok)
+		as.Equal(relation.Row{value.Integer(2), value.String("phone booth")}, row)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestForeignKeyWithoutIndex(t *testing.T) {
+	as := assert.New(t)
+
+	boss := value.NewKey()
+	worker := value.NewKey()
+	d, err := internal.NewDatabase()(func(d database.Database) error {
+		staff, err := d.CreateTable("staff",
+			column.Make("id"),
+			column.Make("manager"),
+		)
+		as.Nil(err)
+		as.Nil(staff.CreateIndex(db.UniqueIndex, "staff-id", "id"))
+		as.Nil(staff.AddForeignKey(
+			column.Names{"manager"}, "staff", "staff-id", table.Restrict,
+		))
+		as.Nil(staff.Insert(boss, relation.Row{value.Integer(1), nil}))
+		as.Nil(staff.Insert(worker, relation.Row{
+			value.Integer(2), value.Integer(1),
+		}))
+
+		_, err = staff.Delete(boss)
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyRestricted, "staff"))
+		_, err = staff.Delete(worker)
+		as.Nil(err)
+		_, err = staff.Delete(boss)
+		as.Nil(err)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestForeignKeyNullValues(t *testing.T) {
+	as := assert.New(t)
+
+	_, err := internal.NewDatabase()(func(d database.Database) error {
+		parents, err := d.CreateTable("parents", column.Make("code"))
+		as.Nil(err)
+		as.Nil(parents.CreateIndex(db.UniqueIndex, "parent-code", "code"))
+		parent := value.NewKey()
+		as.Nil(parents.Insert(parent, relation.Row{nil}))
+
+		children, err := d.CreateTable("children",
+			column.Make("code"), column.Make("name"),
+		)
+		as.Nil(err)
+		as.Nil(children.CreateIndex(db.UniqueIndex, "child-code", "code"))
+		as.Nil(children.AddForeignKey(
+			column.Names{"code"}, "parents", "parent-code", table.SetNull,
+		))
+		err = children.Insert(value.NewKey(), relation.Row{
+			value.String(""), value.String("orphan"),
+		})
+		as.EqualError(err, fmt.Sprintf(internal.ErrForeignKeyViolation, "parents"))
+
+		_, err = parents.Update(parent, relation.Row{value.String("a")})
+		as.Nil(err)
+		other := value.NewKey()
+		as.Nil(parents.Insert(other, relation.Row{value.String("b")}))
+		for _, r := range []relation.Row{
+			{value.String("a"), value.String("first")},
+			{value.String("b"), value.String("second")},
+		} {
+			as.Nil(children.Insert(value.NewKey(), r))
+		}
+
+		_, err = parents.Delete(parent)
+		as.Nil(err)
+		_, err = parents.Delete(other)
+		as.Nil(err)
+		for _, r := range iterate.Collect(children.Rows()) {
+			as.Nil(r.Value.(relation.Row)[0])
+		}
+		return nil
+	})
+	as.Nil(err)
+}
@@ -0,0 +1,416 @@
+package internal
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/prefix"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/text"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// fullTextIndex is an inverted Index of the Terms that its Analyzer
+	// finds in the string Values of its Columns. For each Term, it stores
+	// the positions at which the Term appears in each row. It also stores
+	// the number of Terms in each row, and the totals that BM25 ranking
+	// depends on
+	fullTextIndex struct {
+		*indexInfo
+		txn      transaction.Txn
+		analyzer text.Analyzer
+		err      error
+		keyView
+		eqOnly
+	}
+
+	// textStats are the totals of a full-text Index's rows
+	textStats struct {
+		docs  int
+		terms int
+	}
+
+	// posting is the positions of a Term in a row
+	posting struct {
+		key       value.Key
+		positions []int
+	}
+
+	// termPostings are the postings of a Term, in Key order
+	termPostings struct {
+		term     string
+		postings []posting
+	}
+
+	// textHit accumulates the score of a row found by a search, and the
+	// positions of each of the searched Terms that it contains
+	textHit struct {
+		key       value.Key
+		score     float64
+		positions map[string][]int
+	}
+
+	textHits map[string]*textHit
+)
+
+// FullTextIndex names the index.Type that searches the text of its Columns
+const FullTextIndex index.TypeName = "fulltext"
+
+// Tags that begin the Keys stored beneath a full-text Index's prefix
+const (
+	postingTag byte = 'p'
+	lengthTag  byte = 'd'
+	statsTag   byte = 's'
+)
+
+const (
+	// columnGap separates the positions of consecutive Columns, so that
+	// phrases don't match across them
+	columnGap = 100
+
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+func init() {
+	mustRegisterIndexType(FullTextIndex, fullTextIndexType)
+}
+
+// fullTextIndexType is an index.Type that searches the text of its
+// Columns, using the Analyzer named by its Options
+var fullTextIndexType = index.Type(
+	func(
+		p prefix.Prefixed, d index.Definition, s relation.Selector,
+	) index.Constructor {
+		info := makeIndexInfo(p, d, s)
+		name := text.StandardAnalyzer
+		if n, ok := d.Options[text.AnalyzerOption]; ok {
+			name = text.AnalyzerName(n)
+		}
+		a, err := text.LookupAnalyzer(name)
+		return func(txn transaction.Txn) index.Index {
+			return &fullTextIndex{
+				indexInfo: info,
+				txn:       txn,
+				analyzer:  a,
+				err:       err,
+			}
+		}
+	},
+)
+
+func (i *fullTextIndex) validate() error {
+	return i.err
+}
+
+func (i *fullTextIndex) Insert(k value.Key, r relation.Row) error {
+	if i.err != nil {
+		return i.err
+	}
+	tokens := i.analyze(r)
+	if len(tokens) == 0 {
+		return nil
+	}
+	idx := i.txn.For(i)
+	for term, positions := range termPositions(tokens) {
+		idx.Insert(postingKey(term, k), positions)
+	}
+	idx.Insert(lengthKey(k), len(tokens))
+	i.adjustStats(1, len(tokens))
+	return nil
+}
+
+func (i *fullTextIndex) Delete(k value.Key, r relation.Row) bool {
+	if i.err != nil {
+		return false
+	}
+	idx := i.txn.For(i)
+	length, ok := idx.Delete(lengthKey(k))
+	if !ok {
+		return false
+	}
+	for term := range termPositions(i.analyze(r)) {
+		idx.Delete(postingKey(term, k))
+	}
+	i.adjustStats(-1, -length.(int))
+	return true
+}
+
+func (i *fullTextIndex) Truncate() {
+	i.txn.For(i).Drop()
+}
+
+// analyze returns the Tokens of a row's string Values. The positions of
+// each Column follow those of the Column before it
+func (i *fullTextIndex) analyze(r relation.Row) []text.Token {
+	var res []text.Token
+	base := 0
+	for _, v := range i.selector(r)[:len(i.desc)] {
+		s, ok := v.(value.String)
+		if !ok {
+			continue
+		}
+		tokens := i.analyzer(string(s))
+		if len(tokens) == 0 {
+			continue
+		}
+		for _, t := range tokens {
+			t.Position += base
+			res = append(res, t)
+		}
+		base = res[len(res)-1].Position + columnGap
+	}
+	return res
+}
+
+func termPositions(tokens []text.Token) map[string][]int {
+	res := map[string][]int{}
+	for _, t := range tokens {
+		res[t.Term] = append(res[t.Term], t.Position)
+	}
+	return res
+}
+
+func postingKey(term string, k value.Key) value.Key {
+	res := make(value.Key, 0, len(term)+len(k)+2)
+	res = append(res, postingTag)
+	res = append(res, term...)
+	res = append(res, 0)
+	return append(res, k...)
+}
+
+func lengthKey(k value.Key) value.Key {
+	return append(value.Key{lengthTag}, k...)
+}
+
+func (i *fullTextIndex) stats() textStats {
+	if s, ok := i.txn.For(i).Get(value.Key{statsTag}); ok {
+		return s.(textStats)
+	}
+	return textStats{}
+}
+
+func (i *fullTextIndex) adjustStats(docs, terms int) {
+	s := i.stats()
+	s.docs += docs
+	s.terms += terms
+	i.txn.For(i).Insert(value.Key{statsTag}, s)
+}
+
+// Match finds the rows that contain any of the Terms of the text
+func (i *fullTextIndex) Match(s string) text.Matches {
+	terms := uniqueTerms(i.tokens(s))
+	hits := i.search(terms)
+	return hits.rank(func(*textHit) bool { return true })
+}
+
+// Term finds the rows that contain every Term of the text
+func (i *fullTextIndex) Term(s string) text.Matches {
+	terms := uniqueTerms(i.tokens(s))
+	hits := i.search(terms)
+	return hits.rank(func(h *textHit) bool {
+		return len(h.positions) == len(terms)
+	})
+}
+
+// Phrase finds the rows that contain the Terms of the text, separated by
+// the same distances as they are in the text
+func (i *fullTextIndex) Phrase(s string) text.Matches {
+	tokens := i.tokens(s)
+	terms := uniqueTerms(tokens)
+	hits := i.search(terms)
+	return hits.rank(func(h *textHit) bool {
+		return len(h.positions) == len(terms) && h.hasPhrase(tokens)
+	})
+}
+
+// StartsWith finds the rows that contain a Term beginning with the text.
+// The text is only lowercased, but the Terms were analyzed, so a prefix
+// must match a Term as it is stored, such as a stem
+func (i *fullTextIndex) StartsWith(s string) text.Matches {
+	if s == "" {
+		return nil
+	}
+	pfx := append(value.Key{postingTag}, strings.ToLower(s)...)
+	hits := textHits{}
+	i.score(hits, i.postings(pfx))
+	return hits.rank(func(*textHit) bool { return true })
+}
+
+// tokens analyzes searched text. Nothing is found if the Index's Analyzer
+// isn't registered
+func (i *fullTextIndex) tokens(s string) []text.Token {
+	if i.err != nil {
+		return nil
+	}
+	return i.analyzer(s)
+}
+
+func uniqueTerms(tokens []text.Token) []string {
+	var res []string
+	for _, t := range tokens {
+		if !slices.Contains(res, t.Term) {
+			res = append(res, t.Term)
+		}
+	}
+	return res
+}
+
+func (i *fullTextIndex) search(terms []string) textHits {
+	hits := textHits{}
+	for _, term := range terms {
+		i.score(hits, i.postings(postingKey(term, nil)))
+	}
+	return hits
+}
+
+// postings returns the postings of every Term whose Keys begin with the
+// prefix, grouped by Term
+func (i *fullTextIndex) postings(pfx value.Key) []termPostings {
+	var res []termPostings
+	iter := i.txn.For(i).Ascending().From(pfx)
+	_ = iterate.ForEach(
+		iterate.While(iter, func(k value.Key, _ any) bool {
+			return bytes.HasPrefix(k, pfx)
+		}),
+		func(k value.Key, v any) error {
+			sep := bytes.IndexByte(k, 0)
+			term := string(k[1:sep])
+			p := posting{
+				key:       append(value.Key(nil), k[sep+1:]...),
+				positions: v.([]int),
+			}
+			if n := len(res); n > 0 && res[n-1].term == term {
+				res[n-1].postings = append(res[n-1].postings, p)
+				return nil
+			}
+			res = append(res, termPostings{
+				term:     term,
+				postings: []posting{p},
+			})
+			return nil
+		},
+	)
+	return res
+}
+
+// score adds the BM25 score of each Term to the rows that contain it
+func (i *fullTextIndex) score(hits textHits, terms []termPostings) {
+	s := i.stats()
+	if s.docs == 0 {
+		return
+	}
+	avg := float64(s.terms) / float64(s.docs)
+	for _, t := range terms {
+		n := float64(len(t.postings))
+		idf := math.Log((float64(s.docs)-n+0.5)/(n+0.5) + 1)
+		for _, p := range t.postings {
+			tf := float64(len(p.positions))
+			length, _ := i.txn.For(i).Get(lengthKey(p.key))
+			norm := 1 - bm25B + bm25B*float64(length.(int))/avg
+			h := hits.get(p.key)
+			h.score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+			h.positions[t.term] = p.positions
+		}
+	}
+}
+
+func (h textHits) get(k value.Key) *textHit {
+	if res, ok := h[string(k)]; ok {
+		return res
+	}
+	res := &textHit{key: k, positions: map[string][]int{}}
+	h[string(k)] = res
+	return res
+}
+
+// rank returns the hits that satisfy the filter, highest score first.
+// Hits with the same score are ordered by Key
+func (h textHits) rank(filter func(*textHit) bool) text.Matches {
+	var res text.Matches
+	for _, hit := range h {
+		if filter(hit) {
+			res = append(res, text.Match{Key: hit.key, Score: hit.score})
+		}
+	}
+	slices.SortFunc(res, func(l, r text.Match) int {
+		switch {
+		case l.Score > r.Score:
+			return -1
+		case l.Score < r.Score:
+			return 1
+		default:
+			return bytes.Compare(l.Key, r.Key)
+		}
+	})
+	return res
+}
+
+// hasPhrase returns whether the hit contains the Tokens at the same
+// distances from one another as they have in the searched text
+func (h *textHit) hasPhrase(tokens []text.Token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	first := tokens[0]
+next:
+	for _, start := range h.positions[first.Term] {
+		for _, t := range tokens[1:] {
+			pos := start + t.Position - first.Position
+			if !slices.Contains(h.positions[t.Term], pos) {
+				continue next
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Descending returns a view of the Index whose lookups produce entries in
+// reverse order
+func (i *fullTextIndex) Descending() index.Query {
+	res := *i
+	res.reverse = true
+	return &res
+}
+
+// Covering returns a view of the Index whose lookups produce an Entry for
+// each match, rather than its primary Key
+func (i *fullTextIndex) Covering() index.Query {
+	res := *i
+	res.covering = true
+	return &res
+}
+
+// After returns a view of the Index whose lookups only produce the
+// entries that follow the provided Key
+func (i *fullTextIndex) After(k value.Key) index.Query {
+	res := *i
+	res.after = k
+	return &res
+}
+
+// EQ returns the primary Keys of the rows that contain every Term of the
+// Relation's string Values, in Key order
+func (i *fullTextIndex) EQ(r relation.Relation) transaction.Iterator {
+	var tokens []text.Token
+	for _, v := range r {
+		if s, ok := v.(value.String); ok {
+			tokens = append(tokens, i.tokens(string(s))...)
+		}
+	}
+	terms := uniqueTerms(tokens)
+	if len(terms) == 0 {
+		return noEntries
+	}
+	keys := i.search(terms).rank(func(h *textHit) bool {
+		return len(h.positions) == len(terms)
+	}).Keys()
+	return i.lookup(keys)
+}
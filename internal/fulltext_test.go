@@ -0,0 +1,131 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/text"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFullTextIndex(t *testing.T) {
+	as := assert.New(t)
+
+	d := db.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("articles",
+			column.Make("title"),
+			column.Make("body"),
+		)
+		as.Nil(err)
+		as.Nil(tbl.CreateIndex(db.FullTextIndex, "by-text", "title", "body"))
+
+		as.Nil(tbl.Insert(value.Key("a"), relation.Row{
+			value.String("Connecting Databases"),
+			value.String("The database is connected to the network"),
+		}))
+		as.Nil(tbl.Insert(value.Key("b"), relation.Row{
+			value.String("Networks"),
+			value.String("A network of networks, connected networks"),
+		}))
+		as.Nil(tbl.Insert(value.Key("c"), relation.Row{
+			value.String("Cooking"), value.Integer(42),
+		}))
+
+		s, err := text.Search(tbl, "by-text")
+		as.Nil(err)
+
+		as.Equal([]value.Key{value.Key("a")},
+			s.Term("databases connection").Keys(),
+		)
+		as.Equal([]value.Key{value.Key("b"), value.Key("a")},
+			s.Match("network").Keys(),
+		)
+		as.Equal([]value.Key{value.Key("a")},
+			s.Phrase("connected to the network").Keys(),
+		)
+		as.Empty(s.Phrase("network database"))
+		as.Empty(s.Phrase("databases the database"))
+		as.Equal([]value.Key{value.Key("c")}, s.StartsWith("COOK").Keys())
+		as.Equal(2, len(s.StartsWith("connect")))
+		as.Empty(s.StartsWith("connecti"))
+		as.Empty(s.StartsWith("cooking"))
+
+		idx, err := tbl.Index("by-text")
+		as.Nil(err)
+		connected := relation.Relation{value.String("connected")}
+		as.Equal(2, iterate.Count(idx.EQ(connected)))
+		k, _, _ := iterate.First(idx.Descending().EQ(connected))
+		as.Equal(value.Key("b"), k)
+		k, _, _ = iterate.First(idx.After(value.Key("a")).EQ(connected))
+		as.Equal(value.Key("b"), k)
+		_, v, _ := iterate.First(idx.Covering().EQ(connected))
+		as.Equal(index.Entry{Key: value.Key("a")}, v)
+		as.Equal(0, iterate.Count(idx.GT(connected)))
+
+		_, err = tbl.Update(value.Key("a"), relation.Row{
+			value.String("Cooking Databases"), value.String("Recipes"),
+		})
+		as.Nil(err)
+		as.Equal([]value.Key{value.Key("b")}, s.Term("connected").Keys())
+		as.Equal(2, len(s.StartsWith("cook")))
+
+		_, err = tbl.Delete(value.Key("b"))
+		as.Nil(err)
+		as.Empty(s.Match("network"))
+
+		as.Nil(tbl.Truncate())
+		as.Empty(s.StartsWith("cook"))
+
+		_, err = text.Search(tbl, "missing")
+		as.NotNil(err)
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestFullTextIndexAnalyzer(t *testing.T) {
+	as := assert.New(t)
+
+	d := db.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("notes", column.Make("body"))
+		as.Nil(err)
+		as.Nil(tbl.CreateIndex(db.StandardIndex, "by-body", "body"))
+		as.Nil(tbl.DefineIndex(index.Definition{
+			Name:    "by-words",
+			Type:    db.FullTextIndex,
+			Columns: column.Names{"body"},
+			Options: index.Options{
+				text.AnalyzerOption: string(text.SimpleAnalyzer),
+			},
+		}))
+		as.Nil(tbl.Insert(value.Key("a"), relation.Row{
+			value.String("The Connected Things"),
+		}))
+
+		s, err := text.Search(tbl, "by-words")
+		as.Nil(err)
+		as.Equal(1, len(s.Term("the connected")))
+		as.Empty(s.Term("connect"))
+
+		_, err = text.Search(tbl, "by-body")
+		as.EqualError(err, fmt.Sprintf(text.ErrNotSearchable, "by-body"))
+
+		as.EqualError(tbl.DefineIndex(index.Definition{
+			Name:    "by-missing",
+			Type:    db.FullTextIndex,
+			Columns: column.Names{"body"},
+			Options: index.Options{text.AnalyzerOption: "missing"},
+		}), fmt.Sprintf(text.ErrAnalyzerNotRegistered, "missing"))
+		return nil
+	})
+	as.Nil(err)
+}
@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/caravan/db/geo"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/prefix"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// geoIndex is a standard Index over a single Point Column. Because a
+	// Point's encoding begins with its Code, the entries within an area
+	// are found by scanning the ranges of Codes whose cells cover it
+	geoIndex struct {
+		standardIndex
+	}
+
+	// codeRange is an inclusive range of Z-order Codes
+	codeRange struct {
+		lo, hi uint64
+	}
+)
+
+// GeoIndex names the index.Type that finds the rows whose Point Column lies
+// within an area or near a location
+const GeoIndex index.TypeName = "geo"
+
+// Error messages
+const (
+	ErrGeoIndexColumns = "geo index requires one ascending column: %s"
+	ErrPointRequired   = "geo index value is not a point: %T"
+)
+
+const (
+	// pointLen is the length of an encoded Point
+	pointLen = 24
+
+	// coverDepth is the number of levels that the cells covering an area
+	// are subdivided beyond the level of a cell the size of the area
+	coverDepth = 4
+
+	// nearestRadius is the initial radius of a Nearest search, in meters
+	nearestRadius = 1000
+)
+
+func init() {
+	mustRegisterIndexType(GeoIndex, geoIndexType)
+}
+
+// geoIndexType is an index.Type that finds the rows whose Point Column lies
+// within an area or near a location
+var geoIndexType = index.Type(
+	func(
+		p prefix.Prefixed, d index.Definition, s relation.Selector,
+	) index.Constructor {
+		info := makeIndexInfo(p, d, s)
+		return func(txn transaction.Txn) index.Index {
+			return &geoIndex{
+				standardIndex: standardIndex{
+					baseIndex: makeBaseIndex(info, txn),
+				},
+			}
+		}
+	},
+)
+
+func (i *geoIndex) validate() error {
+	if len(i.desc) != 1 || i.desc[0] {
+		return fmt.Errorf(ErrGeoIndexColumns, i.name)
+	}
+	return nil
+}
+
+// Insert adds a row whose Column holds a Point. Rows whose Column is null
+// aren't indexed
+func (i *geoIndex) Insert(k value.Key, r relation.Row) error {
+	switch v := i.selector(r)[0].(type) {
+	case nil:
+		return nil
+	case value.Point:
+		return i.standardIndex.Insert(k, r)
+	default:
+		return fmt.Errorf(ErrPointRequired, v)
+	}
+}
+
+func (i *geoIndex) Delete(k value.Key, r relation.Row) bool {
+	if _, ok := i.selector(r)[0].(value.Point); !ok {
+		return false
+	}
+	return i.standardIndex.Delete(k, r)
+}
+
+// Within finds the rows whose Point lies within the box between two
+// corners, ordered by Key
+func (i *geoIndex) Within(sw, ne value.Point) geo.Matches {
+	res := i.scan(sw, ne, func(p value.Point) (float64, bool) {
+		return 0, geo.Contains(sw, ne, p)
+	})
+	slices.SortFunc(res, func(l, r geo.Match) int {
+		return bytes.Compare(l.Key, r.Key)
+	})
+	return res
+}
+
+// Radius finds the rows whose Point is within a distance of the center,
+// nearest first
+func (i *geoIndex) Radius(center value.Point, meters float64) geo.Matches {
+	sw, ne := geo.Bounds(center, meters)
+	res := i.scan(sw, ne, func(p value.Point) (float64, bool) {
+		d := geo.Distance(center, p)
+		return d, d <= meters
+	})
+	slices.SortFunc(res, func(l, r geo.Match) int {
+		switch {
+		case l.Distance < r.Distance:
+			return -1
+		case l.Distance > r.Distance:
+			return 1
+		default:
+			return bytes.Compare(l.Key, r.Key)
+		}
+	})
+	return res
+}
+
+// Nearest finds up to k rows whose Points are nearest to the center. The
+// radius of the search grows until it holds k rows or the whole Earth
+func (i *geoIndex) Nearest(center value.Point, k int) geo.Matches {
+	if k <= 0 {
+		return nil
+	}
+	for r := float64(nearestRadius); ; r *= 4 {
+		res := i.Radius(center, r)
+		if len(res) >= k {
+			return res[:k]
+		}
+		if r >= math.Pi*geo.EarthRadius {
+			return res
+		}
+	}
+}
+
+// scan reports the entries within the box between two corners that the
+// function accepts, along with the distance that it returns
+func (i *geoIndex) scan(
+	sw, ne value.Point, fn func(value.Point) (float64, bool),
+) geo.Matches {
+	var res geo.Matches
+	for _, cr := range coverBox(sw, ne) {
+		from := binary.BigEndian.AppendUint64(
+			value.Key{index.ValueTag}, cr.lo,
+		)
+		iter := iterate.While(i.txn.For(i).Ascending().From(from),
+			func(k value.Key, _ any) bool {
+				return binary.BigEndian.Uint64(k[1:]) <= cr.hi
+			},
+		)
+		_ = iterate.ForEach(iter, func(k value.Key, _ any) error {
+			p := pointForKey(k)
+			if d, ok := fn(p); ok {
+				res = append(res, geo.Match{
+					Key:      append(value.Key(nil), k[pointLen+2:]...),
+					Point:    p,
+					Distance: d,
+				})
+			}
+			return nil
+		})
+	}
+	return res
+}
+
+// pointForKey decodes the Point that follows the tag of an entry's Key
+func pointForKey(k value.Key) value.Point {
+	return value.Point{
+		Lat: floatForBytes(k[9:17]),
+		Lon: floatForBytes(k[17 : pointLen+1]),
+	}
+}
+
+// floatForBytes reverses the encoding of value.Float's Bytes
+func floatForBytes(b []byte) float64 {
+	u := binary.BigEndian.Uint64(b)
+	if u&(1<<63) != 0 {
+		u &^= 1 << 63
+	} else {
+		u = ^u
+	}
+	return math.Float64frombits(u)
+}
+
+// coverBox returns the ranges of Codes whose cells cover the box between
+// two corners. A box that crosses the antimeridian is split in two
+func coverBox(sw, ne value.Point) []codeRange {
+	if sw.Lon > ne.Lon {
+		return append(
+			coverBox(sw, value.Point{Lat: ne.Lat, Lon: 180}),
+			coverBox(value.Point{Lat: sw.Lat, Lon: -180}, ne)...,
+		)
+	}
+	minX, minY := sw.Cell()
+	maxX, maxY := ne.Cell()
+	level := min(
+		bits.LeadingZeros32(max(maxX-minX, maxY-minY))+coverDepth, 32,
+	)
+
+	var res []codeRange
+	var cover func(x, y uint32, l int)
+	cover = func(x, y uint32, l int) {
+		size := uint64(1) << (32 - l)
+		hiX, hiY := uint64(x)+size-1, uint64(y)+size-1
+		if hiX < uint64(minX) || x > maxX || hiY < uint64(minY) || y > maxY {
+			return
+		}
+		inside := x >= minX && hiX <= uint64(maxX) &&
+			y >= minY && hiY <= uint64(maxY)
+		if !inside && l < level {
+			half := uint32(size / 2)
+			cover(x, y, l+1)
+			cover(x, y+half, l+1)
+			cover(x+half, y, l+1)
+			cover(x+half, y+half, l+1)
+			return
+		}
+		lo := value.CellCode(x, y)
+		hi := lo | (size*size - 1)
+		if n := len(res); n > 0 && res[n-1].hi+1 == lo {
+			res[n-1].hi = hi
+			return
+		}
+		res = append(res, codeRange{lo: lo, hi: hi})
+	}
+	cover(0, 0, 0)
+	return res
+}
@@ -0,0 +1,132 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/geo"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/internal"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+var cities = map[string]value.Point{
+	"london":     {Lat: 51.5074, Lon: -0.1278},
+	"greenwich":  {Lat: 51.4769, Lon: 0.0005},
+	"paris":      {Lat: 48.8566, Lon: 2.3522},
+	"new-york":   {Lat: 40.7128, Lon: -74.0060},
+	"sydney":     {Lat: -33.8688, Lon: 151.2093},
+	"suva":       {Lat: -18.1416, Lon: 178.4419},
+	"apia":       {Lat: -13.8333, Lon: -171.7500},
+	"longyear":   {Lat: 78.2232, Lon: 15.6267},
+	"quito":      {Lat: -0.1807, Lon: -78.4678},
+	"singapore":  {Lat: 1.3521, Lon: 103.8198},
+	"reykjavik":  {Lat: 64.1466, Lon: -21.9426},
+	"cape-town":  {Lat: -33.9249, Lon: 18.4241},
+	"buenos-air": {Lat: -34.6037, Lon: -58.3816},
+}
+
+func TestGeoIndex(t *testing.T) {
+	as := assert.New(t)
+
+	d := db.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("cities", column.Make("location"))
+		as.Nil(err)
+		as.Nil(tbl.CreateIndex(db.GeoIndex, "by-location", "location"))
+		for name, p := range cities {
+			as.Nil(tbl.Insert(value.Key(name), relation.Row{p}))
+		}
+		as.Nil(tbl.Insert(value.Key("nowhere"), relation.Row{nil}))
+
+		s, err := geo.Search(tbl, "by-location")
+		as.Nil(err)
+
+		as.Equal([]value.Key{
+			value.Key("greenwich"), value.Key("london"), value.Key("paris"),
+		}, s.Within(
+			value.Point{Lat: 45, Lon: -5}, value.Point{Lat: 55, Lon: 5},
+		).Keys())
+		as.Equal([]value.Key{value.Key("apia"), value.Key("suva")},
+			s.Within(
+				value.Point{Lat: -20, Lon: 170},
+				value.Point{Lat: -10, Lon: -170},
+			).Keys(),
+		)
+
+		near := s.Radius(cities["london"], 400000)
+		as.Equal([]value.Key{
+			value.Key("london"), value.Key("greenwich"), value.Key("paris"),
+		}, near.Keys())
+		as.Equal(0.0, near[0].Distance)
+		as.Equal(cities["greenwich"], near[1].Point)
+		as.InDelta(343500, near[2].Distance, 1000)
+
+		as.Equal([]value.Key{value.Key("suva"), value.Key("apia")},
+			s.Nearest(value.Point{Lat: -16, Lon: 179.9}, 2).Keys(),
+		)
+		as.Equal([]value.Key{value.Key("longyear")},
+			s.Nearest(value.Point{Lat: 90, Lon: 0}, 1).Keys(),
+		)
+		as.Equal(len(cities), len(s.Nearest(value.Point{}, 100)))
+
+		idx, err := tbl.Index("by-location")
+		as.Nil(err)
+		k, _, ok := iterate.First(
+			idx.EQ(relation.Relation{cities["paris"]}),
+		)
+		as.True(ok)
+		as.Equal(value.Key("paris"), k[len(k)-len("paris"):])
+
+		_, err = tbl.Update(value.Key("paris"), relation.Row{
+			value.Point{Lat: 51.5, Lon: -0.12},
+		})
+		as.Nil(err)
+		_, err = tbl.Delete(value.Key("greenwich"))
+		as.Nil(err)
+		as.Equal([]value.Key{value.Key("london"), value.Key("paris")},
+			s.Radius(cities["london"], 10000).Keys(),
+		)
+
+		as.EqualError(tbl.Insert(value.Key("bad"), relation.Row{
+			value.String("here"),
+		}), fmt.Sprintf(internal.ErrPointRequired, value.String("here")))
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestGeoIndexDefinition(t *testing.T) {
+	as := assert.New(t)
+
+	d := db.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("cities",
+			column.Make("name"),
+			column.Make("location"),
+		)
+		as.Nil(err)
+		as.EqualError(
+			tbl.CreateIndex(db.GeoIndex, "by-both", "name", "location"),
+			fmt.Sprintf(internal.ErrGeoIndexColumns, "by-both"),
+		)
+		as.EqualError(tbl.DefineIndex(index.Definition{
+			Name:       "by-location",
+			Type:       db.GeoIndex,
+			Columns:    column.Names{"location"},
+			Descending: column.Names{"location"},
+		}), fmt.Sprintf(internal.ErrGeoIndexColumns, "by-location"))
+
+		as.Nil(tbl.CreateIndex(db.StandardIndex, "by-name", "name"))
+		_, err = geo.Search(tbl, "by-name")
+		as.EqualError(err, fmt.Sprintf(geo.ErrNotSpatial, "by-name"))
+		return nil
+	})
+	as.Nil(err)
+}
@@ -1,8 +1,11 @@
 package internal
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
 
+	"github.com/caravan/db/expr"
 	"github.com/caravan/db/index"
 	"github.com/caravan/db/prefix"
 	"github.com/caravan/db/relation"
@@ -17,15 +20,65 @@ type (
 		prefix.Prefixed
 		name     index.Name
 		selector relation.Selector
+		desc     []bool
+		include  bool
 	}
 
 	baseIndex struct {
 		*indexInfo
-		txn transaction.Txn
+		txn      transaction.Txn
+		after    value.Key
+		reverse  bool
+		covering bool
 	}
 
+	// entryValues is a view of an Index's stored entries that converts
+	// each stored value into the form that the Index's lookups produce
+	entryValues struct {
+		transaction.Iterable
+		fn iterate.Mapper
+	}
+
+	// keyView is the state of a Query view over an Index whose lookups
+	// gather the primary Keys of their matches up front
+	keyView struct {
+		after    value.Key
+		reverse  bool
+		covering bool
+	}
+
+	// eqOnly provides the lookups of an Index that only supports EQ,
+	// each of which produces nothing
+	eqOnly struct{}
+
 	uniqueIndex   struct{ baseIndex }
 	standardIndex struct{ baseIndex }
+
+	// computedIndex makes sure that the Exprs of an Index's computed
+	// Columns can be evaluated for a row before it is inserted, as the
+	// Index's Selector can't report the failure
+	computedIndex struct {
+		index.Index
+		evals []expr.Evaluator
+	}
+
+	// partialIndex only passes the rows that satisfy its predicate on to
+	// the Index it wraps. Updating a row deletes its old version before
+	// inserting the new one, so the row moves in or out of the Index as
+	// the result of the predicate changes
+	partialIndex struct {
+		index.Index
+		where expr.Predicate
+	}
+)
+
+// Registered index.TypeNames
+const (
+	// UniqueIndex names the index.Type that allows only unique associations
+	UniqueIndex index.TypeName = "unique"
+
+	// StandardIndex names the index.Type that allows multiple associations
+	StandardIndex index.TypeName = "standard"
 )
 
 // Error messages
@@ -33,29 +86,83 @@ const (
 	ErrUniqueConstraintFailed = "unique constraint failed: %s"
 )
 
+func init() {
+	mustRegisterIndexType(UniqueIndex, uniqueIndexType)
+	mustRegisterIndexType(StandardIndex, standardIndexType)
+}
+
+func mustRegisterIndexType(n index.TypeName, t index.Type) {
+	if err := index.Register(n, t); err != nil {
+		panic(err)
+	}
+}
+
 func makeIndexInfo(
-	p prefix.Prefixed, n index.Name, s relation.Selector,
+	p prefix.Prefixed, d index.Definition, s relation.Selector,
 ) *indexInfo {
+	desc := make([]bool, len(d.Columns))
+	for i, c := range d.Columns {
+		desc[i] = d.IsDescending(c)
+	}
 	return &indexInfo{
-		name:     n,
+		name:     d.Name,
 		selector: s,
+		desc:     desc,
+		include:  d.IsCovering(),
 		Prefixed: p,
 	}
 }
 
 func (i *indexInfo) keysForValues(v ...value.Value) []value.Key {
 	var keys []value.Key
-	for _, cell := range i.selector(v) {
-		keys = append(keys, cell.Bytes())
+	for pos, cell := range i.selector(v)[:len(i.desc)] {
+		keys = append(keys, i.keyForValue(pos, cell))
 	}
 	return keys
 }
 
+// entryForRow returns the value that is stored in a row's entry: its
+// primary Key or, if the Index includes Columns, an Entry that also holds
+// the selected Values
+func (i *indexInfo) entryForRow(k value.Key, r relation.Row) any {
+	if !i.include {
+		return k
+	}
+	return index.Entry{Key: k, Values: i.selector(r)}
+}
+
 func (i *indexInfo) keyForValues(v ...value.Value) value.Key {
 	keys := i.keysForValues(v...)
 	return value.JoinKeys(keys...)
 }
 
+// keyForRelation encodes a Relation that has already been selected in the
+// order of the Index's Columns, such as the Relation provided to a query
+func (i *indexInfo) keyForRelation(r relation.Relation) value.Key {
+	keys := make([]value.Key, len(r))
+	for pos, cell := range r {
+		keys[pos] = i.keyForValue(pos, cell)
+	}
+	return value.JoinKeys(keys...)
+}
+
+// keyForValue encodes the Value of the Column at a position in the Index.
+// The encoding of a descending Column, including the tag of NULL, is
+// inverted, and terminated so that shorter values sort after the longer
+// values they are a prefix of. NULL therefore sorts last
+func (i *indexInfo) keyForValue(pos int, v value.Value) value.Key {
+	k := index.KeyForValue(v)
+	if pos >= len(i.desc) || !i.desc[pos] {
+		return k
+	}
+	res := make(value.Key, len(k)+1)
+	for j, b := range k {
+		res[j] = ^b
+	}
+	res[len(k)] = 0xFF
+	return res
+}
+
 func makeBaseIndex(info *indexInfo, txn transaction.Txn) baseIndex {
 	return baseIndex{
 		indexInfo: info,
@@ -63,46 +170,253 @@ func makeBaseIndex(info *indexInfo, txn transaction.Txn) baseIndex {
 	}
 }
 
+// Descending returns a view of the Index whose lookups produce entries in
+// reverse order
+func (i *baseIndex) Descending() index.Query {
+	res := *i
+	res.reverse = true
+	return &res
+}
+
+// Covering returns a view of the Index whose lookups produce an Entry for
+// each match, rather than its primary Key
+func (i *baseIndex) Covering() index.Query {
+	res := *i
+	res.covering = true
+	return &res
+}
+
+// After returns a view of the Index whose lookups only produce the
+// entries that follow the provided Key
+func (i *baseIndex) After(k value.Key) index.Query {
+	res := *i
+	res.after = k
+	return &res
+}
+
+// entries returns the Index's entries in the order of the view, beginning
+// after the Key of the view, if it has one
+func (i *baseIndex) entries() transaction.Iterable {
+	res := i.stored()
+	switch {
+	case i.covering && !i.include:
+		return &entryValues{Iterable: res, fn: keyEntry}
+	case !i.covering && i.include:
+		return &entryValues{Iterable: res, fn: entryKey}
+	default:
+		return res
+	}
+}
+
+func (i *baseIndex) stored() transaction.Iterable {
+	if i.reverse {
+		res := i.txn.For(i).Descending()
+		if i.after != nil {
+			return iterate.Before(res, i.after)
+		}
+		return res
+	}
+	res := i.txn.For(i).Ascending()
+	if i.after != nil {
+		return iterate.After(res, i.after)
+	}
+	return res
+}
+
+func keyEntry(_ value.Key, v any) any {
+	return index.Entry{Key: v.(value.Key)}
+}
+
+func entryKey(_ value.Key, v any) any {
+	return v.(index.Entry).Key
+}
+
+func (e *entryValues) All() transaction.Iterator {
+	return iterate.Map(e.Iterable.All(), e.fn)
+}
+
+func (e *entryValues) From(k value.Key) transaction.Iterator {
+	return iterate.Map(e.Iterable.From(k), e.fn)
+}
+
 func (i *baseIndex) Truncate() {
 	i.txn.For(i).Drop()
 }
 
+// EQ returns the entries whose leading Columns are equal to the Relation.
+// The Relation may provide fewer values than the Index has Columns
 func (i *baseIndex) EQ(r relation.Relation) transaction.Iterator {
-	pfx := i.keyForValues(r...)
-	iter := i.txn.For(i).Ascending().From(pfx)
+	pfx := i.keyForRelation(r)
+	var iter transaction.Iterator
+	if i.reverse {
+		iter = skipWhile(i.entries().From(limit(pfx)), above(pfx))
+	} else {
+		iter = i.entries().From(pfx)
+	}
 	return iterate.While(iter, func(k value.Key, _ any) bool {
-		return k.Compare(pfx) == value.EqualTo
+		return hasLeading(k, pfx)
 	})
 }
 
+// NEQ returns the entries whose leading Columns are not equal to the
+// Relation
 func (i *baseIndex) NEQ(r relation.Relation) transaction.Iterator {
-	pfx := i.keyForValues(r...)
-	iter := i.txn.For(i).Ascending().All()
-	return iterate.While(iter, func(k value.Key, _ any) bool {
-		return k.Compare(pfx) != value.EqualTo
-	})
+	if i.reverse {
+		return iterate.Concat(i.GT(r), i.LT(r))
+	}
+	return iterate.Concat(i.LT(r), i.GT(r))
 }
 
+// LT returns the entries whose leading Columns are less than the Relation
 func (i *baseIndex) LT(r relation.Relation) transaction.Iterator {
-	pfx := i.keyForValues(r...)
-	iter := i.txn.For(i).Ascending().All()
-	return iterate.While(iter, func(k value.Key, _ any) bool {
+	pfx := i.keyForRelation(r)
+	if i.reverse {
+		return skipWhile(i.entries().From(pfx), func(k value.Key, _ any) bool {
+			return k.Compare(pfx) == value.EqualTo
+		})
+	}
+	return iterate.While(i.entries().All(), func(k value.Key, _ any) bool {
 		return k.Compare(pfx) == value.LessThan
 	})
 }
 
+// LTE returns the entries whose leading Columns are less than or equal to
+// the Relation
+func (i *baseIndex) LTE(r relation.Relation) transaction.Iterator {
+	pfx := i.keyForRelation(r)
+	if i.reverse {
+		return skipWhile(i.entries().From(limit(pfx)), above(pfx))
+	}
+	return iterate.While(i.entries().All(), func(k value.Key, _ any) bool {
+		return k.Compare(pfx) == value.LessThan || hasLeading(k, pfx)
+	})
+}
+
+// GT returns the entries whose leading Columns are greater than the
+// Relation
 func (i *baseIndex) GT(r relation.Relation) transaction.Iterator {
-	pfx := i.keyForValues(r...)
-	iter := i.txn.For(i).Ascending().All()
-	return iterate.While(iter, func(k value.Key, _ any) bool {
-		return k.Compare(pfx) == value.GreaterThan
+	pfx := i.keyForRelation(r)
+	if i.reverse {
+		return iterate.While(i.entries().All(), above(pfx))
+	}
+	return skipWhile(i.entries().From(pfx), func(k value.Key, _ any) bool {
+		return hasLeading(k, pfx)
+	})
+}
+
+// GTE returns the entries whose leading Columns are greater than or equal
+// to the Relation
+func (i *baseIndex) GTE(r relation.Relation) transaction.Iterator {
+	pfx := i.keyForRelation(r)
+	if i.reverse {
+		return iterate.While(i.entries().All(), func(k value.Key, _ any) bool {
+			return k.Compare(pfx) != value.LessThan
+		})
+	}
+	return i.entries().From(pfx)
+}
+
+// limit returns a Key that follows every entry with the prefix's leading
+// Columns, so that a descending iteration can begin there
+func limit(pfx value.Key) value.Key {
+	return append(pfx[:len(pfx):len(pfx)], 1)
+}
+
+// above returns a Predicate matching the entries that are greater than the
+// prefix without having it as their leading Columns
+func above(pfx value.Key) iterate.Predicate {
+	return func(k value.Key, _ any) bool {
+		return k.Compare(pfx) == value.GreaterThan && !hasLeading(k, pfx)
+	}
+}
+
+// hasLeading returns whether an entry's Key starts with the complete
+// encoded Columns of the prefix, rather than a partial value
+func hasLeading(k value.Key, pfx value.Key) bool {
+	if len(k) == len(pfx) {
+		return k.Compare(pfx) == value.EqualTo
+	}
+	return len(k) > len(pfx) && k[len(pfx)] == 0 &&
+		k[:len(pfx)].Compare(pfx) == value.EqualTo
+}
+
+func skipWhile(
+	iter transaction.Iterator, fn iterate.Predicate,
+) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		k, v, next, ok := iter()
+		for ok && fn(k, v) {
+			k, v, next, ok = next()
+		}
+		return k, v, next, ok
+	}
+}
+
+// lookup produces the gathered primary Keys in the order of the view,
+// beginning after the Key of the view, if it has one
+func (v keyView) lookup(keys []value.Key) transaction.Iterator {
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, func(l, r value.Key) int {
+		return bytes.Compare(l, r)
+	})
+	if v.reverse {
+		slices.Reverse(keys)
+	}
+	return iterate.FromSeq(func(yield func(value.Key, any) bool) {
+		for _, k := range keys {
+			if v.after != nil && !v.follows(k) {
+				continue
+			}
+			var e any = k
+			if v.covering {
+				e = index.Entry{Key: k}
+			}
+			if !yield(k, e) {
+				return
+			}
+		}
 	})
 }
 
-// UniqueIndex is an index.Type that allows only unique associations
-var UniqueIndex = index.Type(
-	func(p prefix.Prefixed, n index.Name, s relation.Selector) index.Constructor {
-		info := makeIndexInfo(p, n, s)
+// follows returns whether a Key follows the Key of the view, in the order
+// of the view
+func (v keyView) follows(k value.Key) bool {
+	if v.reverse {
+		return bytes.Compare(k, v.after) < 0
+	}
+	return bytes.Compare(k, v.after) > 0
+}
+
+func (eqOnly) NEQ(relation.Relation) transaction.Iterator {
+	return noEntries
+}
+
+func (eqOnly) GT(relation.Relation) transaction.Iterator {
+	return noEntries
+}
+
+func (eqOnly) GTE(relation.Relation) transaction.Iterator {
+	return noEntries
+}
+
+func (eqOnly) LT(relation.Relation) transaction.Iterator {
+	return noEntries
+}
+
+func (eqOnly) LTE(relation.Relation) transaction.Iterator {
+	return noEntries
+}
+
+func noEntries() (value.Key, any, transaction.Iterator, bool) {
+	return nil, nil, nil, false
+}
+
+// uniqueIndexType is an index.Type that allows only unique associations
+var uniqueIndexType = index.Type(
+	func(
+		p prefix.Prefixed, d index.Definition, s relation.Selector,
+	) index.Constructor {
+		info := makeIndexInfo(p, d, s)
 		return func(txn transaction.Txn) index.Index {
 			return &uniqueIndex{
 				baseIndex: makeBaseIndex(info, txn),
@@ -111,26 +425,40 @@ var UniqueIndex = index.Type(
 	},
 )
 
+// Insert adds a row's entry, failing if another row has the same Values.
+// As in SQL, rows that have a NULL Value never conflict, so their entries
+// are also keyed by the row's Key
 func (w *uniqueIndex) Insert(k value.Key, r relation.Row) error {
 	idx := w.txn.For(w)
-	key := w.keyForValues(r...)
+	key := w.keyForRow(k, r)
 	if _, ok := idx.Get(key); ok {
 		return fmt.Errorf(ErrUniqueConstraintFailed, w.name)
 	}
-	idx.Insert(key, k)
+	idx.Insert(key, w.entryForRow(k, r))
 	return nil
 }
 
-func (w *uniqueIndex) Delete(_ value.Key, r relation.Row) bool {
-	key := w.keyForValues(r...)
-	_, ok := w.txn.For(w).Delete(key)
+func (w *uniqueIndex) Delete(k value.Key, r relation.Row) bool {
+	_, ok := w.txn.For(w).Delete(w.keyForRow(k, r))
 	return ok
 }
 
-// StandardIndex is an index.Type that allows multiple associations
-var StandardIndex = index.Type(
-	func(p prefix.Prefixed, n index.Name, s relation.Selector) index.Constructor {
-		info := makeIndexInfo(p, n, s)
+func (w *uniqueIndex) keyForRow(k value.Key, r relation.Row) value.Key {
+	keys := w.keysForValues(r...)
+	for _, v := range w.selector(r)[:len(w.desc)] {
+		if v == nil {
+			return value.JoinKeys(append(keys, k)...)
+		}
+	}
+	return value.JoinKeys(keys...)
+}
+
+// standardIndexType is an index.Type that allows multiple associations
+var standardIndexType = index.Type(
+	func(
+		p prefix.Prefixed, d index.Definition, s relation.Selector,
+	) index.Constructor {
+		info := makeIndexInfo(p, d, s)
 		return func(txn transaction.Txn) index.Index {
 			return &standardIndex{
 				baseIndex: makeBaseIndex(info, txn),
@@ -142,7 +470,7 @@ var StandardIndex = index.Type(
 func (i *standardIndex) Insert(k value.Key, r relation.Row) error {
 	keys := append(i.keysForValues(r...), k)
 	key := value.JoinKeys(keys...)
-	i.txn.For(i).Insert(key, k)
+	i.txn.For(i).Insert(key, i.entryForRow(k, r))
 	return nil
 }
 
@@ -152,3 +480,26 @@ func (i *standardIndex) Delete(k value.Key, r relation.Row) bool {
 	_, ok := i.txn.For(i).Delete(key)
 	return ok
 }
+
+func (i *computedIndex) Insert(k value.Key, r relation.Row) error {
+	for _, eval := range i.evals {
+		if _, err := eval(r); err != nil {
+			return err
+		}
+	}
+	return i.Index.Insert(k, r)
+}
+
+func (i *partialIndex) Insert(k value.Key, r relation.Row) error {
+	if ok, err := i.where(r); err != nil || !ok {
+		return err
+	}
+	return i.Index.Insert(k, r)
+}
+
+func (i *partialIndex) Delete(k value.Key, r relation.Row) bool {
+	if ok, err := i.where(r); err != nil || !ok {
+		return false
+	}
+	return i.Index.Delete(k, r)
+}
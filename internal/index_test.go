@@ -4,8 +4,15 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
 	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/index"
 	"github.com/caravan/db/internal"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/transaction/iterate"
 	"github.com/caravan/db/value"
 	"github.com/stretchr/testify/assert"
 )
@@ -43,3 +50,400 @@ func TestUniqueIndexUpdate(t *testing.T) {
 		fmt.Sprintf(internal.ErrUniqueConstraintFailed, "unique-index"),
 	)
 }
+
+func TestIndexNullValues(t *testing.T) {
+	as := assert.New(t)
+
+	d, err := internal.NewDatabase()(func(d database.Database) error {
+		tbl, err := d.CreateTable("flags",
+			column.Make("name"), column.Make("flag"),
+		)
+		as.Nil(err)
+		as.Nil(tbl.CreateIndex(db.UniqueIndex, "by-name", "name"))
+		as.Nil(tbl.DefineIndex(index.Definition{
+			Name:       "by-flag",
+			Type:       db.UniqueIndex,
+			Columns:    column.Names{"flag"},
+			Descending: column.Names{"flag"},
+		}))
+
+		empty := value.NewKey()
+		as.Nil(tbl.Insert(empty, relation.Row{
+			value.String(""), value.Bool(false),
+		}))
+		as.Nil(tbl.Insert(value.NewKey(), relation.Row{nil, nil}))
+		as.Nil(tbl.Insert(value.NewKey(), relation.Row{nil, nil}))
+
+		byName, _ := tbl.Index("by-name")
+		as.Equal(2, iterate.Count(byName.EQ(relation.Relation{nil})))
+		_, k, ok := iterate.First(
+			byName.EQ(relation.Relation{value.String("")}),
+		)
+		as.True(ok)
+		as.Equal(empty, k)
+
+		byFlag, _ := tbl.Index("by-flag")
+		as.Equal(1, iterate.Count(
+			byFlag.EQ(relation.Relation{value.Bool(false)}),
+		))
+		as.Equal(2, iterate.Count(byFlag.EQ(relation.Relation{nil})))
+		return nil
+	})
+	as.Nil(err)
+
+	_, err = d(func(d database.Database) error {
+		tbl, _ := d.Table("flags")
+		return tbl.Insert(value.NewKey(), relation.Row{value.String(""), nil})
+	})
+	as.EqualError(err,
+		fmt.Sprintf(internal.ErrUniqueConstraintFailed, "by-name"),
+	)
+}
+
+func TestIndexQuery(t *testing.T) {
+	as := assert.New(t)
+
+	d := internal.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("scores",
+			column.Make("team"),
+			column.Make("score"),
+		)
+		as.Nil(err)
+		as.Nil(tbl.CreateIndex(db.StandardIndex, "by-team", "team", "score"))
+
+		rows := []relation.Row{
+			{value.String("a"), value.Integer(-5)},
+			{value.String("a"), value.Integer(3)},
+			{value.String("a"), value.Integer(3)},
+			{value.String("ab"), value.Integer(1)},
+			{value.String("b"), value.Integer(0)},
+		}
+		for _, r := range rows {
+			as.Nil(tbl.Insert(value.NewKey(), r))
+		}
+
+		idx, err := tbl.Index("by-team")
+		as.Nil(err)
+
+		count := func(iter transaction.Iterator) int {
+			var res int
+			_ = iterate.ForEach(iter, func(value.Key, any) error {
+				res++
+				return nil
+			})
+			return res
+		}
+
+		a := relation.Relation{value.String("a")}
+		a3 := relation.Relation{value.String("a"), value.Integer(3)}
+		as.Equal(3, count(idx.EQ(a)))
+		as.Equal(2, count(idx.NEQ(a)))
+		as.Equal(2, count(idx.GT(a)))
+		as.Equal(5, count(idx.GTE(a)))
+		as.Equal(0, count(idx.LT(a)))
+		as.Equal(3, count(idx.LTE(a)))
+
+		as.Equal(2, count(idx.EQ(a3)))
+		as.Equal(1, count(idx.LT(a3)))
+		as.Equal(2, count(idx.GT(a3)))
+		as.Equal(4, count(idx.GTE(a3)))
+		as.Equal(3, count(idx.LTE(a3)))
+
+		_, err = tbl.Index("missing")
+		as.EqualError(err, fmt.Sprintf(internal.ErrIndexNotFound, "missing"))
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestIndexDescending(t *testing.T) {
+	as := assert.New(t)
+
+	d := internal.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("scores",
+			column.Make("team"),
+			column.Make("score"),
+		)
+		as.Nil(err)
+		as.Nil(tbl.DefineIndex(index.Definition{
+			Name:       "by-team",
+			Type:       db.StandardIndex,
+			Columns:    column.Names{"team", "score"},
+			Descending: column.Names{"score"},
+		}))
+
+		rows := []relation.Row{
+			{value.String("a"), value.Integer(-5)},
+			{value.String("a"), value.Integer(3)},
+			{value.String("a"), nil},
+			{value.String("ab"), value.Integer(1)},
+			{value.String("b"), value.Integer(0)},
+			{value.String("b"), value.Integer(10)},
+		}
+		for _, r := range rows {
+			as.Nil(tbl.Insert(value.NewKey(), r))
+		}
+
+		idx, err := tbl.Index("by-team")
+		as.Nil(err)
+
+		scores := func(iter transaction.Iterator) []value.Value {
+			var res []value.Value
+			_ = iterate.ForEach(iter, func(_ value.Key, v any) error {
+				row, _ := tbl.Select(v.(value.Key))
+				res = append(res, row[1])
+				return nil
+			})
+			return res
+		}
+
+		a := relation.Relation{value.String("a")}
+		a3 := relation.Relation{value.String("a"), value.Integer(3)}
+		as.Equal([]value.Value{
+			value.Integer(3), value.Integer(-5), nil,
+		}, scores(idx.EQ(a)))
+		as.Equal([]value.Value{
+			nil, value.Integer(-5), value.Integer(3),
+		}, scores(idx.Descending().EQ(a)))
+		as.Equal([]value.Value{
+			value.Integer(-5), nil, value.Integer(1),
+			value.Integer(10), value.Integer(0),
+		}, scores(idx.GT(a3)))
+		as.Equal([]value.Value{
+			value.Integer(3),
+		}, scores(idx.Descending().LTE(a3)))
+
+		reverse := func(v []value.Value) []value.Value {
+			var res []value.Value
+			for i := len(v) - 1; i >= 0; i-- {
+				res = append(res, v[i])
+			}
+			return res
+		}
+
+		desc := idx.Descending()
+		for _, r := range []relation.Relation{a, a3} {
+			as.Equal(reverse(scores(idx.EQ(r))), scores(desc.EQ(r)))
+			as.Equal(reverse(scores(idx.NEQ(r))), scores(desc.NEQ(r)))
+			as.Equal(reverse(scores(idx.LT(r))), scores(desc.LT(r)))
+			as.Equal(reverse(scores(idx.LTE(r))), scores(desc.LTE(r)))
+			as.Equal(reverse(scores(idx.GT(r))), scores(desc.GT(r)))
+			as.Equal(reverse(scores(idx.GTE(r))), scores(desc.GTE(r)))
+		}
+
+		err = tbl.DefineIndex(index.Definition{
+			Name:       "by-score",
+			Type:       db.StandardIndex,
+			Columns:    column.Names{"score"},
+			Descending: column.Names{"team"},
+		})
+		as.EqualError(err, fmt.Sprintf(index.ErrDescendingColumn, "team"))
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestPartialIndex(t *testing.T) {
+	as := assert.New(t)
+
+	d := internal.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("users",
+			column.Make("name"),
+			column.Make("active"),
+		)
+		as.Nil(err)
+
+		bill := value.Key("bill")
+		as.Nil(tbl.Insert(bill, relation.Row{
+			value.String("Bill"), value.Bool(true),
+		}))
+		as.Nil(tbl.Insert(value.Key("ted"), relation.Row{
+			value.String("Ted"), value.Bool(false),
+		}))
+		as.Nil(tbl.Insert(value.Key("rufus"), relation.Row{
+			value.String("Bill"), nil,
+		}))
+
+		as.Nil(tbl.DefineIndex(index.Definition{
+			Name:    "active-names",
+			Type:    db.UniqueIndex,
+			Columns: column.Names{"name"},
+			Where:   expr.EQ(expr.Col("active"), expr.Lit(value.Bool(true))),
+		}))
+		idx, err := tbl.Index("active-names")
+		as.Nil(err)
+
+		all := relation.Relation{}
+		as.Equal(1, iterate.Count(idx.GTE(all)))
+
+		_, err = tbl.Update(bill, relation.Row{
+			value.String("Bill"), value.Bool(false),
+		})
+		as.Nil(err)
+		as.Equal(0, iterate.Count(idx.GTE(all)))
+
+		_, err = tbl.Update(value.Key("ted"), relation.Row{
+			value.String("Ted"), value.Bool(true),
+		})
+		as.Nil(err)
+		_, err = tbl.Update(bill, relation.Row{
+			value.String("Bill"), value.Bool(true),
+		})
+		as.Nil(err)
+		as.Equal(2, iterate.Count(idx.GTE(all)))
+
+		_, err = tbl.Delete(bill)
+		as.Nil(err)
+		_, v, ok := iterate.First(idx.GTE(all))
+		as.True(ok)
+		as.Equal(value.Key("ted"), v)
+
+		as.EqualError(tbl.DropColumn("active"),
+			fmt.Sprintf(internal.ErrColumnIndexed, "active-names"),
+		)
+		as.Nil(tbl.RenameColumn("active", "enabled"))
+		def, _ := tbl.IndexInfo("active-names")
+		as.Equal(column.Names{"enabled"}, def.Where.Columns())
+
+		err = tbl.DefineIndex(index.Definition{
+			Name:    "missing",
+			Type:    db.StandardIndex,
+			Columns: column.Names{"name"},
+			Where:   expr.IsNull(expr.Col("missing")),
+		})
+		as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestComputedIndex(t *testing.T) {
+	as := assert.New(t)
+
+	d := internal.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("users",
+			column.Make("email"),
+			column.Make("name"),
+		)
+		as.Nil(err)
+
+		lower := expr.Call("lower", expr.Col("email"))
+		as.Nil(tbl.DefineIndex(index.Definition{
+			Name:     "by-email",
+			Type:     db.UniqueIndex,
+			Columns:  column.Names{"email_lower"},
+			Computed: index.Computed{"email_lower": lower},
+		}))
+
+		bill := value.Key("bill")
+		as.Nil(tbl.Insert(bill, relation.Row{
+			value.String("Bill@Example.com"), value.String("Bill"),
+		}))
+		err = tbl.Insert(value.Key("other"), relation.Row{
+			value.String("bill@example.COM"), value.String("Other Bill"),
+		})
+		as.EqualError(err,
+			fmt.Sprintf(internal.ErrUniqueConstraintFailed, "by-email"),
+		)
+		err = tbl.Insert(value.Key("ted"), relation.Row{
+			value.Integer(42), value.String("Ted"),
+		})
+		as.EqualError(err, fmt.Sprintf(expr.ErrBadArguments, "lower"))
+
+		idx, err := tbl.Index("by-email")
+		as.Nil(err)
+		_, v, ok := iterate.First(idx.EQ(relation.Relation{
+			value.String("bill@example.com"),
+		}))
+		as.True(ok)
+		as.Equal(bill, v)
+
+		as.EqualError(tbl.DropColumn("email"),
+			fmt.Sprintf(internal.ErrColumnIndexed, "by-email"),
+		)
+		as.Nil(tbl.DropColumn("name"))
+		as.Nil(tbl.RenameColumn("email", "address"))
+		def, _ := tbl.IndexInfo("by-email")
+		as.Equal(column.Names{"email_lower"}, def.Columns)
+		as.Equal("lower(address)", def.Computed["email_lower"].String())
+
+		err = tbl.DefineIndex(index.Definition{
+			Name:     "unindexed",
+			Type:     db.StandardIndex,
+			Columns:  column.Names{"address"},
+			Computed: index.Computed{"email_lower": lower},
+		})
+		as.EqualError(err,
+			fmt.Sprintf(index.ErrComputedColumn, "email_lower"),
+		)
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestCoveringIndex(t *testing.T) {
+	as := assert.New(t)
+
+	d := internal.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("users",
+			column.Make("email"),
+			column.Make("name"),
+			column.Make("age"),
+		)
+		as.Nil(err)
+		as.Nil(tbl.DefineIndex(index.Definition{
+			Name:    "by-email",
+			Type:    db.UniqueIndex,
+			Columns: column.Names{"email"},
+			Include: column.Names{"name"},
+		}))
+		as.Nil(tbl.CreateIndex(db.StandardIndex, "by-age", "age"))
+
+		bill := value.Key("bill")
+		as.Nil(tbl.Insert(bill, relation.Row{
+			value.String("bill@example.com"), value.String("Bill"),
+			value.Integer(17),
+		}))
+
+		idx, err := tbl.Index("by-email")
+		as.Nil(err)
+		email := relation.Relation{value.String("bill@example.com")}
+		_, v, ok := iterate.First(idx.EQ(email))
+		as.True(ok)
+		as.Equal(bill, v)
+
+		_, v, ok = iterate.First(idx.Covering().EQ(email))
+		as.True(ok)
+		as.Equal(index.Entry{
+			Key: bill,
+			Values: relation.Relation{
+				value.String("bill@example.com"), value.String("Bill"),
+			},
+		}, v)
+
+		_, err = tbl.Update(bill, relation.Row{
+			value.String("bill@example.com"), value.String("William"),
+			value.Integer(18),
+		})
+		as.Nil(err)
+		_, v, _ = iterate.First(idx.Covering().Descending().EQ(email))
+		as.Equal(value.String("William"), v.(index.Entry).Values[1])
+
+		idx, err = tbl.Index("by-age")
+		as.Nil(err)
+		_, v, ok = iterate.First(idx.Covering().GTE(relation.Relation{}))
+		as.True(ok)
+		as.Equal(index.Entry{Key: bill}, v)
+
+		as.EqualError(tbl.DropColumn("name"),
+			fmt.Sprintf(internal.ErrColumnIndexed, "by-email"),
+		)
+		return nil
+	})
+	as.Nil(err)
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"bytes"
 	"sync"
 
 	"github.com/caravan/db/prefix"
@@ -26,6 +27,18 @@ func (s iterable) start() int {
 	return len(s.Prefix().Bytes()) + 1
 }
 
+// bounded stops a resolver once it produces a key outside of the prefix
+func (s iterable) bounded(fn resolver) resolver {
+	pfx := append(s.Prefix().Bytes(), 0)
+	return func() (value.Key, any, bool) {
+		k, v, ok := fn()
+		if !ok || !bytes.HasPrefix(k, pfx) {
+			return nil, nil, false
+		}
+		return k, v, true
+	}
+}
+
 func (s iterable) resolved(fn resolver) transaction.Iterator {
 	var once sync.Once
 	var k value.Key
@@ -70,9 +83,9 @@ func (f *forwardIterable) All() transaction.Iterator {
 func (f *forwardIterable) From(k value.Key) transaction.Iterator {
 	iter := f.Txn.Root().Iterator()
 	iter.SeekLowerBound(f.Prefix().WithKey(k))
-	return f.resolved(func() (value.Key, any, bool) {
+	return f.resolved(f.bounded(func() (value.Key, any, bool) {
 		return iter.Next()
-	})
+	}))
 }
 
 // ReverseIterable constructs a descending iterable interface
@@ -98,7 +111,7 @@ func (r *reverseIterable) All() transaction.Iterator {
 func (r *reverseIterable) From(k value.Key) transaction.Iterator {
 	iter := r.Txn.Root().ReverseIterator()
 	iter.SeekReverseLowerBound(r.Prefix().WithKey(k))
-	return r.resolved(func() (value.Key, any, bool) {
+	return r.resolved(r.bounded(func() (value.Key, any, bool) {
 		return iter.Previous()
-	})
+	}))
 }
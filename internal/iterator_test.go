@@ -133,3 +133,29 @@ func TestReverseIterableFrom(t *testing.T) {
 	as.Nil(v)
 	as.Nil(next)
 }
+
+func TestIterableFromBounded(t *testing.T) {
+	as := assert.New(t)
+
+	pfx, tree := makeIterableTree()
+	txn := tree.Txn()
+	txn.Insert(pfx.Next().WithKey(value.Integer(9).Bytes()), 5)
+
+	next := internal.
+		ForwardIterable(pfx, txn).
+		From(value.Integer(8).Bytes())
+	_, v, next, ok := next()
+	as.True(ok)
+	as.Equal(4, v)
+	_, _, _, ok = next()
+	as.False(ok)
+
+	next = internal.
+		ReverseIterable(pfx.Next(), txn).
+		From(value.Integer(9).Bytes())
+	_, v, next, ok = next()
+	as.True(ok)
+	as.Equal(5, v)
+	_, _, _, ok = next()
+	as.False(ok)
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+// Error messages
+const (
+	ErrUnknownKeyType = "unknown key type: %s"
+	ErrKeyColumns     = "key type doesn't use columns: %s"
+	ErrNoKeyColumns   = "key type requires columns: %s"
+	ErrNullKeyColumn  = "key column is null: %s"
+	ErrColumnKeyed    = "column is used by table's key: %s"
+	ErrKeyColumnMoved = "update would change key column: %s"
+)
+
+var tableSeqKey = value.Key("table")
+
+// KeyStrategy returns the strategy the table uses to generate Keys
+func (t *tableTxr) KeyStrategy() table.KeyStrategy {
+	return t.Keys
+}
+
+// SetKeyStrategy changes the strategy the table uses to generate Keys
+// for InsertAuto. Keys that have already been stored are unaffected
+func (t *tableTxr) SetKeyStrategy(k table.KeyStrategy) error {
+	switch k.Type {
+	case table.RandomKeys, table.SequenceKeys, table.ULIDKeys:
+		if len(k.Columns) != 0 {
+			return fmt.Errorf(ErrKeyColumns, k.Type)
+		}
+	case table.CompositeKeys:
+		if len(k.Columns) == 0 {
+			return fmt.Errorf(ErrNoKeyColumns, k.Type)
+		}
+		if _, err := relation.MakeOffsets(t.columns, k.Columns...); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf(ErrUnknownKeyType, k.Type)
+	}
+
+	def := t.tableDef
+	def.Keys = k
+	t.storeTableDef(def)
+	return nil
+}
+
+// InsertAuto inserts a Row using a Key generated by the table's
+// KeyStrategy, and returns that Key
+func (t *tableTxr) InsertAuto(r relation.Row) (value.Key, error) {
+	k, err := t.nextKey(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Insert(k, r); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (t *tableTxr) nextKey(r relation.Row) (value.Key, error) {
+	switch t.Keys.Type {
+	case table.SequenceKeys:
+		return t.nextSequence(t.tableDef.Name).Bytes(), nil
+	case table.ULIDKeys:
+		return value.NewULID(), nil
+	case table.CompositeKeys:
+		return t.compositeKey(r)
+	default:
+		return value.NewKey(), nil
+	}
+}
+
+func (t *tableTxr) compositeKey(r relation.Row) (value.Key, error) {
+	off, err := relation.MakeOffsets(t.columns, t.Keys.Columns...)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]value.Key, len(off))
+	for i, o := range off {
+		if r[o] == nil {
+			return nil, fmt.Errorf(ErrNullKeyColumn, t.Keys.Columns[i])
+		}
+		keys[i] = r[o].Bytes()
+	}
+	return value.JoinKeys(keys...), nil
+}
+
+// checkKeyColumns makes sure that an update doesn't change the Values of
+// the Columns that a composite Key is derived from, as the row would
+// otherwise remain stored under a Key that no longer matches it
+func (t *tableTxr) checkKeyColumns(old, r relation.Row) error {
+	if t.Keys.Type != table.CompositeKeys {
+		return nil
+	}
+	off, err := relation.MakeOffsets(t.columns, t.Keys.Columns...)
+	if err != nil {
+		return err
+	}
+	for i, o := range off {
+		l, r := old[o], r[o]
+		if l == nil && r == nil {
+			continue
+		}
+		if l == nil || r == nil || l.Compare(r) != value.EqualTo {
+			return fmt.Errorf(ErrKeyColumnMoved, t.Keys.Columns[i])
+		}
+	}
+	return nil
+}
+
+// nextSequence increments and returns a table's integer sequence, which is
+// stored in the same space as the Database's Prefix sequence
+func (db *dbTxr) nextSequence(n table.Name) value.Integer {
+	sequence := db.txn.For(db.sequence)
+	key := tableSeqKey.WithKeys(value.Key(n))
+	next := value.Integer(1)
+	if stored, ok := sequence.Get(key); ok {
+		next = stored.(value.Integer) + 1
+	}
+	sequence.Insert(key, next)
+	return next
+}
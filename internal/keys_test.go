@@ -0,0 +1,186 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/internal"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetKeyStrategy(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		as.Equal(table.KeyStrategy{Type: table.RandomKeys}, tbl.KeyStrategy())
+
+		err := tbl.SetKeyStrategy(table.KeyStrategy{Type: "explode"})
+		as.EqualError(err, fmt.Sprintf(internal.ErrUnknownKeyType, "explode"))
+
+		err = tbl.SetKeyStrategy(table.KeyStrategy{
+			Type:    table.SequenceKeys,
+			Columns: column.Names{"first"},
+		})
+		as.EqualError(err, fmt.Sprintf(internal.ErrKeyColumns, "sequence"))
+
+		err = tbl.SetKeyStrategy(table.KeyStrategy{Type: table.CompositeKeys})
+		as.EqualError(err, fmt.Sprintf(internal.ErrNoKeyColumns, "composite"))
+
+		err = tbl.SetKeyStrategy(table.KeyStrategy{
+			Type:    table.CompositeKeys,
+			Columns: column.Names{"missing"},
+		})
+		as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+		as.Nil(tbl.SetKeyStrategy(table.KeyStrategy{Type: table.ULIDKeys}))
+		as.Equal(table.KeyStrategy{Type: table.ULIDKeys}, tbl.KeyStrategy())
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestInsertAutoRandom(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		k, err := tbl.InsertAuto(tableRow3)
+		as.Nil(err)
+		as.Equal(16, len(k))
+
+		row, ok := tbl.Select(k)
+		as.True(ok)
+		as.Equal(tableRow3, row)
+
+		_, err = tbl.InsertAuto(tableRow3)
+		as.EqualError(err,
+			fmt.Sprintf(internal.ErrUniqueConstraintFailed, "unique-index"),
+		)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestInsertAutoSequence(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		as.Nil(tbl.SetKeyStrategy(table.KeyStrategy{Type: table.SequenceKeys}))
+
+		k, err := tbl.InsertAuto(tableRow3)
+		as.Nil(err)
+		as.Equal(value.Key(value.Integer(1).Bytes()), k)
+
+		other, err := d.CreateTable("other-table", column.Make("col"))
+		as.Nil(err)
+		as.Nil(other.SetKeyStrategy(table.KeyStrategy{Type: table.SequenceKeys}))
+		k, err = other.InsertAuto(relation.Row{value.String("value")})
+		as.Nil(err)
+		as.Equal(value.Key(value.Integer(1).Bytes()), k)
+		return nil
+	})
+	as.Nil(err)
+
+	d, err = d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		k, err := tbl.InsertAuto(relation.Row{
+			value.String("seventh str"), value.String("eighth str"),
+		})
+		as.Nil(err)
+		as.Equal(value.Key(value.Integer(2).Bytes()), k)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestInsertAutoULID(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		as.Nil(tbl.SetKeyStrategy(table.KeyStrategy{Type: table.ULIDKeys}))
+
+		k1, err := tbl.InsertAuto(tableRow3)
+		as.Nil(err)
+		k2, err := tbl.InsertAuto(relation.Row{
+			value.String("seventh str"), value.String("eighth str"),
+		})
+		as.Nil(err)
+		as.Equal(value.LessThan, k1.Compare(k2))
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestInsertAutoComposite(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+		as.Nil(tbl.SetKeyStrategy(table.KeyStrategy{
+			Type:    table.CompositeKeys,
+			Columns: column.Names{"second", "first"},
+		}))
+
+		k, err := tbl.InsertAuto(tableRow3)
+		as.Nil(err)
+		as.Equal(value.JoinKeys(
+			value.Key("sixth str"), value.Key("fifth str"),
+		), k)
+
+		_, err = tbl.InsertAuto(tableRow3)
+		as.EqualError(err, fmt.Sprintf(internal.ErrKeyAlreadyExists, k))
+
+		_, err = tbl.InsertAuto(relation.Row{value.String("first"), nil})
+		as.EqualError(err, fmt.Sprintf(internal.ErrNullKeyColumn, "second"))
+
+		err = tbl.DropColumn("second")
+		as.EqualError(err, fmt.Sprintf(internal.ErrColumnIndexed, "unique-index"))
+
+		as.Nil(tbl.RenameColumn("second", "other"))
+		as.Equal(column.Names{"other", "first"}, tbl.KeyStrategy().Columns)
+
+		keyed, _ := d.CreateTable("keyed-table",
+			column.Make("id"), column.Make("name"),
+		)
+		as.Nil(keyed.SetKeyStrategy(table.KeyStrategy{
+			Type:    table.CompositeKeys,
+			Columns: column.Names{"id"},
+		}))
+		k, err = keyed.InsertAuto(relation.Row{
+			value.Integer(1), value.String("bill"),
+		})
+		as.Nil(err)
+		_, err = keyed.Update(k, relation.Row{
+			value.Integer(1), value.String("ted"),
+		})
+		as.Nil(err)
+		_, err = keyed.Update(k, relation.Row{
+			value.Integer(2), value.String("ted"),
+		})
+		as.EqualError(err, fmt.Sprintf(internal.ErrKeyColumnMoved, "id"))
+		_, err = keyed.Update(k, relation.Row{nil, value.String("ted")})
+		as.EqualError(err, fmt.Sprintf(internal.ErrKeyColumnMoved, "id"))
+		err = keyed.DropColumn("id")
+		as.EqualError(err, fmt.Sprintf(internal.ErrColumnKeyed, "keyed-table"))
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
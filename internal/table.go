@@ -2,25 +2,43 @@ package internal
 
 import (
 	"fmt"
+	"iter"
 
 	"github.com/caravan/db/column"
+	"github.com/caravan/db/expr"
 	"github.com/caravan/db/index"
 	"github.com/caravan/db/prefix"
 	"github.com/caravan/db/relation"
 	"github.com/caravan/db/table"
+	"github.com/caravan/db/transaction"
 	"github.com/caravan/db/transaction/iterate"
 	"github.com/caravan/db/value"
 )
 
 type (
+	// tableDef is the serializable catalog entry for a Table
+	tableDef struct {
+		Name        table.Name        `json:"name"`
+		Columns     column.Names      `json:"columns"`
+		Types       column.Types      `json:"types,omitempty"`
+		Keys        table.KeyStrategy `json:"keys"`
+		ForeignKeys table.ForeignKeys `json:"foreignKeys,omitempty"`
+		Checks      table.Checks      `json:"checks,omitempty"`
+		IndexData   prefix.Prefix     `json:"indexData"`
+		RowData     prefix.Prefix     `json:"rowData"`
+	}
+
+	// indexDef is the serializable catalog entry for an Index
+	indexDef struct {
+		index.Definition
+		Data prefix.Prefix `json:"data"`
+	}
+
 	// tableInfo is the basic internal implementation of a Table
 	tableInfo struct {
-		db      *dbInfo
-		name    table.Name
+		tableDef
 		columns column.Columns
 		offsets column.NamedOffsets
-		indexes prefix.Prefix
-		rows    prefix.Prefix
 	}
 
 	// tableTxr is the basic implementation of a table.Transactor
@@ -29,24 +47,57 @@ type (
 		*dbTxr
 	}
 
+	// storedRow pairs a stored Row with its Key
+	storedRow struct {
+		key value.Key
+		row relation.Row
+	}
+
 	indexerFunc func(index.Index) error
+
+	// validator is implemented by Indexes whose Definition can be invalid
+	// in ways that only their Type can detect
+	validator interface {
+		validate() error
+	}
 )
 
 // Error messages
 const (
 	ErrIndexAlreadyExists = "index already exists in table: %s"
-	ErrKeyAlreadyExists   = "key already exists in table: %s"
-	ErrKeyNotFound        = "key not found in table: %s"
+	ErrKeyAlreadyExists   = "key already exists in table: %x"
+	ErrKeyNotFound        = "key not found in table: %x"
+	ErrColumnType         = "value has the wrong type for column: %s"
 )
 
-func makeTable(db *dbTxr, n table.Name, cols ...column.Column) *tableInfo {
+func makeTableDef(db *dbTxr, n table.Name, cols ...column.Column) tableDef {
+	names := make(column.Names, len(cols))
+	var types column.Types
+	for i, c := range cols {
+		names[i] = c.Name()
+		if typ := column.TypeOf(c); typ != column.AnyType {
+			types = types.With(c.Name(), typ)
+		}
+	}
+	return tableDef{
+		Name:      n,
+		Columns:   names,
+		Types:     types,
+		Keys:      table.KeyStrategy{Type: table.RandomKeys},
+		IndexData: db.nextPrefix(),
+		RowData:   db.nextPrefix(),
+	}
+}
+
+func (d tableDef) info() *tableInfo {
+	cols := make(column.Columns, len(d.Columns))
+	for i, n := range d.Columns {
+		cols[i] = column.MakeTyped(n, d.Types[n])
+	}
 	return &tableInfo{
-		db:      db.dbInfo,
-		name:    n,
-		columns: cols,
-		offsets: column.MakeNamedOffsets(cols...),
-		indexes: db.nextPrefix(),
-		rows:    db.nextPrefix(),
+		tableDef: d,
+		columns:  cols,
+		offsets:  column.MakeNamedOffsets(cols...),
 	}
 }
 
@@ -58,7 +109,7 @@ func (t *tableInfo) transactor(db *dbTxr) *tableTxr {
 }
 
 func (t *tableTxr) Name() table.Name {
-	return t.name
+	return t.tableDef.Name
 }
 
 // Columns returns the defined Columns for this table
@@ -67,29 +118,49 @@ func (t *tableTxr) Columns() column.Columns {
 }
 
 func (t *tableTxr) CreateIndex(
-	typ index.Type, n index.Name, cols ...column.Name,
+	typ index.TypeName, n index.Name, cols ...column.Name,
 ) error {
-	idx := t.txn.For(t.indexes)
-	key := value.Key(n)
-	if _, ok := idx.Get(key); ok {
-		return fmt.Errorf(ErrIndexAlreadyExists, n)
+	return t.DefineIndex(index.Definition{
+		Name:    n,
+		Type:    typ,
+		Columns: cols,
+	})
+}
+
+// DefineIndex creates an Index from its Definition and populates it with
+// the rows that are already stored in the table
+func (t *tableTxr) DefineIndex(d index.Definition) error {
+	indexes := t.txn.For(t.IndexData)
+	key := value.Key(d.Name)
+	if _, ok := indexes.Get(key); ok {
+		return fmt.Errorf(ErrIndexAlreadyExists, d.Name)
 	}
 
-	off, err := t.columnOffsets(cols)
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	def := indexDef{
+		Definition: d,
+		Data:       t.nextPrefix(),
+	}
+	idx, err := t.indexMutator(def)
 	if err != nil {
 		return err
 	}
 
-	pfx := t.nextPrefix()
-	cons := typ(pfx, n, relation.MakeOffsetSelector(off...))
-	idx.Insert(key, cons)
+	indexes.Insert(key, def)
+	for _, s := range t.storedRows() {
+		if err := idx.Insert(s.key, s.row); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // Indexes returns the defined Indexes for this table
 func (t *tableTxr) Indexes() index.Names {
 	var res index.Names
-	_ = iterate.ForEach(t.txn.For(t.indexes).Ascending().All(),
+	_ = iterate.ForEach(t.txn.For(t.IndexData).Ascending().All(),
 		func(k value.Key, v any) error {
 			name := index.Name(k)
 			res = append(res, name)
@@ -99,36 +170,151 @@ func (t *tableTxr) Indexes() index.Names {
 	return res
 }
 
-func (t *tableInfo) columnOffsets(cols column.Names) (column.Offsets, error) {
-	return relation.MakeOffsets(t.columns, cols...)
+// IndexInfo returns the Definition of the named Index
+func (t *tableTxr) IndexInfo(n index.Name) (index.Definition, bool) {
+	if def, ok := t.txn.For(t.IndexData).Get(value.Key(n)); ok {
+		return def.(indexDef).Definition, true
+	}
+	return index.Definition{}, false
+}
+
+// Index returns the Query interface of the named Index, allowing rows to be
+// looked up through it
+func (t *tableTxr) Index(n index.Name) (index.Query, error) {
+	return t.index(n)
 }
 
-func (t *tableTxr) Truncate() {
-	_ = t.mutateIndexes(func(i index.Index) error {
+func (t *tableTxr) definition() table.Definition {
+	var indexes index.Definitions
+	for _, def := range t.indexDefs() {
+		indexes = append(indexes, def.Definition)
+	}
+	return table.Definition{
+		Name:        t.tableDef.Name,
+		Columns:     t.tableDef.Columns,
+		Types:       t.Types,
+		Keys:        t.Keys,
+		Indexes:     indexes,
+		ForeignKeys: t.ForeignKeys,
+		Checks:      t.Checks,
+	}
+}
+
+// makeIndex instantiates an Index for querying
+func (t *tableTxr) makeIndex(def indexDef) (index.Index, error) {
+	typ, ok := index.Lookup(def.Type)
+	if !ok {
+		return nil, fmt.Errorf(index.ErrTypeNotRegistered, def.Type)
+	}
+	sel, err := def.Selector(t.columns, def.Covered()...)
+	if err != nil {
+		return nil, err
+	}
+	idx := typ(def.Data, def.Definition, sel)(t.txn)
+	if v, ok := idx.(validator); ok {
+		if err := v.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// indexMutator instantiates an Index for maintaining its entries as rows
+// change. Unlike the Index returned by makeIndex, it only passes on the
+// rows that it should hold
+func (t *tableTxr) indexMutator(def indexDef) (index.Index, error) {
+	idx, err := t.makeIndex(def)
+	if err != nil {
+		return nil, err
+	}
+	if def.IsComputed() {
+		if idx, err = t.computedIndex(idx, def.Computed); err != nil {
+			return nil, err
+		}
+	}
+	if !def.IsPartial() {
+		return idx, nil
+	}
+	where, err := expr.CompilePredicate(def.Where, t.columns)
+	if err != nil {
+		return nil, err
+	}
+	return &partialIndex{Index: idx, where: where}, nil
+}
+
+func (t *tableTxr) computedIndex(
+	idx index.Index, c index.Computed,
+) (index.Index, error) {
+	res := &computedIndex{Index: idx}
+	for _, e := range c {
+		eval, err := expr.Compile(e, t.columns)
+		if err != nil {
+			return nil, err
+		}
+		res.evals = append(res.evals, eval)
+	}
+	return res, nil
+}
+
+// Truncate deletes all rows from the table. If the table is referenced by
+// any ForeignKey, each row is deleted individually so that the ForeignKey's
+// Action is performed
+func (t *tableTxr) Truncate() error {
+	if len(t.referencingKeys()) != 0 {
+		for _, s := range t.storedRows() {
+			if _, _, err := t.delete(s.key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := t.mutateIndexes(func(i index.Index) error {
 		i.Truncate()
 		return nil
 	})
-	t.txn.For(t.rows).Drop()
+	if err != nil {
+		return err
+	}
+	t.txn.For(t.RowData).Drop()
+	t.adjustCount(-t.Count())
+	return nil
 }
 
 func (t *tableTxr) Insert(k value.Key, r relation.Row) error {
-	rows := t.txn.For(t.rows)
+	rows := t.txn.For(t.RowData)
 	if _, ok := rows.Get(k); ok {
 		return fmt.Errorf(ErrKeyAlreadyExists, k)
 	}
+	if err := t.checkRow(r); err != nil {
+		return err
+	}
 	_, _ = rows.Insert(k, r)
+	t.adjustCount(1)
 	return t.mutateIndexes(func(i index.Index) error {
 		return i.Insert(k, r)
 	})
 }
 
+// Update replaces the row associated with a Key. The Values of a row that
+// its composite Key is derived from can't be changed, nor can those that
+// are referenced by any ForeignKey while rows refer to it
 func (t *tableTxr) Update(k value.Key, r relation.Row) (relation.Row, error) {
-	rows := t.txn.For(t.rows)
-	if _, ok := rows.Get(k); !ok {
+	rows := t.txn.For(t.RowData)
+	res, ok := rows.Get(k)
+	if !ok {
 		return nil, fmt.Errorf(ErrKeyNotFound, k)
 	}
-	res, _ := rows.Insert(k, r)
 	old := res.(relation.Row)
+	if err := t.checkRow(r); err != nil {
+		return nil, err
+	}
+	if err := t.checkKeyColumns(old, r); err != nil {
+		return nil, err
+	}
+	if err := t.checkReferenced(k, old, r); err != nil {
+		return nil, err
+	}
+	_, _ = rows.Insert(k, r)
 	err := t.mutateIndexes(func(i index.Index) error {
 		i.Delete(k, old)
 		return i.Insert(k, r)
@@ -139,34 +325,138 @@ func (t *tableTxr) Update(k value.Key, r relation.Row) (relation.Row, error) {
 	return old, nil
 }
 
-func (t *tableTxr) Delete(k value.Key) (relation.Row, bool) {
-	res, ok := t.txn.For(t.rows).Delete(k)
-	if res == nil || !ok {
-		return nil, ok
+// checkRow validates a Row against the table's constraints before it is
+// stored or indexed
+func (t *tableTxr) checkRow(r relation.Row) error {
+	if err := t.checkTypes(r); err != nil {
+		return err
 	}
-	row := res.(relation.Row)
-	_ = t.mutateIndexes(func(i index.Index) error {
+	if err := t.checkConstraints(r); err != nil {
+		return err
+	}
+	return t.checkReferences(r)
+}
+
+// checkTypes makes sure that the Values of a Row can be stored by the
+// table's Typed Columns
+func (t *tableTxr) checkTypes(r relation.Row) error {
+	for i, c := range t.columns {
+		if i < len(r) && !column.TypeOf(c).Accepts(r[i]) {
+			return fmt.Errorf(ErrColumnType, c.Name())
+		}
+	}
+	return nil
+}
+
+// Delete removes the row associated with a Key, performing the Action of
+// any ForeignKey that references it. Deleting a Key that isn't stored
+// does nothing, and returns no row
+func (t *tableTxr) Delete(k value.Key) (relation.Row, error) {
+	row, _, err := t.delete(k)
+	return row, err
+}
+
+func (t *tableTxr) delete(k value.Key) (relation.Row, bool, error) {
+	row, ok := t.Select(k)
+	if !ok {
+		return nil, false, nil
+	}
+	refs, err := t.referencingRows(k, row)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := refs.restrict(); err != nil {
+		return nil, false, err
+	}
+
+	t.txn.For(t.RowData).Delete(k)
+	t.adjustCount(-1)
+	err = t.mutateIndexes(func(i index.Index) error {
 		i.Delete(k, row)
 		return nil
 	})
-	return row, true
+	if err != nil {
+		return nil, false, err
+	}
+	if err := refs.perform(); err != nil {
+		return nil, false, err
+	}
+	return row, true, nil
 }
 
 func (t *tableTxr) Select(k value.Key) (relation.Row, bool) {
-	if v, ok := t.txn.For(t.rows).Get(k); ok {
+	if v, ok := t.txn.For(t.RowData).Get(k); ok {
 		return v.(relation.Row), true
 	}
 	return nil, false
 }
 
-func (t *tableTxr) mutateIndexes(fn indexerFunc) error {
-	return iterate.ForEach(t.txn.For(t.indexes).Ascending().All(),
-		func(k value.Key, v any) error {
-			cons := v.(index.Constructor)
-			if err := fn(cons(t.txn)); err != nil {
-				return err
+// Rows returns an Iterator over the table's Keys and their Rows, ordered
+// by Key
+func (t *tableTxr) Rows() transaction.Iterator {
+	return t.txn.For(t.RowData).Ascending().All()
+}
+
+// RowsAfter returns an Iterator over the table's Keys and Rows that
+// follow the provided Key, so that an earlier iteration can be resumed
+func (t *tableTxr) RowsAfter(k value.Key) transaction.Iterator {
+	return iterate.After(t.txn.For(t.RowData).Ascending(), k).All()
+}
+
+// Scan returns a sequence of the table's Keys and Rows, ordered by Key.
+// The table must not be modified while the sequence is being ranged over
+func (t *tableTxr) Scan() iter.Seq2[value.Key, relation.Row] {
+	return iterate.Rows(t.Rows())
+}
+
+// Lookup returns a sequence of the Keys and Rows referred to by the
+// entries of an Index Iterator, in the order of those entries
+func (t *tableTxr) Lookup(
+	entries transaction.Iterator,
+) iter.Seq2[value.Key, relation.Row] {
+	return func(yield func(value.Key, relation.Row) bool) {
+		for _, v := range iterate.Seq(entries) {
+			k := v.(value.Key)
+			if row, ok := t.Select(k); ok && !yield(k, row) {
+				return
 			}
+		}
+	}
+}
+
+// storedRows gathers the table's rows up front, as the underlying
+// transaction may modify its nodes in place while they are being iterated
+func (t *tableTxr) storedRows() []storedRow {
+	var res []storedRow
+	_ = iterate.ForEach(t.txn.For(t.RowData).Ascending().All(),
+		func(k value.Key, v any) error {
+			res = append(res, storedRow{key: k, row: v.(relation.Row)})
+			return nil
+		},
+	)
+	return res
+}
+
+func (t *tableTxr) indexDefs() []indexDef {
+	var res []indexDef
+	_ = iterate.ForEach(t.txn.For(t.IndexData).Ascending().All(),
+		func(_ value.Key, v any) error {
+			res = append(res, v.(indexDef))
 			return nil
 		},
 	)
+	return res
+}
+
+func (t *tableTxr) mutateIndexes(fn indexerFunc) error {
+	for _, def := range t.indexDefs() {
+		idx, err := t.indexMutator(def)
+		if err != nil {
+			return err
+		}
+		if err := fn(idx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -1,15 +1,18 @@
 package internal_test
 
 import (
+	"encoding/hex"
 	"fmt"
 	"testing"
 
 	"github.com/caravan/db"
 	"github.com/caravan/db/column"
 	"github.com/caravan/db/database"
+	"github.com/caravan/db/index"
 	"github.com/caravan/db/internal"
 	"github.com/caravan/db/relation"
 	"github.com/caravan/db/table"
+	"github.com/caravan/db/transaction/iterate"
 	"github.com/caravan/db/value"
 	"github.com/stretchr/testify/assert"
 )
@@ -104,6 +107,13 @@ func TestTable(t *testing.T) {
 		as.False(ok)
 		as.Nil(row)
 
+		var rows []relation.Row
+		_ = iterate.ForEach(tbl.Rows(), func(_ value.Key, v any) error {
+			rows = append(rows, v.(relation.Row))
+			return nil
+		})
+		as.ElementsMatch([]relation.Row{tableRow1, tableRow2}, rows)
+
 		old, err := tbl.Update(tableKey1, tableRow3)
 		as.Nil(err)
 		as.Equal(tableRow1, old)
@@ -118,6 +128,39 @@ func TestTable(t *testing.T) {
 	as.Nil(err)
 }
 
+func TestTableScan(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("test-table")
+
+		rows := map[string]relation.Row{}
+		for k, row := range tbl.Scan() {
+			rows[string(k)] = row
+		}
+		as.Equal(map[string]relation.Row{
+			string(tableKey1): tableRow1,
+			string(tableKey2): tableRow2,
+		}, rows)
+
+		for range tbl.Scan() {
+			break
+		}
+
+		idx, err := tbl.Index("standard-index")
+		as.Nil(err)
+		var keys []value.Key
+		for k, row := range tbl.Lookup(idx.EQ(relation.Relation(tableRow2[:1]))) {
+			as.Equal(tableRow2, row)
+			keys = append(keys, k)
+		}
+		as.Equal([]value.Key{tableKey2}, keys)
+		return nil
+	})
+	as.Nil(err)
+}
+
 func TestTableDelete(t *testing.T) {
 	as := assert.New(t)
 
@@ -126,9 +169,10 @@ func TestTableDelete(t *testing.T) {
 		tbl, ok := d.Table("test-table")
 		as.True(ok)
 
-		old, ok := tbl.Delete(tableKey1)
-		as.True(ok)
+		old, err := tbl.Delete(tableKey1)
+		as.Nil(err)
 		as.Equal(tableRow1, old)
+		as.Equal(1, tbl.Count())
 
 		row, ok := tbl.Select(tableKey1)
 		as.False(ok)
@@ -146,8 +190,10 @@ func TestTableTruncate(t *testing.T) {
 	d, err := d(func(d database.Database) error {
 		tbl, ok := d.Table("test-table")
 		as.True(ok)
+		as.Equal(2, tbl.Count())
 
-		tbl.Truncate()
+		as.Nil(tbl.Truncate())
+		as.Equal(0, tbl.Count())
 
 		old, ok := tbl.Select(tableKey1)
 		as.Nil(old)
@@ -173,10 +219,11 @@ func TestTableMutateWithErrors(t *testing.T) {
 		old, err := tbl.Update(tableKey3, tableRow3)
 		as.Nil(old)
 		as.EqualError(err, fmt.Sprintf(internal.ErrKeyNotFound, tableKey3))
+		as.Contains(err.Error(), hex.EncodeToString(tableKey3))
 
-		old, ok = tbl.Delete(tableKey3)
+		old, err = tbl.Delete(tableKey3)
 		as.Nil(old)
-		as.False(ok)
+		as.Nil(err)
 		return nil
 	})
 	as.NotNil(d)
@@ -203,3 +250,133 @@ func TestTableCreateIndexError(t *testing.T) {
 	as.NotNil(d)
 	as.Nil(err)
 }
+
+func TestTableDefineIndex(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, ok := d.Table("test-table")
+		as.True(ok)
+
+		err := tbl.DefineIndex(index.Definition{
+			Name:    "missing-type-index",
+			Type:    "missing-type",
+			Columns: column.Names{"first"},
+		})
+		as.EqualError(err,
+			fmt.Sprintf(index.ErrTypeNotRegistered, "missing-type"),
+		)
+
+		err = tbl.DefineIndex(index.Definition{
+			Name:    "second-index",
+			Type:    db.UniqueIndex,
+			Columns: column.Names{"second"},
+		})
+		as.Nil(err)
+		as.Equal(3, len(tbl.Indexes()))
+
+		err = tbl.Insert(value.NewKey(), relation.Row{
+			value.String("another str"), value.String("second str"),
+		})
+		as.EqualError(err,
+			fmt.Sprintf(internal.ErrUniqueConstraintFailed, "second-index"),
+		)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestTableDefineIndexDuplicates(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, ok := d.Table("test-table")
+		as.True(ok)
+
+		_, err := tbl.Update(tableKey2, relation.Row{
+			value.String("third str"), value.String("second str"),
+		})
+		as.Nil(err)
+		return tbl.CreateIndex(db.UniqueIndex, "second-index", "second")
+	})
+	as.NotNil(d)
+	as.EqualError(err,
+		fmt.Sprintf(internal.ErrUniqueConstraintFailed, "second-index"),
+	)
+}
+
+func TestTableIndexInfo(t *testing.T) {
+	as := assert.New(t)
+
+	d, _ := makeTestDatabase()
+	d, err := d(func(d database.Database) error {
+		tbl, ok := d.Table("test-table")
+		as.True(ok)
+
+		def, ok := tbl.IndexInfo("unique-index")
+		as.True(ok)
+		as.Equal(index.Name("unique-index"), def.Name)
+		as.Equal(db.UniqueIndex, def.Type)
+		as.Equal(column.Names{"first", "second"}, def.Columns)
+
+		def, ok = tbl.IndexInfo("missing-index")
+		as.False(ok)
+		as.Equal(index.Definition{}, def)
+		return nil
+	})
+	as.NotNil(d)
+	as.Nil(err)
+}
+
+func TestTableTypedColumns(t *testing.T) {
+	as := assert.New(t)
+
+	d, err := internal.NewDatabase()(func(d database.Database) error {
+		tbl, err := d.CreateTable("people",
+			column.Make("name"), column.Of[int64]("age"),
+		)
+		as.Nil(err)
+		as.Nil(tbl.Insert(value.NewKey(), relation.Row{
+			value.String("bill"), value.Integer(42),
+		}))
+		as.Nil(tbl.Insert(value.NewKey(), relation.Row{
+			value.Integer(7), nil,
+		}))
+		return nil
+	})
+	as.Nil(err)
+
+	_, err = d(func(d database.Database) error {
+		tbl, _ := d.Table("people")
+		as.Equal(column.IntegerType, column.TypeOf(tbl.Columns()[1]))
+		as.Equal(column.Types{"age": column.IntegerType},
+			d.Schema().Tables[0].Types,
+		)
+
+		err := tbl.Insert(value.NewKey(), relation.Row{
+			value.String("ted"), value.String("old"),
+		})
+		as.EqualError(err, fmt.Sprintf(internal.ErrColumnType, "age"))
+
+		err = tbl.AddColumn(column.Of[bool]("alive"), value.Integer(1))
+		as.EqualError(err, fmt.Sprintf(internal.ErrColumnType, "alive"))
+		as.Nil(tbl.AddColumn(column.Of[bool]("alive"), value.Bool(true)))
+
+		as.Nil(tbl.RenameColumn("age", "years"))
+		tbl, _ = d.Table("people")
+		err = tbl.Insert(value.NewKey(), relation.Row{
+			value.String("ted"), value.Float(1.5), value.Bool(true),
+		})
+		as.EqualError(err, fmt.Sprintf(internal.ErrColumnType, "years"))
+
+		as.Nil(tbl.DropColumn("years"))
+		as.Equal(column.Types{"alive": column.BoolType},
+			d.Schema().Tables[0].Types,
+		)
+		return nil
+	})
+	as.Nil(err)
+}
@@ -0,0 +1,556 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/prefix"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+	"github.com/caravan/db/vector"
+)
+
+type (
+	// vectorIndex stores the Vector of each row beneath its prefix. In
+	// HNSW Mode, it also stores a node for each row that links it to its
+	// nearest neighbors on each of the graph's layers, and the entry
+	// point of the graph. Nodes are replaced rather than modified, so
+	// that earlier versions of the Database keep a consistent graph
+	vectorIndex struct {
+		*indexInfo
+		*vectorConfig
+		txn transaction.Txn
+		keyView
+		eqOnly
+	}
+
+	// vectorConfig holds the settings parsed from a vector Index's Options
+	vectorConfig struct {
+		metric     vector.Metric
+		mode       vector.Mode
+		dimensions int
+		neighbors  int
+		candidates int
+		err        error
+	}
+
+	// hnswNode holds the links of a row's Vector on each layer of the
+	// graph, from the bottom layer up
+	hnswNode struct {
+		links [][]value.Key
+	}
+
+	// hnswEntry is the node on the highest layer of the graph, from which
+	// every search begins
+	hnswEntry struct {
+		key   value.Key
+		level int
+	}
+
+	// candidate is a row considered by a search, along with its distance
+	// from the query
+	candidate struct {
+		key      value.Key
+		distance float64
+	}
+
+	candidates []candidate
+)
+
+// VectorIndex names the index.Type that finds the rows whose Vector Column
+// is nearest to a query Vector
+const VectorIndex index.TypeName = "vector"
+
+// Error messages
+const (
+	ErrVectorIndexColumns = "vector index requires one column: %s"
+	ErrVectorOption       = "invalid vector index option: %s"
+	ErrVectorRequired     = "vector index value is not a vector: %T"
+	ErrVectorDimensions   = "vector has the wrong number of dimensions: %d"
+)
+
+// Tags that begin the Keys stored beneath a vector Index's prefix
+const (
+	vectorTag byte = 'v'
+	nodeTag   byte = 'n'
+	entryTag  byte = 'e'
+)
+
+const (
+	defaultNeighbors  = 16
+	defaultCandidates = 64
+)
+
+func init() {
+	mustRegisterIndexType(VectorIndex, vectorIndexType)
+}
+
+// vectorIndexType is an index.Type that finds the rows whose Vector Column
+// is nearest to a query Vector, configured by its Options
+var vectorIndexType = index.Type(
+	func(
+		p prefix.Prefixed, d index.Definition, s relation.Selector,
+	) index.Constructor {
+		info := makeIndexInfo(p, d, s)
+		cfg := makeVectorConfig(d.Options)
+		return func(txn transaction.Txn) index.Index {
+			return &vectorIndex{
+				indexInfo:    info,
+				vectorConfig: cfg,
+				txn:          txn,
+			}
+		}
+	},
+)
+
+func makeVectorConfig(o index.Options) *vectorConfig {
+	res := &vectorConfig{
+		metric:     vector.Cosine,
+		mode:       vector.ExactMode,
+		neighbors:  defaultNeighbors,
+		candidates: defaultCandidates,
+	}
+	if m, ok := o[vector.MetricOption]; ok {
+		res.metric = vector.Metric(m)
+	}
+	if m, ok := o[vector.ModeOption]; ok {
+		res.mode = vector.Mode(m)
+	}
+	if res.err = res.metric.Validate(); res.err != nil {
+		return res
+	}
+	if res.err = res.mode.Validate(); res.err != nil {
+		return res
+	}
+	for _, opt := range []struct {
+		name string
+		dest *int
+	}{
+		{vector.DimensionsOption, &res.dimensions},
+		{vector.NeighborsOption, &res.neighbors},
+		{vector.CandidatesOption, &res.candidates},
+	} {
+		s, ok := o[opt.name]
+		if !ok {
+			continue
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 1 {
+			res.err = fmt.Errorf(ErrVectorOption, opt.name)
+			return res
+		}
+		*opt.dest = i
+	}
+	return res
+}
+
+func (i *vectorIndex) validate() error {
+	if len(i.desc) != 1 {
+		return fmt.Errorf(ErrVectorIndexColumns, i.name)
+	}
+	return i.err
+}
+
+// Insert adds a row whose Column holds a Vector. Rows whose Column is null
+// aren't indexed
+func (i *vectorIndex) Insert(k value.Key, r relation.Row) error {
+	if i.err != nil {
+		return i.err
+	}
+	var vec value.Vector
+	switch v := i.selector(r)[0].(type) {
+	case nil:
+		return nil
+	case value.Vector:
+		vec = v
+	default:
+		return fmt.Errorf(ErrVectorRequired, v)
+	}
+	if i.dimensions != 0 && len(vec) != i.dimensions {
+		return fmt.Errorf(ErrVectorDimensions, len(vec))
+	}
+	i.txn.For(i).Insert(vectorKey(k), vec)
+	if i.mode == vector.HNSWMode {
+		i.link(k, vec)
+	}
+	return nil
+}
+
+// Delete removes a row's Vector, using the Vector that was stored for it
+func (i *vectorIndex) Delete(k value.Key, _ relation.Row) bool {
+	if i.err != nil {
+		return false
+	}
+	if _, ok := i.txn.For(i).Delete(vectorKey(k)); !ok {
+		return false
+	}
+	if i.mode == vector.HNSWMode {
+		i.unlink(k)
+	}
+	return true
+}
+
+func (i *vectorIndex) Truncate() {
+	i.txn.For(i).Drop()
+}
+
+func vectorKey(k value.Key) value.Key {
+	return append(value.Key{vectorTag}, k...)
+}
+
+func nodeKey(k value.Key) value.Key {
+	return append(value.Key{nodeTag}, k...)
+}
+
+func (i *vectorIndex) vector(k value.Key) (value.Vector, bool) {
+	if v, ok := i.txn.For(i).Get(vectorKey(k)); ok {
+		return v.(value.Vector), true
+	}
+	return nil, false
+}
+
+func (i *vectorIndex) distance(q value.Vector, k value.Key) (float64, bool) {
+	if v, ok := i.vector(k); ok {
+		return i.metric.Distance(q, v)
+	}
+	return 0, false
+}
+
+// Nearest finds up to k rows whose Vectors are nearest to the query. In
+// HNSW Mode, the search descends the layers of the graph from its entry
+// point, and then considers the best candidates of the bottom layer
+func (i *vectorIndex) Nearest(q value.Vector, k int) vector.Matches {
+	if i.mode != vector.HNSWMode {
+		return i.Exact(q, k)
+	}
+	e, ok := i.entry()
+	if i.err != nil || k <= 0 || !ok {
+		return nil
+	}
+	d, ok := i.distance(q, e.key)
+	if !ok {
+		return nil
+	}
+	found := candidates{{key: e.key, distance: d}}
+	for l := e.level; l > 0; l-- {
+		found = i.searchLayer(q, found, 1, l)
+	}
+	found = i.searchLayer(q, found, max(i.candidates, k), 0)
+	return found[:min(k, len(found))].matches()
+}
+
+// Exact finds up to k rows whose Vectors are nearest to the query by
+// comparing the query to every Vector
+func (i *vectorIndex) Exact(q value.Vector, k int) vector.Matches {
+	if i.err != nil || k <= 0 {
+		return nil
+	}
+	var res candidates
+	_ = iterate.ForEach(i.vectors(), func(key value.Key, v any) error {
+		if d, ok := i.metric.Distance(q, v.(value.Vector)); ok {
+			res = res.insert(candidate{key: key[1:], distance: d})
+		}
+		if len(res) > k {
+			res = res[:k]
+		}
+		return nil
+	})
+	return res.matches()
+}
+
+func (i *vectorIndex) vectors() transaction.Iterator {
+	pfx := value.Key{vectorTag}
+	iter := i.txn.For(i).Ascending().From(pfx)
+	return iterate.While(iter, func(k value.Key, _ any) bool {
+		return bytes.HasPrefix(k, pfx)
+	})
+}
+
+// EQ returns the primary Keys of the rows whose Vector is equal to the
+// Relation's Vector, in Key order
+func (i *vectorIndex) EQ(r relation.Relation) transaction.Iterator {
+	if len(r) == 0 {
+		return noEntries
+	}
+	q, ok := r[0].(value.Vector)
+	if !ok {
+		return noEntries
+	}
+	var keys []value.Key
+	_ = iterate.ForEach(i.vectors(), func(k value.Key, v any) error {
+		if q.Compare(v.(value.Vector)) == value.EqualTo {
+			keys = append(keys, k[1:])
+		}
+		return nil
+	})
+	return i.lookup(keys)
+}
+
+// Descending returns a view of the Index whose lookups produce entries in
+// reverse order
+func (i *vectorIndex) Descending() index.Query {
+	res := *i
+	res.reverse = true
+	return &res
+}
+
+// Covering returns a view of the Index whose lookups produce an Entry for
+// each match, rather than its primary Key
+func (i *vectorIndex) Covering() index.Query {
+	res := *i
+	res.covering = true
+	return &res
+}
+
+// After returns a view of the Index whose lookups only produce the
+// entries that follow the provided Key
+func (i *vectorIndex) After(k value.Key) index.Query {
+	res := *i
+	res.after = k
+	return &res
+}
+
+func (i *vectorIndex) node(k value.Key) (hnswNode, bool) {
+	if n, ok := i.txn.For(i).Get(nodeKey(k)); ok {
+		return n.(hnswNode), true
+	}
+	return hnswNode{}, false
+}
+
+func (i *vectorIndex) entry() (hnswEntry, bool) {
+	if e, ok := i.txn.For(i).Get(value.Key{entryTag}); ok {
+		return e.(hnswEntry), true
+	}
+	return hnswEntry{}, false
+}
+
+// level returns the highest layer of a row's node. Levels are derived
+// from the row's Key, so that the graph only depends on its contents
+func (i *vectorIndex) level(k value.Key) int {
+	h := fnv.New64a()
+	_, _ = h.Write(k)
+	u := (float64(h.Sum64()>>11) + 0.5) / (1 << 53)
+	return int(-math.Log(u) / math.Log(float64(max(i.neighbors, 2))))
+}
+
+// maxLinks returns the number of links that a node keeps on a layer. The
+// bottom layer holds every node, so its nodes keep twice as many
+func (i *vectorIndex) maxLinks(layer int) int {
+	if layer == 0 {
+		return 2 * i.neighbors
+	}
+	return i.neighbors
+}
+
+// link adds a row's node to the graph, linking it to its nearest
+// neighbors on each of its layers, and linking them back to it
+func (i *vectorIndex) link(k value.Key, vec value.Vector) {
+	level := i.level(k)
+	node := hnswNode{links: make([][]value.Key, level+1)}
+	entry := hnswEntry{key: k, level: level}
+	e, ok := i.entry()
+	if !ok {
+		i.txn.For(i).Insert(nodeKey(k), node)
+		i.txn.For(i).Insert(value.Key{entryTag}, entry)
+		return
+	}
+	d, _ := i.distance(vec, e.key)
+	found := candidates{{key: e.key, distance: d}}
+	for l := e.level; l > level; l-- {
+		found = i.searchLayer(vec, found, 1, l)
+	}
+	for l := min(level, e.level); l >= 0; l-- {
+		found = i.searchLayer(vec, found, i.candidates, l)
+		nearest := found[:min(len(found), i.neighbors)]
+		node.links[l] = nearest.keys()
+		for _, n := range nearest {
+			i.addLink(n.key, k, l)
+		}
+	}
+	i.txn.For(i).Insert(nodeKey(k), node)
+	if level > e.level {
+		i.txn.For(i).Insert(value.Key{entryTag}, entry)
+	}
+}
+
+// addLink links a node to another on a layer, keeping only its nearest
+// links if it then has too many
+func (i *vectorIndex) addLink(from, to value.Key, layer int) {
+	n, ok := i.node(from)
+	if !ok || layer >= len(n.links) {
+		return
+	}
+	links := append(slices.Clone(n.links[layer]), to)
+	i.setLinks(from, n, layer, links)
+}
+
+func (i *vectorIndex) setLinks(
+	k value.Key, n hnswNode, layer int, links []value.Key,
+) {
+	if len(links) > i.maxLinks(layer) {
+		links = i.nearestTo(k, links, i.maxLinks(layer))
+	}
+	n.links = slices.Clone(n.links)
+	n.links[layer] = links
+	i.txn.For(i).Insert(nodeKey(k), n)
+}
+
+// nearestTo returns up to count of the Keys whose Vectors are nearest to
+// the Vector of a row
+func (i *vectorIndex) nearestTo(
+	k value.Key, keys []value.Key, count int,
+) []value.Key {
+	vec, _ := i.vector(k)
+	var res candidates
+	for _, key := range keys {
+		if d, ok := i.distance(vec, key); ok {
+			res = res.insert(candidate{key: key, distance: d})
+		}
+	}
+	return res[:min(len(res), count)].keys()
+}
+
+// unlink removes a row's node from the graph. Each of its neighbors loses
+// its link to the node, and is offered the node's other neighbors in its
+// place, so that the graph remains navigable
+func (i *vectorIndex) unlink(k value.Key) {
+	node, ok := i.node(k)
+	if !ok {
+		return
+	}
+	i.txn.For(i).Delete(nodeKey(k))
+	for l, links := range node.links {
+		for _, nk := range links {
+			n, ok := i.node(nk)
+			if !ok || l >= len(n.links) || !containsKey(n.links[l], k) {
+				continue
+			}
+			var repaired []value.Key
+			for _, o := range append(slices.Clone(n.links[l]), links...) {
+				if !bytes.Equal(o, k) && !bytes.Equal(o, nk) &&
+					!containsKey(repaired, o) {
+					repaired = append(repaired, o)
+				}
+			}
+			i.setLinks(nk, n, l, repaired)
+		}
+	}
+	if e, ok := i.entry(); ok && bytes.Equal(e.key, k) {
+		i.resetEntry()
+	}
+}
+
+func containsKey(keys []value.Key, k value.Key) bool {
+	return slices.ContainsFunc(keys, func(o value.Key) bool {
+		return bytes.Equal(o, k)
+	})
+}
+
+// resetEntry makes the node with the highest level the entry point of the
+// graph, or removes the entry point if the graph is empty
+func (i *vectorIndex) resetEntry() {
+	var res hnswEntry
+	found := false
+	pfx := value.Key{nodeTag}
+	iter := iterate.While(i.txn.For(i).Ascending().From(pfx),
+		func(k value.Key, _ any) bool { return bytes.HasPrefix(k, pfx) },
+	)
+	_ = iterate.ForEach(iter, func(k value.Key, v any) error {
+		level := len(v.(hnswNode).links) - 1
+		if !found || level > res.level {
+			res = hnswEntry{key: k[1:], level: level}
+			found = true
+		}
+		return nil
+	})
+	if !found {
+		i.txn.For(i).Delete(value.Key{entryTag})
+		return
+	}
+	i.txn.For(i).Insert(value.Key{entryTag}, res)
+}
+
+// searchLayer finds up to ef of the nodes nearest to the query on a layer
+// of the graph, by following links from the provided candidates
+func (i *vectorIndex) searchLayer(
+	q value.Vector, from candidates, ef, layer int,
+) candidates {
+	visited := map[string]bool{}
+	for _, c := range from {
+		visited[string(c.key)] = true
+	}
+	pending := slices.Clone(from)
+	res := slices.Clone(from)
+	for len(pending) > 0 {
+		c := pending[0]
+		pending = pending[1:]
+		if len(res) >= ef && c.distance > res[len(res)-1].distance {
+			break
+		}
+		n, ok := i.node(c.key)
+		if !ok || layer >= len(n.links) {
+			continue
+		}
+		for _, k := range n.links[layer] {
+			if visited[string(k)] {
+				continue
+			}
+			visited[string(k)] = true
+			d, ok := i.distance(q, k)
+			if !ok {
+				continue
+			}
+			if len(res) < ef || d < res[len(res)-1].distance {
+				found := candidate{key: k, distance: d}
+				pending = pending.insert(found)
+				res = res.insert(found)
+				if len(res) > ef {
+					res = res[:ef]
+				}
+			}
+		}
+	}
+	return res
+}
+
+// insert adds a candidate, keeping the candidates ordered by distance
+// and then by Key
+func (c candidates) insert(n candidate) candidates {
+	pos, _ := slices.BinarySearchFunc(c, n, func(l, r candidate) int {
+		switch {
+		case l.distance < r.distance:
+			return -1
+		case l.distance > r.distance:
+			return 1
+		default:
+			return bytes.Compare(l.key, r.key)
+		}
+	})
+	return slices.Insert(c, pos, n)
+}
+
+func (c candidates) keys() []value.Key {
+	res := make([]value.Key, len(c))
+	for i, e := range c {
+		res[i] = e.key
+	}
+	return res
+}
+
+func (c candidates) matches() vector.Matches {
+	res := make(vector.Matches, len(c))
+	for i, e := range c {
+		res[i] = vector.Match{
+			Key:      append(value.Key(nil), e.key...),
+			Distance: e.distance,
+		}
+	}
+	return res
+}
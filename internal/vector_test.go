@@ -0,0 +1,214 @@
+package internal_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/internal"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+	"github.com/caravan/db/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeVectors(n, dims int) []value.Vector {
+	r := rand.New(rand.NewPCG(1, 2))
+	res := make([]value.Vector, n)
+	for i := range res {
+		res[i] = make(value.Vector, dims)
+		for j := range res[i] {
+			res[i][j] = r.Float32()*2 - 1
+		}
+	}
+	return res
+}
+
+func makeVectorDatabase(
+	as *assert.Assertions, opts index.Options, vecs []value.Vector,
+) database.Transactor {
+	d, err := db.NewDatabase()(func(d database.Database) error {
+		tbl, err := d.CreateTable("docs", column.Make("embedding"))
+		as.Nil(err)
+		as.Nil(tbl.DefineIndex(index.Definition{
+			Name:    "by-embedding",
+			Type:    db.VectorIndex,
+			Columns: column.Names{"embedding"},
+			Options: opts,
+		}))
+		for i, v := range vecs {
+			key := value.Key(fmt.Sprintf("doc-%03d", i))
+			as.Nil(tbl.Insert(key, relation.Row{v}))
+		}
+		return nil
+	})
+	as.Nil(err)
+	return d
+}
+
+func searchVectors(
+	as *assert.Assertions, d database.Transactor, fn func(vector.Searcher),
+) {
+	_, err := d(func(d database.Database) error {
+		tbl, ok := d.Table("docs")
+		as.True(ok)
+		s, err := vector.Search(tbl, "by-embedding")
+		as.Nil(err)
+		fn(s)
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestVectorIndexExact(t *testing.T) {
+	as := assert.New(t)
+
+	d := makeVectorDatabase(as, index.Options{
+		vector.MetricOption: string(vector.Euclidean),
+	}, []value.Vector{
+		{0, 0}, {1, 0}, {0, 3}, {5, 5},
+	})
+	searchVectors(as, d, func(s vector.Searcher) {
+		res := s.Nearest(value.Vector{0.9, 0.1}, 2)
+		as.Equal([]value.Key{
+			value.Key("doc-001"), value.Key("doc-000"),
+		}, res.Keys())
+		as.InDelta(0.1414, res[0].Distance, 0.001)
+		as.Equal(4, len(s.Exact(value.Vector{0, 0}, 10)))
+		as.Empty(s.Exact(value.Vector{0, 0, 0}, 10))
+	})
+
+	_, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("docs")
+		idx, err := tbl.Index("by-embedding")
+		as.Nil(err)
+		k, _, ok := iterate.First(
+			idx.EQ(relation.Relation{value.Vector{0, 3}}),
+		)
+		as.True(ok)
+		as.Equal(value.Key("doc-002"), k)
+
+		_, err = tbl.Update(value.Key("doc-003"), relation.Row{
+			value.Vector{1, 0.1},
+		})
+		as.Nil(err)
+		s, _ := vector.Search(tbl, "by-embedding")
+		as.Equal([]value.Key{value.Key("doc-003")},
+			s.Nearest(value.Vector{1, 0.2}, 1).Keys(),
+		)
+
+		as.EqualError(tbl.Insert(value.Key("bad"), relation.Row{
+			value.Float(1),
+		}), fmt.Sprintf(internal.ErrVectorRequired, value.Float(1)))
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestVectorIndexHNSW(t *testing.T) {
+	as := assert.New(t)
+
+	vecs := makeVectors(300, 8)
+	queries := makeVectors(320, 8)[300:]
+	d := makeVectorDatabase(as, index.Options{
+		vector.ModeOption:      string(vector.HNSWMode),
+		vector.NeighborsOption: "8",
+	}, vecs)
+
+	recall := func(s vector.Searcher) float64 {
+		found, total := 0, 0
+		for _, q := range queries {
+			exact := s.Exact(q, 10).Keys()
+			for _, k := range s.Nearest(q, 10).Keys() {
+				if slices.ContainsFunc(exact, func(e value.Key) bool {
+					return bytes.Equal(e, k)
+				}) {
+					found++
+				}
+			}
+			total += len(exact)
+		}
+		return float64(found) / float64(total)
+	}
+	searchVectors(as, d, func(s vector.Searcher) {
+		as.Greater(recall(s), 0.9)
+		res := s.Nearest(vecs[42], 1)
+		as.Equal([]value.Key{value.Key("doc-042")}, res.Keys())
+		as.InDelta(0, res[0].Distance, 1e-6)
+	})
+
+	next, err := d(func(d database.Database) error {
+		tbl, _ := d.Table("docs")
+		for i := 0; i < len(vecs); i += 2 {
+			_, err := tbl.Delete(value.Key(fmt.Sprintf("doc-%03d", i)))
+			as.Nil(err)
+		}
+		return nil
+	})
+	as.Nil(err)
+	searchVectors(as, next, func(s vector.Searcher) {
+		as.Greater(recall(s), 0.9)
+		as.Equal([]value.Key{value.Key("doc-043")},
+			s.Nearest(vecs[43], 1).Keys(),
+		)
+		for _, k := range s.Nearest(vecs[42], 20).Keys() {
+			as.NotEqual(value.Key("doc-042"), k)
+		}
+	})
+
+	searchVectors(as, d, func(s vector.Searcher) {
+		as.Equal([]value.Key{value.Key("doc-042")},
+			s.Nearest(vecs[42], 1).Keys(),
+		)
+	})
+
+	empty, err := next(func(d database.Database) error {
+		tbl, _ := d.Table("docs")
+		return tbl.Truncate()
+	})
+	as.Nil(err)
+	searchVectors(as, empty, func(s vector.Searcher) {
+		as.Empty(s.Nearest(vecs[43], 5))
+	})
+}
+
+func TestVectorIndexOptions(t *testing.T) {
+	as := assert.New(t)
+
+	d := db.NewDatabase()
+	_, err := d(func(d database.Database) error {
+		tbl, err := d.CreateTable("docs", column.Make("embedding"))
+		as.Nil(err)
+		as.EqualError(tbl.DefineIndex(index.Definition{
+			Name:    "by-metric",
+			Type:    db.VectorIndex,
+			Columns: column.Names{"embedding"},
+			Options: index.Options{vector.MetricOption: "manhattan"},
+		}), fmt.Sprintf(vector.ErrUnknownMetric, "manhattan"))
+		as.EqualError(tbl.DefineIndex(index.Definition{
+			Name:    "by-size",
+			Type:    db.VectorIndex,
+			Columns: column.Names{"embedding"},
+			Options: index.Options{vector.NeighborsOption: "none"},
+		}), fmt.Sprintf(internal.ErrVectorOption, vector.NeighborsOption))
+
+		as.Nil(tbl.DefineIndex(index.Definition{
+			Name:    "by-embedding",
+			Type:    db.VectorIndex,
+			Columns: column.Names{"embedding"},
+			Options: index.Options{vector.DimensionsOption: "3"},
+		}))
+		as.EqualError(tbl.Insert(value.Key("short"), relation.Row{
+			value.Vector{1, 2},
+		}), fmt.Sprintf(internal.ErrVectorDimensions, 2))
+		return nil
+	})
+	as.Nil(err)
+}
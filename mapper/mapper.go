@@ -0,0 +1,318 @@
+// Package mapper converts between Go structs and the Rows of a Table. The
+// fields of a struct are associated with a Table's Columns using db struct
+// tags, such as `db:"first_name"`. Fields without a tag aren't mapped
+package mapper
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Mapper converts between values of the struct type T and the Rows of
+	// a Table. A Mapper is validated against the Table's Columns when it
+	// is created, and refuses to convert Rows once those Columns change
+	Mapper[T any] struct {
+		table   table.Table
+		columns column.Names
+		fields  []field
+	}
+
+	field struct {
+		name   string
+		index  int
+		offset column.Offset
+	}
+)
+
+// TagName is the struct tag that names the Column a field is mapped to
+const TagName = "db"
+
+// Error messages
+const (
+	ErrNotStruct         = "type is not a struct: %s"
+	ErrUnsupportedField  = "field type is not supported: %s"
+	ErrDuplicateColumn   = "column is mapped more than once: %s"
+	ErrIncompatibleValue = "value can't be assigned to field: %s"
+	ErrColumnsChanged    = "table columns changed since mapper was created: %s"
+)
+
+var valueType = reflect.TypeOf((*value.Value)(nil)).Elem()
+
+// New creates a Mapper for the struct type T, making sure that each of
+// its tagged fields refers to a Column of the Table and has a supported
+// type
+func New[T any](tbl table.Table) (*Mapper[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf(ErrNotStruct, typ)
+	}
+	cols := tbl.Columns()
+	named := column.MakeNamedOffsets(cols...)
+	seen := map[column.Name]bool{}
+	res := &Mapper[T]{table: tbl, columns: columnNames(cols)}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag, ok := f.Tag.Lookup(TagName)
+		if !ok || tag == "-" || !f.IsExported() {
+			continue
+		}
+		n := column.Name(tag)
+		off, ok := named[n]
+		if !ok {
+			return nil, fmt.Errorf(relation.ErrColumnNotFound, n)
+		}
+		if seen[n] {
+			return nil, fmt.Errorf(ErrDuplicateColumn, n)
+		}
+		if !supported(f.Type) {
+			return nil, fmt.Errorf(ErrUnsupportedField, f.Name)
+		}
+		seen[n] = true
+		res.fields = append(res.fields, field{
+			name:   f.Name,
+			index:  i,
+			offset: off,
+		})
+	}
+	return res, nil
+}
+
+// Row converts a struct to a Row of the Mapper's Table. Columns that
+// aren't mapped to a field are left null
+func (m *Mapper[T]) Row(v *T) (relation.Row, error) {
+	if err := m.checkColumns(); err != nil {
+		return nil, err
+	}
+	rv := reflect.ValueOf(v).Elem()
+	res := make(relation.Row, len(m.columns))
+	for _, f := range m.fields {
+		val, err := toValue(rv.Field(f.index))
+		if err != nil {
+			return nil, fmt.Errorf(ErrIncompatibleValue, f.name)
+		}
+		res[f.offset] = val
+	}
+	return res, nil
+}
+
+// Scan populates a struct from a Row of the Mapper's Table. A null Value
+// sets its field to the field type's zero value
+func (m *Mapper[T]) Scan(r relation.Row, v *T) error {
+	if err := m.checkColumns(); err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v).Elem()
+	for _, f := range m.fields {
+		if !fromValue(r[f.offset], rv.Field(f.index)) {
+			return fmt.Errorf(ErrIncompatibleValue, f.name)
+		}
+	}
+	return nil
+}
+
+// checkColumns makes sure that the Table still has the Columns that the
+// Mapper was validated against, as its field Offsets would otherwise be
+// stale
+func (m *Mapper[T]) checkColumns() error {
+	if !slices.Equal(m.columns, columnNames(m.table.Columns())) {
+		return fmt.Errorf(ErrColumnsChanged, m.table.Name())
+	}
+	return nil
+}
+
+// Insert converts a struct to a Row and stores it under the provided Key
+func (m *Mapper[T]) Insert(k value.Key, v *T) error {
+	r, err := m.Row(v)
+	if err != nil {
+		return err
+	}
+	return m.table.Insert(k, r)
+}
+
+// InsertAuto converts a struct to a Row and stores it under a Key that
+// is generated by the Table
+func (m *Mapper[T]) InsertAuto(v *T) (value.Key, error) {
+	r, err := m.Row(v)
+	if err != nil {
+		return nil, err
+	}
+	return m.table.InsertAuto(r)
+}
+
+// Update converts a struct to a Row and replaces the Row stored under the
+// provided Key
+func (m *Mapper[T]) Update(k value.Key, v *T) error {
+	r, err := m.Row(v)
+	if err != nil {
+		return err
+	}
+	_, err = m.table.Update(k, r)
+	return err
+}
+
+// Select retrieves the Row stored under the provided Key as a struct
+func (m *Mapper[T]) Select(k value.Key) (*T, bool, error) {
+	r, ok := m.table.Select(k)
+	if !ok {
+		return nil, false, nil
+	}
+	var res T
+	if err := m.Scan(r, &res); err != nil {
+		return nil, false, err
+	}
+	return &res, true, nil
+}
+
+// Insert stores a struct in the Table under the provided Key
+func Insert[T any](tbl table.Table, k value.Key, v *T) error {
+	m, err := New[T](tbl)
+	if err != nil {
+		return err
+	}
+	return m.Insert(k, v)
+}
+
+// Select retrieves the Row stored in the Table under the provided Key as
+// a struct
+func Select[T any](tbl table.Table, k value.Key) (*T, bool, error) {
+	m, err := New[T](tbl)
+	if err != nil {
+		return nil, false, err
+	}
+	return m.Select(k)
+}
+
+func columnNames(cols column.Columns) column.Names {
+	res := make(column.Names, len(cols))
+	for i, c := range cols {
+		res[i] = c.Name()
+	}
+	return res
+}
+
+func supported(t reflect.Type) bool {
+	if t == valueType {
+		return true
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	default:
+		return false
+	}
+}
+
+func toValue(rv reflect.Value) (value.Value, error) {
+	if rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Kind() == reflect.Interface {
+			return rv.Interface().(value.Value), nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Bool:
+		return value.Bool(rv.Bool()), nil
+	case reflect.String:
+		return value.String(rv.String()), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(rv.Float()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return value.Integer(rv.Int()), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return value.Integer(rv.Uint()), nil
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return value.Key(rv.Bytes()), nil
+	default:
+		return nil, fmt.Errorf(ErrUnsupportedField, rv.Type())
+	}
+}
+
+// fromValue assigns a Value to a field, reporting whether the Value could
+// be represented by the field's type
+func fromValue(v value.Value, rv reflect.Value) bool {
+	if v == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Interface:
+		rv.Set(reflect.ValueOf(v))
+		return true
+	case reflect.Pointer:
+		elem := reflect.New(rv.Type().Elem())
+		if !fromValue(v, elem.Elem()) {
+			return false
+		}
+		rv.Set(elem)
+		return true
+	}
+	switch v := v.(type) {
+	case value.Bool:
+		if rv.Kind() == reflect.Bool {
+			rv.SetBool(bool(v))
+			return true
+		}
+	case value.String:
+		if rv.Kind() == reflect.String {
+			rv.SetString(string(v))
+			return true
+		}
+	case value.Float:
+		if k := rv.Kind(); k == reflect.Float32 || k == reflect.Float64 {
+			rv.SetFloat(float64(v))
+			return true
+		}
+	case value.Integer:
+		return setInteger(int64(v), rv)
+	case value.Key:
+		if rv.Kind() == reflect.Slice {
+			rv.SetBytes(append([]byte(nil), v...))
+			return true
+		}
+	}
+	return false
+}
+
+func setInteger(i int64, rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		if rv.OverflowInt(i) {
+			return false
+		}
+		rv.SetInt(i)
+		return true
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		if i < 0 || rv.OverflowUint(uint64(i)) {
+			return false
+		}
+		rv.SetUint(uint64(i))
+		return true
+	case reflect.Float32, reflect.Float64:
+		rv.SetFloat(float64(i))
+		return true
+	default:
+		return false
+	}
+}
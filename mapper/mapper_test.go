@@ -0,0 +1,135 @@
+package mapper_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/mapper"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name    string      `db:"name"`
+	Age     int8        `db:"age"`
+	Score   *float64    `db:"score"`
+	Active  bool        `db:"active"`
+	Avatar  []byte      `db:"avatar"`
+	Extra   value.Value `db:"extra"`
+	Ignored string
+	Skipped string `db:"-"`
+}
+
+func withTable(as *assert.Assertions, fn func(tbl table.Table)) {
+	_, err := db.NewDatabase()(func(d database.Database) error {
+		tbl, err := d.CreateTable("users",
+			column.Make("id"), column.Make("name"), column.Make("age"),
+			column.Make("score"), column.Make("active"),
+			column.Make("avatar"), column.Make("extra"),
+		)
+		as.Nil(err)
+		fn(tbl)
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestMapper(t *testing.T) {
+	as := assert.New(t)
+
+	withTable(as, func(tbl table.Table) {
+		score := 9.5
+		bill := &user{
+			Name:    "Bill",
+			Age:     17,
+			Score:   &score,
+			Active:  true,
+			Avatar:  []byte{1, 2},
+			Extra:   value.String("excellent"),
+			Ignored: "ignored",
+		}
+		as.Nil(mapper.Insert(tbl, value.Key("bill"), bill))
+
+		row, ok := tbl.Select(value.Key("bill"))
+		as.True(ok)
+		as.Equal(relation.Row{
+			nil, value.String("Bill"), value.Integer(17), value.Float(9.5),
+			value.Bool(true), value.Key{1, 2}, value.String("excellent"),
+		}, row)
+
+		res, ok, err := mapper.Select[user](tbl, value.Key("bill"))
+		as.Nil(err)
+		as.True(ok)
+		bill.Ignored = ""
+		as.Equal(bill, res)
+
+		m, err := mapper.New[user](tbl)
+		as.Nil(err)
+		k, err := m.InsertAuto(&user{Name: "Ted"})
+		as.Nil(err)
+		res, ok, err = m.Select(k)
+		as.Nil(err)
+		as.True(ok)
+		as.Nil(res.Score)
+		as.Nil(res.Avatar)
+
+		res.Age = 18
+		as.Nil(m.Update(k, res))
+		row, _ = tbl.Select(k)
+		as.Equal(value.Integer(18), row[2])
+
+		_, ok, err = m.Select(value.Key("missing"))
+		as.Nil(err)
+		as.False(ok)
+	})
+}
+
+func TestMapperErrors(t *testing.T) {
+	as := assert.New(t)
+
+	withTable(as, func(tbl table.Table) {
+		_, err := mapper.New[string](tbl)
+		as.EqualError(err, fmt.Sprintf(mapper.ErrNotStruct, "string"))
+
+		_, err = mapper.New[struct {
+			Missing string `db:"missing"`
+		}](tbl)
+		as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+		_, err = mapper.New[struct {
+			First  string `db:"name"`
+			Second string `db:"name"`
+		}](tbl)
+		as.EqualError(err, fmt.Sprintf(mapper.ErrDuplicateColumn, "name"))
+
+		_, err = mapper.New[struct {
+			Tags []string `db:"extra"`
+		}](tbl)
+		as.EqualError(err, fmt.Sprintf(mapper.ErrUnsupportedField, "Tags"))
+
+		as.Nil(tbl.Insert(value.Key("old"), relation.Row{
+			nil, value.String("Rufus"), value.Integer(700),
+		}))
+		_, _, err = mapper.Select[user](tbl, value.Key("old"))
+		as.EqualError(err, fmt.Sprintf(mapper.ErrIncompatibleValue, "Age"))
+
+		m, err := mapper.New[user](tbl)
+		as.Nil(err)
+		as.Nil(tbl.DropColumn("id"))
+		_, err = m.Row(&user{Name: "Ted"})
+		as.EqualError(err, fmt.Sprintf(mapper.ErrColumnsChanged, "users"))
+		_, _, err = m.Select(value.Key("old"))
+		as.EqualError(err, fmt.Sprintf(mapper.ErrColumnsChanged, "users"))
+
+		m, err = mapper.New[user](tbl)
+		as.Nil(err)
+		r, err := m.Row(&user{Name: "Ted"})
+		as.Nil(err)
+		as.Equal(value.String("Ted"), r[0])
+	})
+}
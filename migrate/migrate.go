@@ -0,0 +1,187 @@
+package migrate
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// ID identifies a Migration
+	ID string
+
+	// IDs are a set of ID
+	IDs []ID
+
+	// Migration is a database.Query that is applied to a Database no more
+	// than once
+	Migration struct {
+		ID    ID
+		Query database.Query
+	}
+
+	// Migrations are an ordered set of Migration
+	Migrations []Migration
+)
+
+// HistoryTable is the reserved system table that records the IDs of the
+// Migrations that have been applied to a Database, in order. Its name is
+// namespaced so that it doesn't collide with an application's tables, and
+// a table of that name that doesn't have its Columns is refused
+const HistoryTable table.Name = "_caravan_migrations"
+
+// IDColumn is the HistoryTable column that stores a Migration's ID
+const IDColumn column.Name = "id"
+
+// Error messages
+const (
+	ErrDuplicateMigration = "migration already registered: %s"
+	ErrHistoryDiverged    = "applied migration does not match registered: %s"
+	ErrHistoryTooLong     = "applied migration is not registered: %s"
+	ErrNotHistoryTable    = "table is not a migration history: %s"
+	ErrInvalidHistory     = "migration history has an invalid entry: %d"
+)
+
+var registry = struct {
+	sync.Mutex
+	Migrations
+}{}
+
+// Register adds a Migration to the end of the set that Up applies
+func Register(id ID, q database.Query) error {
+	registry.Lock()
+	defer registry.Unlock()
+	for _, m := range registry.Migrations {
+		if m.ID == id {
+			return fmt.Errorf(ErrDuplicateMigration, id)
+		}
+	}
+	registry.Migrations = append(registry.Migrations, Migration{
+		ID:    id,
+		Query: q,
+	})
+	return nil
+}
+
+// Up applies the pending registered Migrations to the Database
+func Up(d database.Transactor) (database.Transactor, error) {
+	registry.Lock()
+	m := registry.Migrations
+	registry.Unlock()
+	return m.Up(d)
+}
+
+// Up applies the Migrations that are not yet recorded in the Database's
+// HistoryTable. All pending Migrations are applied in a single transaction,
+// so if any of them fails, the original Database is returned. Up refuses
+// to apply anything if the recorded history is not a prefix of these
+// Migrations
+func (m Migrations) Up(d database.Transactor) (database.Transactor, error) {
+	return d(func(d database.Database) error {
+		if err := m.checkDuplicates(); err != nil {
+			return err
+		}
+		history, err := historyTable(d)
+		if err != nil {
+			return err
+		}
+		applied, err := appliedIDs(history)
+		if err != nil {
+			return err
+		}
+		if err := m.checkHistory(applied); err != nil {
+			return err
+		}
+		for i, mig := range m[len(applied):] {
+			if err := mig.Query(d); err != nil {
+				return err
+			}
+			key := historyKey(len(applied) + i)
+			row := relation.Row{value.String(mig.ID)}
+			if err := history.Insert(key, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m Migrations) checkDuplicates() error {
+	seen := make(map[ID]bool, len(m))
+	for _, mig := range m {
+		if seen[mig.ID] {
+			return fmt.Errorf(ErrDuplicateMigration, mig.ID)
+		}
+		seen[mig.ID] = true
+	}
+	return nil
+}
+
+func (m Migrations) checkHistory(applied IDs) error {
+	for i, id := range applied {
+		if i >= len(m) {
+			return fmt.Errorf(ErrHistoryTooLong, id)
+		}
+		if m[i].ID != id {
+			return fmt.Errorf(ErrHistoryDiverged, id)
+		}
+	}
+	return nil
+}
+
+// Applied returns the IDs of the Migrations that have been applied to the
+// Database, in the order they were applied
+func Applied(d database.Database) (IDs, error) {
+	if history, ok := d.Table(HistoryTable); ok {
+		if err := checkHistoryTable(history); err != nil {
+			return nil, err
+		}
+		return appliedIDs(history)
+	}
+	return IDs{}, nil
+}
+
+func appliedIDs(history table.Table) (IDs, error) {
+	res := IDs{}
+	for i := 0; ; i++ {
+		row, ok := history.Select(historyKey(i))
+		if !ok {
+			return res, nil
+		}
+		id, ok := value.As[string](row[0])
+		if !ok {
+			return nil, fmt.Errorf(ErrInvalidHistory, i)
+		}
+		res = append(res, ID(id))
+	}
+}
+
+func historyTable(d database.Database) (table.Table, error) {
+	if history, ok := d.Table(HistoryTable); ok {
+		if err := checkHistoryTable(history); err != nil {
+			return nil, err
+		}
+		return history, nil
+	}
+	return d.CreateTable(HistoryTable, column.Of[string](IDColumn))
+}
+
+// checkHistoryTable makes sure that a table found under the HistoryTable's
+// name was created to record Migrations
+func checkHistoryTable(history table.Table) error {
+	cols := history.Columns()
+	if len(cols) != 1 || cols[0].Name() != IDColumn ||
+		column.TypeOf(cols[0]) != column.StringType {
+		return fmt.Errorf(ErrNotHistoryTable, history.Name())
+	}
+	return nil
+}
+
+func historyKey(i int) value.Key {
+	return value.Integer(i).Bytes()
+}
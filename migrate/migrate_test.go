@@ -0,0 +1,165 @@
+package migrate_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/migrate"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTable(n table.Name) database.Query {
+	return func(d database.Database) error {
+		_, err := d.CreateTable("table-"+n, column.Make("col"))
+		return err
+	}
+}
+
+func TestUp(t *testing.T) {
+	as := assert.New(t)
+
+	first := migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+		{ID: "2", Query: createTable("2")},
+	}
+	d, err := first.Up(db.NewDatabase())
+	as.Nil(err)
+
+	second := append(first, migrate.Migration{
+		ID: "3", Query: createTable("3"),
+	})
+	d, err = second.Up(d)
+	as.Nil(err)
+
+	d, err = second.Up(d)
+	as.Nil(err)
+
+	_, err = d(func(d database.Database) error {
+		applied, err := migrate.Applied(d)
+		as.Nil(err)
+		as.Equal(migrate.IDs{"1", "2", "3"}, applied)
+		as.Equal(4, len(d.Tables()))
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestUpFailure(t *testing.T) {
+	as := assert.New(t)
+
+	d, err := migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+	}.Up(db.NewDatabase())
+	as.Nil(err)
+
+	d, err = migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+		{ID: "2", Query: createTable("2")},
+		{ID: "3", Query: func(database.Database) error {
+			return errors.New("boom")
+		}},
+	}.Up(d)
+	as.EqualError(err, "boom")
+
+	_, err = d(func(d database.Database) error {
+		applied, err := migrate.Applied(d)
+		as.Nil(err)
+		as.Equal(migrate.IDs{"1"}, applied)
+		_, ok := d.Table("table-2")
+		as.False(ok)
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestUpDiverged(t *testing.T) {
+	as := assert.New(t)
+
+	d, err := migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+		{ID: "2", Query: createTable("2")},
+	}.Up(db.NewDatabase())
+	as.Nil(err)
+
+	_, err = migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+		{ID: "other", Query: createTable("other")},
+	}.Up(d)
+	as.EqualError(err, fmt.Sprintf(migrate.ErrHistoryDiverged, "2"))
+
+	_, err = migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+	}.Up(d)
+	as.EqualError(err, fmt.Sprintf(migrate.ErrHistoryTooLong, "2"))
+
+	_, err = migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+		{ID: "1", Query: createTable("1")},
+	}.Up(d)
+	as.EqualError(err, fmt.Sprintf(migrate.ErrDuplicateMigration, "1"))
+}
+
+func TestHistoryTable(t *testing.T) {
+	as := assert.New(t)
+
+	d, err := db.NewDatabase()(func(d database.Database) error {
+		_, err := d.CreateTable(migrate.HistoryTable, column.Make("name"))
+		return err
+	})
+	as.Nil(err)
+	_, err = migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+	}.Up(d)
+	as.EqualError(err,
+		fmt.Sprintf(migrate.ErrNotHistoryTable, migrate.HistoryTable),
+	)
+	_, err = d(func(d database.Database) error {
+		_, err := migrate.Applied(d)
+		return err
+	})
+	as.EqualError(err,
+		fmt.Sprintf(migrate.ErrNotHistoryTable, migrate.HistoryTable),
+	)
+
+	d, err = migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+	}.Up(db.NewDatabase())
+	as.Nil(err)
+	d, err = d(func(d database.Database) error {
+		history, _ := d.Table(migrate.HistoryTable)
+		return history.Insert(value.Integer(1).Bytes(), relation.Row{nil})
+	})
+	as.Nil(err)
+	_, err = migrate.Migrations{
+		{ID: "1", Query: createTable("1")},
+	}.Up(d)
+	as.EqualError(err, fmt.Sprintf(migrate.ErrInvalidHistory, 1))
+}
+
+func TestRegister(t *testing.T) {
+	as := assert.New(t)
+
+	as.Nil(migrate.Register("registered-1", createTable("1")))
+	as.Nil(migrate.Register("registered-2", createTable("2")))
+	err := migrate.Register("registered-1", createTable("1"))
+	as.EqualError(err,
+		fmt.Sprintf(migrate.ErrDuplicateMigration, "registered-1"),
+	)
+
+	d, err := migrate.Up(db.NewDatabase())
+	as.Nil(err)
+	_, err = d(func(d database.Database) error {
+		applied, err := migrate.Applied(d)
+		as.Nil(err)
+		as.Equal(migrate.IDs{"registered-1", "registered-2"}, applied)
+		return nil
+	})
+	as.Nil(err)
+}
@@ -0,0 +1,480 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// AggregateFunc identifies how an Aggregate combines Values
+	AggregateFunc string
+
+	// Aggregate computes a single Value from the Values of a Column across
+	// the rows of a group. An Aggregate without a Column counts rows
+	Aggregate struct {
+		Func   AggregateFunc
+		Column column.Name
+		Name   column.Name
+	}
+
+	// Aggregates are a set of Aggregate
+	Aggregates []Aggregate
+
+	// aggregator accumulates the Values of a single group
+	aggregator interface {
+		add(value.Value) error
+		result() value.Value
+	}
+
+	countAggregator struct {
+		all   bool
+		count int64
+	}
+
+	sumAggregator struct {
+		sum   value.Value
+		count int64
+		avg   bool
+	}
+
+	extremeAggregator struct {
+		best value.Value
+		want value.Comparison
+	}
+
+	distinctAggregator struct {
+		seen map[string]bool
+	}
+
+	// group accumulates the Aggregates of rows sharing grouping Values
+	group struct {
+		key    value.Key
+		values relation.Relation
+		aggs   []aggregator
+	}
+
+	aggregation struct {
+		input   Plan
+		groups  column.Names
+		aggs    Aggregates
+		groupBy column.Offsets
+		aggOff  []int
+		columns column.Names
+		ordered bool
+	}
+)
+
+// Aggregate functions
+const (
+	CountFunc         AggregateFunc = "count"
+	SumFunc           AggregateFunc = "sum"
+	MinFunc           AggregateFunc = "min"
+	MaxFunc           AggregateFunc = "max"
+	AvgFunc           AggregateFunc = "avg"
+	CountDistinctFunc AggregateFunc = "countDistinct"
+)
+
+// Error messages
+const (
+	ErrUnknownAggregate = "unknown aggregate function: %s"
+	ErrNotNumeric       = "value is not numeric: %s"
+	ErrNotOrdered       = "values can't be ordered: %s"
+	ErrHavingNotGrouped = "having requires grouping or aggregates: %s"
+)
+
+// Count returns an Aggregate that counts the non-null Values of a Column,
+// or all rows if no Column is provided
+func Count(col ...column.Name) Aggregate {
+	if len(col) == 0 {
+		return makeAggregate(CountFunc, "")
+	}
+	return makeAggregate(CountFunc, col[0])
+}
+
+// Sum returns an Aggregate that adds up the Values of a Column
+func Sum(col column.Name) Aggregate {
+	return makeAggregate(SumFunc, col)
+}
+
+// Min returns an Aggregate that finds the least Value of a Column
+func Min(col column.Name) Aggregate {
+	return makeAggregate(MinFunc, col)
+}
+
+// Max returns an Aggregate that finds the greatest Value of a Column
+func Max(col column.Name) Aggregate {
+	return makeAggregate(MaxFunc, col)
+}
+
+// Avg returns an Aggregate that averages the Values of a Column
+func Avg(col column.Name) Aggregate {
+	return makeAggregate(AvgFunc, col)
+}
+
+// CountDistinct returns an Aggregate that counts the distinct non-null
+// Values of a Column
+func CountDistinct(col column.Name) Aggregate {
+	return makeAggregate(CountDistinctFunc, col)
+}
+
+func makeAggregate(fn AggregateFunc, col column.Name) Aggregate {
+	arg := string(col)
+	if arg == "" {
+		arg = "*"
+	}
+	return Aggregate{
+		Func:   fn,
+		Column: col,
+		Name:   column.Name(fmt.Sprintf("%s(%s)", fn, arg)),
+	}
+}
+
+// As names the Column that the Aggregate produces
+func (a Aggregate) As(n column.Name) Aggregate {
+	a.Name = n
+	return a
+}
+
+func (a Aggregate) aggregator() aggregator {
+	switch a.Func {
+	case SumFunc:
+		return &sumAggregator{}
+	case AvgFunc:
+		return &sumAggregator{avg: true}
+	case MinFunc:
+		return &extremeAggregator{want: value.LessThan}
+	case MaxFunc:
+		return &extremeAggregator{want: value.GreaterThan}
+	case CountDistinctFunc:
+		return &distinctAggregator{seen: map[string]bool{}}
+	default:
+		return &countAggregator{all: a.Column == ""}
+	}
+}
+
+// GroupBy groups the Query's rows by the Values of the named Columns. A
+// grouped Query produces the grouping Columns followed by its Aggregates
+func (q *Query) GroupBy(cols ...column.Name) *Query {
+	res := q.copy()
+	res.groups = append(res.groups[:len(q.groups):len(q.groups)], cols...)
+	return res
+}
+
+// Aggregate computes Aggregates over the groups of the Query. If the Query
+// isn't grouped, the Aggregates are computed over all of its rows
+func (q *Query) Aggregate(aggs ...Aggregate) *Query {
+	for _, a := range aggs {
+		switch a.Func {
+		case CountFunc, SumFunc, MinFunc, MaxFunc, AvgFunc, CountDistinctFunc:
+		default:
+			return q.fail(fmt.Errorf(ErrUnknownAggregate, a.Func))
+		}
+	}
+	res := q.copy()
+	res.aggs = append(res.aggs[:len(q.aggs):len(q.aggs)], aggs...)
+	return res
+}
+
+// Having restricts the groups of the Query to those for which the provided
+// Expr is true. The Expr refers to grouping Columns and Aggregates by name
+func (q *Query) Having(e *expr.Expr) *Query {
+	res := q.copy()
+	res.having = append(res.having[:len(q.having):len(q.having)], e)
+	return res
+}
+
+func (q *Query) grouped() bool {
+	return len(q.groups) != 0 || len(q.aggs) != 0
+}
+
+// groupedAccess replaces a full scan of the Table with a scan of an Index
+// whose leading Columns are the grouping Columns, so that groups can be
+// aggregated as they stream by. Returns whether the input is so ordered
+func (q *Query) groupedAccess(tbl table.Table, p Plan) (Plan, bool) {
+	if len(q.groups) == 0 {
+		return p, false
+	}
+	def, _, ok := joinIndex(tbl, q.groups)
+	if !ok {
+		return p, false
+	}
+	switch p := p.(type) {
+	case *indexScan:
+		return p, p.index == def.Name
+	case *scan:
+		return newIndexScan(tbl, &access{def: def}), true
+	default:
+		return p, false
+	}
+}
+
+func newAggregation(
+	input Plan, groups column.Names, aggs Aggregates, ordered bool,
+) (*aggregation, error) {
+	cols := makeColumns(input.Columns())
+	groupBy, err := relation.MakeOffsets(cols, groups...)
+	if err != nil {
+		return nil, err
+	}
+	aggOff := make([]int, len(aggs))
+	names := append(column.Names{}, groups...)
+	for i, a := range aggs {
+		aggOff[i] = -1
+		if a.Column != "" {
+			off, err := relation.MakeOffsets(cols, a.Column)
+			if err != nil {
+				return nil, err
+			}
+			aggOff[i] = int(off[0])
+		}
+		names = append(names, a.Name)
+	}
+	return &aggregation{
+		input:   input,
+		groups:  groups,
+		aggs:    aggs,
+		groupBy: groupBy,
+		aggOff:  aggOff,
+		columns: names,
+		ordered: ordered,
+	}, nil
+}
+
+func (a *aggregation) Columns() column.Names {
+	return a.columns
+}
+
+func (a *aggregation) String() string {
+	return Explain(a).chain()
+}
+
+func (a *aggregation) explain(st statistics) *Explanation {
+	parts := make([]string, 0, len(a.groups)+len(a.aggs))
+	for _, g := range a.groups {
+		parts = append(parts, string(g))
+	}
+	for _, agg := range a.aggs {
+		parts = append(parts, string(agg.Name))
+	}
+	in := a.input.explain(st)
+	est := 1.0
+	if len(a.groups) != 0 {
+		est = float64(in.Estimated) * equalSelectivity
+	}
+	op := HashAggregateOp
+	if a.ordered {
+		op = StreamAggregateOp
+	}
+	return st.describe(a, op, strings.Join(parts, ", "), est, in)
+}
+
+func (a *aggregation) open(e *execution) (transaction.Iterator, error) {
+	iter, err := e.open(a.input)
+	if err != nil {
+		return nil, err
+	}
+	if a.ordered {
+		return a.stream(e, iter), nil
+	}
+	return a.hash(e, iter), nil
+}
+
+// hash accumulates every group in a table before producing any of them
+func (a *aggregation) hash(
+	e *execution, iter transaction.Iterator,
+) transaction.Iterator {
+	var order []*group
+	groups := map[string]*group{}
+	for _, v, next, ok := iter(); ok; _, v, next, ok = next() {
+		r := v.(relation.Relation)
+		k := a.groupKey(r)
+		g, ok := groups[string(k)]
+		if !ok {
+			g = a.newGroup(k, r)
+			groups[string(k)] = g
+			order = append(order, g)
+		}
+		if err := a.add(g, r); err != nil {
+			e.fail(err)
+			return sliceIterator(nil)
+		}
+	}
+	if len(order) == 0 && len(a.groups) == 0 {
+		order = append(order, a.newGroup(value.Key{}, nil))
+	}
+	res := make([]item, len(order))
+	for i, g := range order {
+		res[i] = g.item()
+	}
+	return sliceIterator(res)
+}
+
+// stream aggregates input that is ordered by the grouping Columns, so
+// that each group is complete once a row of the next group arrives
+func (a *aggregation) stream(
+	e *execution, iter transaction.Iterator,
+) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		var g *group
+		for k, v, next, ok := iter(); ok; k, v, next, ok = next() {
+			r := v.(relation.Relation)
+			key := a.groupKey(r)
+			if g == nil {
+				g = a.newGroup(key, r)
+			} else if key.Compare(g.key) != value.EqualTo {
+				res := g.item()
+				rest := a.stream(e, func() (
+					value.Key, any, transaction.Iterator, bool,
+				) {
+					return k, v, next, true
+				})
+				return res.key, res.rel, rest, true
+			}
+			if err := a.add(g, r); err != nil {
+				e.fail(err)
+				return nil, nil, nil, false
+			}
+		}
+		if g == nil {
+			return nil, nil, nil, false
+		}
+		res := g.item()
+		return res.key, res.rel, sliceIterator(nil), true
+	}
+}
+
+// groupKey encodes the grouping Values of a row as an Index does, so that
+// rows ordered by an Index arrive with their group's rows
+func (a *aggregation) groupKey(r relation.Relation) value.Key {
+	vals := make(relation.Relation, len(a.groupBy))
+	for i, o := range a.groupBy {
+		vals[i] = r[o]
+	}
+	return index.KeyForValues(vals...)
+}
+
+func (a *aggregation) newGroup(k value.Key, r relation.Relation) *group {
+	res := &group{
+		key:    k,
+		values: make(relation.Relation, len(a.groupBy)),
+		aggs:   make([]aggregator, len(a.aggs)),
+	}
+	for i, o := range a.groupBy {
+		res.values[i] = r[o]
+	}
+	for i, agg := range a.aggs {
+		res.aggs[i] = agg.aggregator()
+	}
+	return res
+}
+
+func (a *aggregation) add(g *group, r relation.Relation) error {
+	for i, agg := range g.aggs {
+		var v value.Value = value.Bool(true)
+		if o := a.aggOff[i]; o >= 0 {
+			v = r[o]
+		}
+		if err := agg.add(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *group) item() item {
+	rel := append(relation.Relation{}, g.values...)
+	for _, agg := range g.aggs {
+		rel = append(rel, agg.result())
+	}
+	return item{key: g.key, rel: rel}
+}
+
+func (c *countAggregator) add(v value.Value) error {
+	if c.all || v != nil {
+		c.count++
+	}
+	return nil
+}
+
+func (c *countAggregator) result() value.Value {
+	return value.Integer(c.count)
+}
+
+func (s *sumAggregator) add(v value.Value) error {
+	if v == nil {
+		return nil
+	}
+	switch v.(type) {
+	case value.Integer, value.Float:
+	default:
+		return fmt.Errorf(ErrNotNumeric, expr.Lit(v))
+	}
+	s.count++
+	switch sum := s.sum.(type) {
+	case nil:
+		s.sum = v
+	case value.Integer:
+		if i, ok := v.(value.Integer); ok {
+			s.sum = sum + i
+		} else {
+			s.sum = value.Float(sum) + v.(value.Float)
+		}
+	case value.Float:
+		s.sum = sum + toFloat(v)
+	}
+	return nil
+}
+
+func (s *sumAggregator) result() value.Value {
+	if s.sum == nil || !s.avg {
+		return s.sum
+	}
+	return toFloat(s.sum) / value.Float(s.count)
+}
+
+func toFloat(v value.Value) value.Float {
+	if i, ok := v.(value.Integer); ok {
+		return value.Float(i)
+	}
+	return v.(value.Float)
+}
+
+func (x *extremeAggregator) add(v value.Value) error {
+	if v == nil {
+		return nil
+	}
+	if x.best == nil {
+		x.best = v
+		return nil
+	}
+	switch v.Compare(x.best) {
+	case x.want:
+		x.best = v
+	case value.Incomparable:
+		return fmt.Errorf(ErrNotOrdered, expr.Lit(v))
+	}
+	return nil
+}
+
+func (x *extremeAggregator) result() value.Value {
+	return x.best
+}
+
+func (d *distinctAggregator) add(v value.Value) error {
+	if v != nil {
+		d.seen[fmt.Sprintf("%T:%x", v, v.Bytes())] = true
+	}
+	return nil
+}
+
+func (d *distinctAggregator) result() value.Value {
+	return value.Integer(len(d.seen))
+}
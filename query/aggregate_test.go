@@ -0,0 +1,184 @@
+package query_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/query"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateAll(t *testing.T) {
+	as := assert.New(t)
+
+	res, err := run(query.From("people").Aggregate(
+		query.Count(),
+		query.Count("last_name"),
+		query.CountDistinct("last_name"),
+		query.Sum("age"),
+		query.Min("first_name"),
+		query.Max("age").As("oldest"),
+		query.Avg("age"),
+	))
+	as.Nil(err)
+	as.Equal(column.Names{
+		"count(*)", "count(last_name)", "countDistinct(last_name)",
+		"sum(age)", "min(first_name)", "oldest", "avg(age)",
+	}, res.Columns)
+	as.Equal([]relation.Relation{{
+		value.Integer(5), value.Integer(4), value.Integer(2),
+		value.Integer(783), value.String("Bill"), value.Integer(700),
+		value.Float(156.6),
+	}}, res.Rows)
+
+	res, err = run(query.From("people").
+		Where("age", query.GT, value.Integer(1000)).
+		Aggregate(query.Count(), query.Sum("age")),
+	)
+	as.Nil(err)
+	as.Equal([]relation.Relation{{value.Integer(0), nil}}, res.Rows)
+}
+
+func TestGroupBy(t *testing.T) {
+	as := assert.New(t)
+
+	q := query.From("people").
+		GroupBy("last_name").
+		Aggregate(query.Count().As("members"), query.Avg("age")).
+		OrderBy("last_name")
+
+	res, err := run(q)
+	as.Nil(err)
+	as.Equal(column.Names{"last_name", "members", "avg(age)"}, res.Columns)
+	as.Equal([]relation.Relation{
+		{nil, value.Integer(1), value.Float(700)},
+		{value.String("Logan"), value.Integer(2), value.Float(20.5)},
+		{value.String("Preston"), value.Integer(2), value.Float(21)},
+	}, res.Rows)
+
+	res, err = run(q.
+		Having(expr.GT(expr.Col("members"), expr.Lit(value.Integer(1)))).
+		Select("last_name"),
+	)
+	as.Nil(err)
+	as.Equal([]relation.Relation{
+		{value.String("Logan")}, {value.String("Preston")},
+	}, res.Rows)
+}
+
+func TestGroupByIndex(t *testing.T) {
+	as := assert.New(t)
+
+	plan, res, err := runIndexed(query.From("people").
+		GroupBy("first_name", "last_name").
+		Aggregate(query.Max("age")).
+		Having(expr.LT(expr.Col("max(age)"), expr.Lit(value.Integer(20)))),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Filter(max(age) < 20) <- `+
+			`StreamAggregate(first_name, last_name, max(age)) <- `+
+			`IndexScan(people.by-name)`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{
+		{value.String("Ted"), value.String("Logan"), value.Integer(17)},
+		{value.String("Bill"), value.String("Preston"), value.Integer(17)},
+	}, res.Rows)
+
+	plan, res, err = runIndexed(query.From("people").
+		Where("age", query.LT, value.Integer(100)).
+		GroupBy("last_name").
+		Aggregate(query.Count()),
+	)
+	as.Nil(err)
+	as.Equal(
+		`HashAggregate(last_name, count(*)) <- `+
+			`IndexScan(people.by-age: age < 100)`,
+		plan.String(),
+	)
+	as.Equal(2, len(res.Rows))
+}
+
+func TestGroupByNullAndEmpty(t *testing.T) {
+	as := assert.New(t)
+
+	d, err := db.NewDatabase()(func(d database.Database) error {
+		tbl, err := d.CreateTable("tags", column.Make("g"), column.Make("n"))
+		as.Nil(err)
+		for _, r := range []relation.Row{
+			{value.String(""), value.Integer(1)},
+			{nil, value.Integer(2)},
+			{value.String(""), value.Integer(3)},
+		} {
+			_, err := tbl.InsertAuto(r)
+			as.Nil(err)
+		}
+		return nil
+	})
+	as.Nil(err)
+
+	q := query.From("tags").GroupBy("g").Aggregate(query.Count())
+	expected := []relation.Relation{
+		{value.String(""), value.Integer(2)},
+		{nil, value.Integer(1)},
+	}
+	d, err = d(func(d database.Database) error {
+		plan, err := q.Plan(d)
+		as.Nil(err)
+		as.Contains(plan.String(), "HashAggregate")
+		res, err := query.Execute(d, plan)
+		as.Nil(err)
+		as.ElementsMatch(expected, res.Rows)
+
+		tbl, _ := d.Table("tags")
+		return tbl.CreateIndex(db.StandardIndex, "by-g", "g")
+	})
+	as.Nil(err)
+
+	_, err = d(func(d database.Database) error {
+		plan, err := q.Plan(d)
+		as.Nil(err)
+		as.Contains(plan.String(), "StreamAggregate")
+		res, err := query.Execute(d, plan)
+		as.Nil(err)
+		as.ElementsMatch(expected, res.Rows)
+		return nil
+	})
+	as.Nil(err)
+}
+
+func TestAggregateErrors(t *testing.T) {
+	as := assert.New(t)
+
+	_, err := run(query.From("people").Aggregate(query.Aggregate{
+		Func: "median", Column: "age",
+	}))
+	as.EqualError(err, fmt.Sprintf(query.ErrUnknownAggregate, "median"))
+
+	_, err = run(query.From("people").Aggregate(query.Sum("first_name")))
+	as.ErrorContains(err, "value is not numeric")
+
+	_, err = run(query.From("people").Aggregate(query.Avg("missing")))
+	as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+	having := expr.IsNull(expr.Col("age"))
+	_, err = run(query.From("people").Having(having))
+	as.EqualError(err, fmt.Sprintf(query.ErrHavingNotGrouped, having))
+
+	_, err = db.NewDatabase()(func(d database.Database) error {
+		tbl, err := d.CreateTable("mixed", column.Make("v"))
+		as.Nil(err)
+		_, _ = tbl.InsertAuto(relation.Row{value.Integer(1)})
+		_, _ = tbl.InsertAuto(relation.Row{value.String("one")})
+		_, err = query.From("mixed").Aggregate(query.Min("v")).Run(d)
+		return err
+	})
+	as.ErrorContains(err, "values can't be ordered")
+}
@@ -0,0 +1,198 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Operation names the kind of step described by an Explanation
+	Operation string
+
+	// Explanation describes a step of a Plan, how many rows it's estimated
+	// to produce and, if the Plan was analyzed, what actually happened
+	Explanation struct {
+		Operation Operation      `json:"operation"`
+		Detail    string         `json:"detail,omitempty"`
+		Estimated int            `json:"estimated"`
+		Actual    *Actual        `json:"actual,omitempty"`
+		Inputs    []*Explanation `json:"inputs,omitempty"`
+	}
+
+	// Actual holds the measurements of a step gathered while executing a
+	// Plan. Duration includes the time spent in the step's inputs
+	Actual struct {
+		Rows     int           `json:"rows"`
+		Duration time.Duration `json:"duration"`
+	}
+
+	// statistics collects the Actual measurements of each step in a Plan
+	statistics map[Plan]*Actual
+)
+
+// Operations
+const (
+	ScanOp      Operation = "Scan"
+	IndexScanOp Operation = "IndexScan"
+	FilterOp    Operation = "Filter"
+	SortOp      Operation = "Sort"
+	LimitOp     Operation = "Limit"
+	ProjectOp   Operation = "Project"
+
+	NestedLoopJoinOp Operation = "NestedLoopJoin"
+	HashJoinOp       Operation = "HashJoin"
+	MergeJoinOp      Operation = "MergeJoin"
+
+	HashAggregateOp   Operation = "HashAggregate"
+	StreamAggregateOp Operation = "StreamAggregate"
+)
+
+// Selectivities used to estimate the rows that satisfy a predicate
+const (
+	equalSelectivity   = 0.1
+	rangeSelectivity   = 0.3
+	defaultSelectivity = 0.5
+)
+
+// Explain describes the Plan without executing it
+func Explain(p Plan) *Explanation {
+	return p.explain(nil)
+}
+
+// ExplainAnalyze executes the Plan against the Database, discarding its
+// results, and describes the Plan along with the rows each step produced
+// and the time it took
+func ExplainAnalyze(d database.Database, p Plan) (*Explanation, error) {
+	e := &execution{
+		db:    d,
+		stats: statistics{},
+	}
+	iter, err := e.open(p)
+	if err != nil {
+		return nil, err
+	}
+	_ = iterate.ForEach(iter, func(value.Key, any) error {
+		return nil
+	})
+	if e.err != nil {
+		return nil, e.err
+	}
+	return p.explain(e.stats), nil
+}
+
+// String renders the Explanation as an indented tree
+func (e *Explanation) String() string {
+	var buf strings.Builder
+	e.write(&buf, 0)
+	return buf.String()
+}
+
+func (e *Explanation) write(buf *strings.Builder, depth int) {
+	buf.WriteString(strings.Repeat("  ", depth))
+	buf.WriteString(e.label())
+	fmt.Fprintf(buf, " [estimated rows: %d", e.Estimated)
+	if a := e.Actual; a != nil {
+		fmt.Fprintf(buf, ", actual rows: %d, time: %s", a.Rows, a.Duration)
+	}
+	buf.WriteString("]\n")
+	for _, in := range e.Inputs {
+		in.write(buf, depth+1)
+	}
+}
+
+func (e *Explanation) label() string {
+	return fmt.Sprintf("%s(%s)", e.Operation, e.Detail)
+}
+
+// chain renders the Explanation on a single line, followed by its inputs
+func (e *Explanation) chain() string {
+	switch len(e.Inputs) {
+	case 0:
+		return e.label()
+	case 1:
+		return fmt.Sprintf("%s <- %s", e.label(), e.Inputs[0].chain())
+	default:
+		inputs := make([]string, len(e.Inputs))
+		for i, in := range e.Inputs {
+			inputs[i] = in.chain()
+		}
+		return fmt.Sprintf("%s <- [%s]",
+			e.label(), strings.Join(inputs, ", "),
+		)
+	}
+}
+
+func (s statistics) describe(
+	p Plan, op Operation, detail string, est float64, in ...*Explanation,
+) *Explanation {
+	return &Explanation{
+		Operation: op,
+		Detail:    detail,
+		Estimated: int(math.Ceil(est)),
+		Actual:    s[p],
+		Inputs:    in,
+	}
+}
+
+// instrument wraps a Plan's Iterator so that the rows it produces and the
+// time spent producing them are recorded
+func (s statistics) instrument(
+	p Plan, iter transaction.Iterator, opened time.Duration,
+) transaction.Iterator {
+	a, ok := s[p]
+	if !ok {
+		a = &Actual{}
+		s[p] = a
+	}
+	a.Duration += opened
+	return measure(a, iter)
+}
+
+func measure(a *Actual, iter transaction.Iterator) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		start := time.Now()
+		k, v, next, ok := iter()
+		a.Duration += time.Since(start)
+		if !ok {
+			return nil, nil, nil, false
+		}
+		a.Rows++
+		return k, v, measure(a, next), true
+	}
+}
+
+// selectivity estimates the fraction of rows that satisfy a predicate
+func selectivity(e *expr.Expr) float64 {
+	switch e.Op {
+	case expr.EQOp, expr.IsNullOp:
+		return equalSelectivity
+	case expr.LTOp, expr.LTEOp, expr.GTOp, expr.GTEOp:
+		return rangeSelectivity
+	case expr.NEQOp:
+		return 1 - equalSelectivity
+	case expr.NotOp:
+		return 1 - selectivity(e.Args[0])
+	case expr.AndOp:
+		res := 1.0
+		for _, a := range e.Args {
+			res *= selectivity(a)
+		}
+		return res
+	case expr.OrOp:
+		res := 0.0
+		for _, a := range e.Args {
+			res += selectivity(a)
+		}
+		return math.Min(res, 1)
+	default:
+		return defaultSelectivity
+	}
+}
@@ -0,0 +1,108 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/query"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExplain(t *testing.T) {
+	as := assert.New(t)
+
+	plan, _, err := runIndexed(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")).
+		Where("age", query.GT, value.Integer(20)).
+		OrderBy("first_name").
+		Limit(1).
+		Select("first_name"),
+	)
+	as.Nil(err)
+
+	ex := query.Explain(plan)
+	as.Equal(query.ProjectOp, ex.Operation)
+	as.Equal("first_name", ex.Detail)
+	as.Nil(ex.Actual)
+
+	ex = ex.Inputs[0]
+	as.Equal(query.LimitOp, ex.Operation)
+	as.Equal("0, 1", ex.Detail)
+	as.Equal(1, ex.Estimated)
+
+	ex = ex.Inputs[0]
+	as.Equal(query.FilterOp, ex.Operation)
+	as.Equal("age > 20", ex.Detail)
+	as.Equal(1, ex.Estimated)
+
+	ex = ex.Inputs[0]
+	as.Equal(query.IndexScanOp, ex.Operation)
+	as.Equal(`people.by-name: last_name = "Preston"`, ex.Detail)
+	as.Equal(1, ex.Estimated)
+	as.Nil(ex.Inputs)
+
+	as.Equal(
+		"Project(first_name) [estimated rows: 1]\n"+
+			"  Limit(0, 1) [estimated rows: 1]\n"+
+			"    Filter(age > 20) [estimated rows: 1]\n"+
+			"      IndexScan(people.by-name: last_name = \"Preston\") "+
+			"[estimated rows: 1]\n",
+		query.Explain(plan).String(),
+	)
+}
+
+func TestExplainSort(t *testing.T) {
+	as := assert.New(t)
+
+	plan, _, err := runIndexed(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")).
+		Where("age", query.GT, value.Integer(20)).
+		OrderBy("age").
+		Limit(1).
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		"Project(first_name) [estimated rows: 1]\n"+
+			"  Limit(0, 1) [estimated rows: 1]\n"+
+			"    Sort(age) [estimated rows: 1]\n"+
+			"      Filter(age > 20) [estimated rows: 1]\n"+
+			"        IndexScan(people.by-name: last_name = \"Preston\") "+
+			"[estimated rows: 1]\n",
+		query.Explain(plan).String(),
+	)
+}
+
+func TestExplainAnalyze(t *testing.T) {
+	as := assert.New(t)
+
+	q := query.From("people").
+		Where("age", query.LT, value.Integer(30)).
+		Limit(2)
+
+	_, err := makePeopleDatabase()(func(d database.Database) error {
+		plan, err := q.Plan(d)
+		as.Nil(err)
+
+		ex, err := query.ExplainAnalyze(d, plan)
+		as.Nil(err)
+		as.Equal(query.LimitOp, ex.Operation)
+		as.Equal(2, ex.Estimated)
+		as.Equal(2, ex.Actual.Rows)
+
+		ex = ex.Inputs[0]
+		as.Equal(query.FilterOp, ex.Operation)
+		as.Equal(2, ex.Estimated)
+		as.Equal(2, ex.Actual.Rows)
+
+		ex = ex.Inputs[0]
+		as.Equal(query.ScanOp, ex.Operation)
+		as.Equal("people", ex.Detail)
+		as.Equal(5, ex.Estimated)
+		as.LessOrEqual(2, ex.Actual.Rows)
+		as.Contains(ex.String(), "actual rows: ")
+		return nil
+	})
+	as.Nil(err)
+}
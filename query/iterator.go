@@ -0,0 +1,52 @@
+package query
+
+import (
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// item is a materialized element of an Iterator of Relations
+	item struct {
+		key value.Key
+		rel relation.Relation
+	}
+
+	expander func(value.Key, any) []item
+)
+
+// flatMapIterator reports every item that the expander produces for each
+// of the pairs
+func flatMapIterator(
+	iter transaction.Iterator, fn expander,
+) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		for k, v, next, ok := iter(); ok; k, v, next, ok = next() {
+			if items := fn(k, v); len(items) != 0 {
+				rest := flatMapIterator(next, fn)
+				return iterate.Concat(sliceIterator(items), rest)()
+			}
+		}
+		return nil, nil, nil, false
+	}
+}
+
+func collect(iter transaction.Iterator) []item {
+	var res []item
+	_ = iterate.ForEach(iter, func(k value.Key, v any) error {
+		res = append(res, item{key: k, rel: v.(relation.Relation)})
+		return nil
+	})
+	return res
+}
+
+func sliceIterator(items []item) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		if len(items) == 0 {
+			return nil, nil, nil, false
+		}
+		return items[0].key, items[0].rel, sliceIterator(items[1:]), true
+	}
+}
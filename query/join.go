@@ -0,0 +1,761 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// JoinType determines which rows a Join produces
+	JoinType string
+
+	// JoinStrategy determines how a Join finds matching rows
+	JoinStrategy string
+
+	// JoinOn pairs a Column of the rows being joined with a Column of the
+	// joined Table. The pair matches when their Values are equal
+	JoinOn struct {
+		Left  column.Name
+		Right column.Name
+	}
+
+	// Join combines the rows of a Query with those of another Table. If
+	// no Strategy is provided, the planner chooses one
+	Join struct {
+		Type     JoinType
+		Table    table.Name
+		On       []JoinOn
+		Strategy JoinStrategy
+	}
+
+	// joinBase holds what every join Plan needs to match and combine rows
+	joinBase struct {
+		typ      JoinType
+		left     Plan
+		table    table.Name
+		on       []JoinOn
+		leftOff  column.Offsets
+		rightOff column.Offsets
+		names    column.Names
+		columns  column.Names
+		rows     int
+	}
+
+	nestedLoopJoin struct {
+		joinBase
+		index index.Name
+	}
+
+	hashJoin struct {
+		joinBase
+		right Plan
+	}
+
+	mergeJoin struct {
+		joinBase
+		right Plan
+	}
+
+	// joinSource describes the Table that a Query starts from, and is only
+	// available to the first Join, whose left rows can still be read
+	// through one of that Table's Indexes
+	joinSource struct {
+		table  table.Table
+		pushed []*expr.Expr
+	}
+)
+
+// Join types
+const (
+	Inner     JoinType = "inner"
+	LeftOuter JoinType = "left"
+	Semi      JoinType = "semi"
+	Anti      JoinType = "anti"
+)
+
+// Join strategies
+const (
+	NestedLoop JoinStrategy = "nested-loop"
+	Hash       JoinStrategy = "hash"
+	Merge      JoinStrategy = "merge"
+)
+
+// Error messages
+const (
+	ErrUnknownJoinType     = "unknown join type: %s"
+	ErrUnknownJoinStrategy = "unknown join strategy: %s"
+	ErrNoJoinConditions    = "join requires at least one condition: %s"
+	ErrJoinStrategy        = "join strategy can't be used: %s"
+	ErrAmbiguousColumn     = "column reference is ambiguous: %s"
+)
+
+// On pairs a Column of the rows being joined with a Column of the joined
+// Table
+func On(left, right column.Name) JoinOn {
+	return JoinOn{Left: left, Right: right}
+}
+
+// Join combines the Query's rows with the matching rows of another Table.
+// Once a Query has been joined, its Columns are qualified by the name of
+// their Table, as in "table.column"
+func (q *Query) Join(n table.Name, on ...JoinOn) *Query {
+	return q.JoinWith(Join{Type: Inner, Table: n, On: on})
+}
+
+// LeftJoin combines the Query's rows with the matching rows of another
+// Table, keeping rows that have no match by pairing them with nulls
+func (q *Query) LeftJoin(n table.Name, on ...JoinOn) *Query {
+	return q.JoinWith(Join{Type: LeftOuter, Table: n, On: on})
+}
+
+// SemiJoin restricts the Query to rows that have a match in another Table
+func (q *Query) SemiJoin(n table.Name, on ...JoinOn) *Query {
+	return q.JoinWith(Join{Type: Semi, Table: n, On: on})
+}
+
+// AntiJoin restricts the Query to rows that have no match in another
+// Table
+func (q *Query) AntiJoin(n table.Name, on ...JoinOn) *Query {
+	return q.JoinWith(Join{Type: Anti, Table: n, On: on})
+}
+
+// JoinWith adds a fully described Join to the Query
+func (q *Query) JoinWith(j Join) *Query {
+	switch j.Type {
+	case Inner, LeftOuter, Semi, Anti:
+	default:
+		return q.fail(fmt.Errorf(ErrUnknownJoinType, j.Type))
+	}
+	switch j.Strategy {
+	case "", NestedLoop, Hash, Merge:
+	default:
+		return q.fail(fmt.Errorf(ErrUnknownJoinStrategy, j.Strategy))
+	}
+	if len(j.On) == 0 {
+		return q.fail(fmt.Errorf(ErrNoJoinConditions, j.Table))
+	}
+	res := q.copy()
+	res.joins = append(res.joins[:len(q.joins):len(q.joins)], j)
+	return res
+}
+
+// planJoins reads the Query's Table, pushing down the restrictions that
+// only involve its Columns, and then performs each Join. The restrictions
+// that remain must be filtered after joining
+func (q *Query) planJoins(
+	d database.Database, tbl table.Table,
+) (Plan, []*expr.Expr, error) {
+	pushed, rest := pushDown(tbl, conjuncts(q.where))
+	src := &joinSource{table: tbl, pushed: pushed}
+
+	left, err := src.access()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, j := range q.joins {
+		right, ok := d.Table(j.Table)
+		if !ok {
+			return nil, nil, fmt.Errorf(ErrTableNotFound, j.Table)
+		}
+		if left, err = planJoin(left, src, right, j); err != nil {
+			return nil, nil, err
+		}
+		src = nil
+	}
+	return left, rest, nil
+}
+
+// pushDown separates the conjuncts that only refer to the Table's own
+// qualified Columns from those that must wait for the Joins
+func pushDown(
+	tbl table.Table, conj []*expr.Expr,
+) ([]*expr.Expr, []*expr.Expr) {
+	own := map[column.Name]bool{}
+	for _, c := range tbl.Columns() {
+		own[qualify(tbl.Name(), c.Name())] = true
+	}
+	var pushed, rest []*expr.Expr
+	for _, e := range conj {
+		cols := e.Columns()
+		ok := len(cols) != 0
+		for _, c := range cols {
+			ok = ok && own[c]
+		}
+		if ok {
+			pushed = append(pushed, e)
+		} else {
+			rest = append(rest, e)
+		}
+	}
+	return pushed, rest
+}
+
+// access plans how the source Table is read when its rows are the left
+// side of a Join that doesn't need them in any particular order
+func (s *joinSource) access() (Plan, error) {
+	local := make([]*expr.Expr, len(s.pushed))
+	orig := map[*expr.Expr]*expr.Expr{}
+	for i, e := range s.pushed {
+		local[i] = unqualify(s.table, e)
+		orig[local[i]] = e
+	}
+	res, residual := planAccess(s.table, local)
+	qualifyColumns(res, s.table.Name())
+	filters := make([]*expr.Expr, len(residual))
+	for i, e := range residual {
+		filters[i] = orig[e]
+	}
+	return filterAll(res, filters)
+}
+
+// ordered reads the source Table through an Index, so that its rows are
+// produced in the order of that Index's Columns
+func (s *joinSource) ordered(def index.Definition) (Plan, error) {
+	var res Plan = newIndexScan(s.table, &access{def: def})
+	qualifyColumns(res, s.table.Name())
+	return filterAll(res, s.pushed)
+}
+
+func planJoin(
+	left Plan, src *joinSource, right table.Table, j Join,
+) (Plan, error) {
+	base, err := makeJoinBase(left, right, j)
+	if err != nil {
+		return nil, err
+	}
+
+	rightCols := base.rightColumns()
+	rightIdx, perm, hasRight := joinIndex(right, rightCols)
+
+	var leftIdx index.Definition
+	canMerge := false
+	if src != nil && hasRight && ascending(rightIdx, len(rightCols)) {
+		leftCols := base.permuted(perm).leftColumns(src.table.Name())
+		leftIdx, canMerge = exactJoinIndex(src.table, leftCols)
+		canMerge = canMerge && ascending(leftIdx, len(leftCols))
+	}
+
+	strategy := j.Strategy
+	if strategy == "" {
+		switch {
+		case canMerge && len(src.pushed) == 0:
+			strategy = Merge
+		case hasRight:
+			strategy = NestedLoop
+		default:
+			strategy = Hash
+		}
+	}
+
+	switch strategy {
+	case NestedLoop:
+		if !hasRight {
+			return nil, fmt.Errorf(ErrJoinStrategy, strategy)
+		}
+		return &nestedLoopJoin{
+			joinBase: *base.permuted(perm),
+			index:    rightIdx.Name,
+		}, nil
+	case Merge:
+		if !canMerge {
+			return nil, fmt.Errorf(ErrJoinStrategy, strategy)
+		}
+		ordered, err := src.ordered(leftIdx)
+		if err != nil {
+			return nil, err
+		}
+		res := base.permuted(perm)
+		if err := res.setLeft(ordered); err != nil {
+			return nil, err
+		}
+		r := newIndexScan(right, &access{def: rightIdx})
+		qualifyColumns(r, right.Name())
+		return &mergeJoin{joinBase: *res, right: r}, nil
+	default:
+		r := newScan(right)
+		qualifyColumns(r, right.Name())
+		return &hashJoin{joinBase: *base, right: r}, nil
+	}
+}
+
+func makeJoinBase(left Plan, right table.Table, j Join) (*joinBase, error) {
+	res := &joinBase{
+		typ:   j.Type,
+		table: right.Name(),
+		names: columnNames(right.Columns()),
+		rows:  right.Count(),
+		on:    make([]JoinOn, len(j.On)),
+	}
+	for i, on := range j.On {
+		l, err := resolveColumn(left.Columns(), on.Left)
+		if err != nil {
+			return nil, err
+		}
+		res.on[i] = JoinOn{Left: l, Right: on.Right}
+	}
+	rightOff, err := relation.MakeOffsets(right.Columns(), res.rightColumns()...)
+	if err != nil {
+		return nil, err
+	}
+	res.rightOff = rightOff
+	if err := res.setLeft(left); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (j *joinBase) setLeft(left Plan) error {
+	off, err := relation.MakeOffsets(
+		makeColumns(left.Columns()), j.leftColumnNames()...,
+	)
+	if err != nil {
+		return err
+	}
+	j.left = left
+	j.leftOff = off
+	j.columns = left.Columns()
+	if j.typ == Inner || j.typ == LeftOuter {
+		j.columns = append(j.columns[:len(j.columns):len(j.columns)],
+			qualifyNames(j.table, j.names)...,
+		)
+	}
+	return nil
+}
+
+// permuted returns a copy of the joinBase with its conditions reordered
+// to follow the Columns of an Index
+func (j *joinBase) permuted(perm []int) *joinBase {
+	res := *j
+	res.on = make([]JoinOn, len(perm))
+	res.leftOff = make(column.Offsets, len(perm))
+	res.rightOff = make(column.Offsets, len(perm))
+	for i, p := range perm {
+		res.on[i] = j.on[p]
+		res.leftOff[i] = j.leftOff[p]
+		res.rightOff[i] = j.rightOff[p]
+	}
+	return &res
+}
+
+func (j *joinBase) leftColumnNames() column.Names {
+	res := make(column.Names, len(j.on))
+	for i, on := range j.on {
+		res[i] = on.Left
+	}
+	return res
+}
+
+// leftColumns returns the unqualified names of the left Columns, assuming
+// that they belong to the named Table
+func (j *joinBase) leftColumns(n table.Name) column.Names {
+	res := make(column.Names, len(j.on))
+	pfx := string(n) + "."
+	for i, on := range j.on {
+		res[i] = column.Name(strings.TrimPrefix(string(on.Left), pfx))
+	}
+	return res
+}
+
+func (j *joinBase) rightColumns() column.Names {
+	res := make(column.Names, len(j.on))
+	for i, on := range j.on {
+		res[i] = on.Right
+	}
+	return res
+}
+
+func (j *joinBase) Columns() column.Names {
+	return j.columns
+}
+
+func (j *joinBase) describe(
+	st statistics, p Plan, op Operation, via string, in ...*Explanation,
+) *Explanation {
+	conds := make([]string, len(j.on))
+	for i, on := range j.on {
+		conds[i] = fmt.Sprintf("%s = %s", on.Left, qualify(j.table, on.Right))
+	}
+	detail := fmt.Sprintf("%s %s: %s",
+		j.typ, j.table, strings.Join(conds, " AND "),
+	)
+	if via != "" {
+		detail += " via " + via
+	}
+	return st.describe(p, op, detail, j.estimate(in[0].Estimated), in...)
+}
+
+func (j *joinBase) estimate(left int) float64 {
+	matched := float64(left) * float64(j.rows) * equalSelectivity
+	switch j.typ {
+	case Semi, Anti:
+		return float64(left) * defaultSelectivity
+	case LeftOuter:
+		if matched < float64(left) {
+			return float64(left)
+		}
+	}
+	return matched
+}
+
+// combine produces the results of joining a left row with the right rows
+// that match it
+func (j *joinBase) combine(l item, matches []item) []item {
+	switch j.typ {
+	case Semi:
+		if len(matches) != 0 {
+			return []item{l}
+		}
+		return nil
+	case Anti:
+		if len(matches) == 0 {
+			return []item{l}
+		}
+		return nil
+	case LeftOuter:
+		if len(matches) == 0 {
+			nulls := make(relation.Relation, len(j.names))
+			return []item{joinItems(l, item{rel: nulls})}
+		}
+	}
+	res := make([]item, len(matches))
+	for i, r := range matches {
+		res[i] = joinItems(l, r)
+	}
+	return res
+}
+
+// matches returns whether the join Columns of two rows hold equal Values
+func (j *joinBase) matches(l, r relation.Relation) bool {
+	for i, lo := range j.leftOff {
+		lv, rv := l[lo], r[j.rightOff[i]]
+		if lv == nil || rv == nil || lv.Compare(rv) != value.EqualTo {
+			return false
+		}
+	}
+	return true
+}
+
+func (j *nestedLoopJoin) String() string {
+	return Explain(j).chain()
+}
+
+func (j *nestedLoopJoin) explain(st statistics) *Explanation {
+	via := fmt.Sprintf("%s.%s", j.table, j.index)
+	return j.describe(st, j, NestedLoopJoinOp, via, j.left.explain(st))
+}
+
+func (j *nestedLoopJoin) open(e *execution) (transaction.Iterator, error) {
+	right, err := e.table(j.table)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := right.Index(j.index)
+	if err != nil {
+		return nil, err
+	}
+	left, err := e.open(j.left)
+	if err != nil {
+		return nil, err
+	}
+	return flatMapIterator(left, func(k value.Key, v any) []item {
+		l := item{key: k, rel: v.(relation.Relation)}
+		vals, ok := selectValues(l.rel, j.leftOff)
+		if !ok {
+			return j.combine(l, nil)
+		}
+		var matches []item
+		for _, pk, next, ok := idx.EQ(vals)(); ok; _, pk, next, ok = next() {
+			rk := pk.(value.Key)
+			row, ok := right.Select(rk)
+			if ok && j.matches(l.rel, relation.Relation(row)) {
+				matches = append(matches, item{
+					key: rk,
+					rel: relation.Relation(row),
+				})
+			}
+		}
+		return j.combine(l, matches)
+	}), nil
+}
+
+func (j *hashJoin) String() string {
+	return Explain(j).chain()
+}
+
+func (j *hashJoin) explain(st statistics) *Explanation {
+	return j.describe(st, j, HashJoinOp, "",
+		j.left.explain(st), j.right.explain(st),
+	)
+}
+
+func (j *hashJoin) open(e *execution) (transaction.Iterator, error) {
+	right, err := e.open(j.right)
+	if err != nil {
+		return nil, err
+	}
+	buckets := map[string][]item{}
+	for _, r := range collect(right) {
+		if k, ok := joinKey(r.rel, j.rightOff); ok {
+			buckets[string(k)] = append(buckets[string(k)], r)
+		}
+	}
+
+	left, err := e.open(j.left)
+	if err != nil {
+		return nil, err
+	}
+	return flatMapIterator(left, func(k value.Key, v any) []item {
+		l := item{key: k, rel: v.(relation.Relation)}
+		key, ok := joinKey(l.rel, j.leftOff)
+		if !ok {
+			return j.combine(l, nil)
+		}
+		var matches []item
+		for _, r := range buckets[string(key)] {
+			if j.matches(l.rel, r.rel) {
+				matches = append(matches, r)
+			}
+		}
+		return j.combine(l, matches)
+	}), nil
+}
+
+func (j *mergeJoin) String() string {
+	return Explain(j).chain()
+}
+
+func (j *mergeJoin) explain(st statistics) *Explanation {
+	return j.describe(st, j, MergeJoinOp, "",
+		j.left.explain(st), j.right.explain(st),
+	)
+}
+
+// open walks both inputs in the order of their Indexes. Each left row is
+// compared to the group of right rows that share its encoded key, which
+// is how the Indexes order them
+func (j *mergeJoin) open(e *execution) (transaction.Iterator, error) {
+	left, err := e.open(j.left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.open(j.right)
+	if err != nil {
+		return nil, err
+	}
+	rights := collect(right)
+
+	var res []item
+	var pos int
+	for _, l := range collect(left) {
+		lk, ok := joinKey(l.rel, j.leftOff)
+		if !ok {
+			res = append(res, j.combine(l, nil)...)
+			continue
+		}
+		for pos < len(rights) && j.before(rights[pos], lk) {
+			pos++
+		}
+		var matches []item
+		for _, r := range rights[pos:] {
+			rk, _ := joinKey(r.rel, j.rightOff)
+			if rk.Compare(lk) != value.EqualTo {
+				break
+			}
+			if j.matches(l.rel, r.rel) {
+				matches = append(matches, r)
+			}
+		}
+		res = append(res, j.combine(l, matches)...)
+	}
+	return sliceIterator(res), nil
+}
+
+func (j *mergeJoin) before(r item, k value.Key) bool {
+	rk, ok := joinKey(r.rel, j.rightOff)
+	return !ok || rk.Compare(k) == value.LessThan
+}
+
+// joinIndex finds an ordered Index of the Table whose leading Columns are
+// the provided Columns, in any order. The returned permutation orders the
+// Columns as the Index does
+func joinIndex(
+	tbl table.Table, cols column.Names,
+) (index.Definition, []int, bool) {
+	for _, n := range tbl.Indexes() {
+		def, ok := tbl.IndexInfo(n)
+		if !ok || !completeIndex(def) || len(def.Columns) < len(cols) {
+			continue
+		}
+		if perm, ok := permutation(cols, def.Columns[:len(cols)]); ok {
+			return def, perm, true
+		}
+	}
+	return index.Definition{}, nil, false
+}
+
+// exactJoinIndex finds an ordered Index of the Table whose leading Columns
+// are the provided Columns, in the same order
+func exactJoinIndex(
+	tbl table.Table, cols column.Names,
+) (index.Definition, bool) {
+	for _, n := range tbl.Indexes() {
+		def, ok := tbl.IndexInfo(n)
+		if !ok || !completeIndex(def) || len(def.Columns) < len(cols) {
+			continue
+		}
+		if equalNames(cols, def.Columns[:len(cols)]) {
+			return def, true
+		}
+	}
+	return index.Definition{}, false
+}
+
+// ascending returns whether the leading Columns of an Index are all
+// ordered from lowest to highest, as a merge of two Indexes requires
+func ascending(def index.Definition, n int) bool {
+	for _, c := range def.Columns[:n] {
+		if def.IsDescending(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func permutation(cols, target column.Names) ([]int, bool) {
+	res := make([]int, len(target))
+	used := make([]bool, len(cols))
+	for i, t := range target {
+		found := false
+		for j, c := range cols {
+			if c == t && !used[j] {
+				res[i], used[j], found = j, true, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return res, true
+}
+
+func equalNames(l, r column.Names) bool {
+	if len(l) != len(r) {
+		return false
+	}
+	for i, n := range l {
+		if r[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveColumn finds a Column by its exact name or, if it's unambiguous,
+// by its name without a Table qualifier
+func resolveColumn(names column.Names, n column.Name) (column.Name, error) {
+	var res column.Name
+	found := 0
+	for _, c := range names {
+		switch {
+		case c == n:
+			return c, nil
+		case strings.HasSuffix(string(c), "."+string(n)):
+			res = c
+			found++
+		}
+	}
+	switch found {
+	case 0:
+		return "", fmt.Errorf(relation.ErrColumnNotFound, n)
+	case 1:
+		return res, nil
+	default:
+		return "", fmt.Errorf(ErrAmbiguousColumn, n)
+	}
+}
+
+func qualify(t table.Name, c column.Name) column.Name {
+	return column.Name(fmt.Sprintf("%s.%s", t, c))
+}
+
+func qualifyNames(t table.Name, names column.Names) column.Names {
+	res := make(column.Names, len(names))
+	for i, n := range names {
+		res[i] = qualify(t, n)
+	}
+	return res
+}
+
+// qualifyColumns names the Columns produced by a Table access Plan after
+// the Table they were read from
+func qualifyColumns(p Plan, t table.Name) {
+	switch p := p.(type) {
+	case *scan:
+		p.columns = qualifyNames(t, p.columns)
+	case *indexScan:
+		p.columns = qualifyNames(t, p.columns)
+	}
+}
+
+// unqualify rewrites an Expr over a Table's qualified Columns so that it
+// refers to them by their own names
+func unqualify(tbl table.Table, e *expr.Expr) *expr.Expr {
+	for _, c := range tbl.Columns() {
+		e = e.RenameColumn(qualify(tbl.Name(), c.Name()), c.Name())
+	}
+	return e
+}
+
+func filterAll(p Plan, filters []*expr.Expr) (Plan, error) {
+	switch len(filters) {
+	case 0:
+		return p, nil
+	case 1:
+		return newFilter(p, filters[0])
+	default:
+		return newFilter(p, expr.And(filters...))
+	}
+}
+
+func selectValues(
+	r relation.Relation, off column.Offsets,
+) (relation.Relation, bool) {
+	res := make(relation.Relation, len(off))
+	for i, o := range off {
+		if r[o] == nil {
+			return nil, false
+		}
+		res[i] = r[o]
+	}
+	return res, true
+}
+
+// joinKey encodes the join Columns of a row in the same way that an
+// Index would. Rows with a null join Column have no key
+func joinKey(r relation.Relation, off column.Offsets) (value.Key, bool) {
+	vals, ok := selectValues(r, off)
+	if !ok {
+		return nil, false
+	}
+	return index.KeyForValues(vals...), true
+}
+
+func joinItems(l, r item) item {
+	rel := make(relation.Relation, 0, len(l.rel)+len(r.rel))
+	rel = append(append(rel, l.rel...), r.rel...)
+	key := l.key
+	if r.key != nil {
+		key = l.key.WithKeys(r.key)
+	}
+	return item{key: key, rel: rel}
+}
@@ -0,0 +1,260 @@
+package query_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/query"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func runJoin(q *query.Query) (query.Plan, *query.Result, error) {
+	var plan query.Plan
+	var res *query.Result
+	d, err := db.NewDatabase()(func(d database.Database) error {
+		users, err := d.CreateTable("users",
+			column.Make("id"), column.Make("name"),
+		)
+		if err != nil {
+			return err
+		}
+		if err := users.CreateIndex(db.UniqueIndex, "by-id", "id"); err != nil {
+			return err
+		}
+		orders, err := d.CreateTable("orders",
+			column.Make("user_id"), column.Make("total"),
+		)
+		if err != nil {
+			return err
+		}
+		err = orders.CreateIndex(db.StandardIndex, "by-user", "user_id")
+		if err != nil {
+			return err
+		}
+		if _, err := d.CreateTable("notes",
+			column.Make("user_id"), column.Make("text"),
+		); err != nil {
+			return err
+		}
+
+		for _, u := range []relation.Row{
+			{value.Integer(1), value.String("Bill")},
+			{value.Integer(2), value.String("Ted")},
+			{value.Integer(3), value.String("Rufus")},
+			{nil, value.String("Nobody")},
+		} {
+			if _, err := users.InsertAuto(u); err != nil {
+				return err
+			}
+		}
+		for _, o := range []relation.Row{
+			{value.Integer(1), value.Integer(10)},
+			{value.Integer(1), value.Integer(20)},
+			{value.Integer(2), value.Integer(5)},
+			{nil, value.Integer(99)},
+		} {
+			if _, err := orders.InsertAuto(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	_, err = d(func(d database.Database) error {
+		var err error
+		if plan, err = q.Plan(d); err != nil {
+			return err
+		}
+		res, err = query.Execute(d, plan)
+		return err
+	})
+	return plan, res, err
+}
+
+func TestInnerJoinStrategies(t *testing.T) {
+	as := assert.New(t)
+
+	base := query.From("users").
+		OrderBy("users.name").
+		OrderBy("orders.total").
+		Select("users.name", "orders.total")
+	expected := []relation.Relation{
+		{value.String("Bill"), value.Integer(10)},
+		{value.String("Bill"), value.Integer(20)},
+		{value.String("Ted"), value.Integer(5)},
+	}
+
+	plan, res, err := runJoin(base.Join("orders", query.On("id", "user_id")))
+	as.Nil(err)
+	as.Equal(expected, res.Rows)
+	as.Equal(column.Names{"users.name", "orders.total"}, res.Columns)
+	as.Contains(plan.String(),
+		"MergeJoin(inner orders: users.id = orders.user_id) <- "+
+			"[IndexScan(users.by-id), IndexScan(orders.by-user)]",
+	)
+
+	plan, res, err = runJoin(base.
+		Join("orders", query.On("id", "user_id")).
+		Where("users.name", query.NEQ, value.String("Rufus")),
+	)
+	as.Nil(err)
+	as.Equal(expected, res.Rows)
+	as.Contains(plan.String(),
+		"NestedLoopJoin(inner orders: users.id = orders.user_id "+
+			"via orders.by-user) <- Filter(users.name <> \"Rufus\") <- "+
+			"Scan(users)",
+	)
+
+	plan, res, err = runJoin(base.JoinWith(query.Join{
+		Type:     query.Inner,
+		Table:    "orders",
+		On:       []query.JoinOn{query.On("users.id", "user_id")},
+		Strategy: query.Hash,
+	}))
+	as.Nil(err)
+	as.Equal(expected, res.Rows)
+	as.Contains(plan.String(),
+		"HashJoin(inner orders: users.id = orders.user_id) <- "+
+			"[Scan(users), Scan(orders)]",
+	)
+}
+
+func TestOuterJoins(t *testing.T) {
+	as := assert.New(t)
+
+	for _, s := range []query.JoinStrategy{
+		query.NestedLoop, query.Hash, query.Merge,
+	} {
+		join := func(typ query.JoinType) *query.Query {
+			return query.From("users").
+				JoinWith(query.Join{
+					Type:     typ,
+					Table:    "orders",
+					On:       []query.JoinOn{query.On("id", "user_id")},
+					Strategy: s,
+				}).
+				OrderBy("users.name")
+		}
+
+		_, res, err := runJoin(join(query.LeftOuter).
+			Select("users.name", "orders.total").
+			OrderBy("orders.total"),
+		)
+		as.Nil(err)
+		as.Equal([]relation.Relation{
+			{value.String("Bill"), value.Integer(10)},
+			{value.String("Bill"), value.Integer(20)},
+			{value.String("Nobody"), nil},
+			{value.String("Rufus"), nil},
+			{value.String("Ted"), value.Integer(5)},
+		}, res.Rows, s)
+
+		_, res, err = runJoin(join(query.Semi))
+		as.Nil(err)
+		as.Equal(column.Names{"users.id", "users.name"}, res.Columns)
+		as.Equal([]relation.Relation{
+			{value.Integer(1), value.String("Bill")},
+			{value.Integer(2), value.String("Ted")},
+		}, res.Rows, s)
+
+		_, res, err = runJoin(join(query.Anti).Select("users.name"))
+		as.Nil(err)
+		as.Equal([]relation.Relation{
+			{value.String("Nobody")}, {value.String("Rufus")},
+		}, res.Rows, s)
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	as := assert.New(t)
+
+	_, _, err := runJoin(query.From("users").Join("missing", query.On("id", "id")))
+	as.EqualError(err, fmt.Sprintf(query.ErrTableNotFound, "missing"))
+
+	_, _, err = runJoin(query.From("users").Join("orders"))
+	as.EqualError(err, fmt.Sprintf(query.ErrNoJoinConditions, "orders"))
+
+	_, _, err = runJoin(query.From("users").JoinWith(query.Join{
+		Type: "sideways", Table: "orders",
+	}))
+	as.EqualError(err, fmt.Sprintf(query.ErrUnknownJoinType, "sideways"))
+
+	_, _, err = runJoin(query.From("users").JoinWith(query.Join{
+		Type:     query.Inner,
+		Table:    "notes",
+		On:       []query.JoinOn{query.On("id", "user_id")},
+		Strategy: query.NestedLoop,
+	}))
+	as.EqualError(err, fmt.Sprintf(query.ErrJoinStrategy, query.NestedLoop))
+
+	_, _, err = runJoin(query.From("users").
+		Join("orders", query.On("id", "user_id")).
+		LeftJoin("notes", query.On("id", "user_id")).
+		SemiJoin("users", query.On("user_id", "id")),
+	)
+	as.EqualError(err, fmt.Sprintf(query.ErrAmbiguousColumn, "user_id"))
+
+	_, _, err = runJoin(query.From("users").
+		Join("orders", query.On("missing", "user_id")),
+	)
+	as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+}
+
+func TestJoinDescendingIndexes(t *testing.T) {
+	as := assert.New(t)
+
+	d, err := db.NewDatabase()(func(d database.Database) error {
+		a, err := d.CreateTable("a", column.Make("id"))
+		as.Nil(err)
+		as.Nil(a.DefineIndex(index.Definition{
+			Name:       "a-id",
+			Type:       db.UniqueIndex,
+			Columns:    column.Names{"id"},
+			Descending: column.Names{"id"},
+		}))
+		b, err := d.CreateTable("b", column.Make("aid"))
+		as.Nil(err)
+		as.Nil(b.DefineIndex(index.Definition{
+			Name:       "b-aid",
+			Type:       db.StandardIndex,
+			Columns:    column.Names{"aid"},
+			Descending: column.Names{"aid"},
+		}))
+		for i := int64(1); i <= 3; i++ {
+			_, err := a.InsertAuto(relation.Row{value.Integer(i)})
+			as.Nil(err)
+			_, err = b.InsertAuto(relation.Row{value.Integer(i)})
+			as.Nil(err)
+		}
+		return nil
+	})
+	as.Nil(err)
+
+	_, err = d(func(d database.Database) error {
+		q := query.From("a").Join("b", query.On("id", "aid"))
+		plan, err := q.Plan(d)
+		as.Nil(err)
+		as.NotContains(plan.String(), "MergeJoin")
+		res, err := query.Execute(d, plan)
+		as.Nil(err)
+		as.Len(res.Rows, 3)
+
+		_, err = query.From("a").JoinWith(query.Join{
+			Type:     query.Inner,
+			Table:    "b",
+			On:       []query.JoinOn{query.On("a.id", "aid")},
+			Strategy: query.Merge,
+		}).Plan(d)
+		as.EqualError(err, fmt.Sprintf(query.ErrJoinStrategy, query.Merge))
+		return nil
+	})
+	as.Nil(err)
+}
@@ -0,0 +1,370 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Plan is an executable step of a Query. Each Plan produces an
+	// Iterator of Relations, keyed by the Key of the row they came from
+	Plan interface {
+		// Columns returns the Names of the Columns of produced Relations
+		Columns() column.Names
+
+		// String describes the Plan, including the Plans it reads from
+		String() string
+
+		explain(statistics) *Explanation
+		open(*execution) (transaction.Iterator, error)
+	}
+
+	// Result is the materialized output of an executed Plan. Each of its
+	// Rows is accompanied by the Key that it was produced under
+	Result struct {
+		Columns column.Names
+		Rows    []relation.Relation
+		Keys    []value.Key
+	}
+
+	// execution carries the state of a single execution of a Plan. Errors
+	// that occur while iterating are recorded here, ending the iteration
+	execution struct {
+		db    database.Database
+		stats statistics
+		err   error
+	}
+
+	scan struct {
+		table   table.Name
+		columns column.Names
+		rows    int
+	}
+
+	filter struct {
+		input Plan
+		expr  *expr.Expr
+		pred  expr.Predicate
+	}
+
+	sorter struct {
+		input  Plan
+		orders Orders
+		offset column.Offsets
+	}
+
+	limiter struct {
+		input  Plan
+		offset int
+		limit  int
+	}
+
+	projection struct {
+		input    Plan
+		columns  column.Names
+		selector relation.Selector
+	}
+)
+
+// Execute runs a Plan against the Database and gathers its Result
+func Execute(d database.Database, p Plan) (*Result, error) {
+	e := &execution{db: d}
+	iter, err := e.open(p)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Columns: p.Columns()}
+	_ = iterate.ForEach(iter, func(k value.Key, v any) error {
+		res.Rows = append(res.Rows, v.(relation.Relation))
+		res.Keys = append(res.Keys, k)
+		return nil
+	})
+	if e.err != nil {
+		return nil, e.err
+	}
+	return res, nil
+}
+
+// open opens a Plan as part of the execution. If the execution is being
+// analyzed, the Plan's Iterator is instrumented
+func (e *execution) open(p Plan) (transaction.Iterator, error) {
+	start := time.Now()
+	iter, err := p.open(e)
+	if err != nil || e.stats == nil {
+		return iter, err
+	}
+	return e.stats.instrument(p, iter, time.Since(start)), nil
+}
+
+func (e *execution) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *execution) table(n table.Name) (table.Table, error) {
+	if tbl, ok := e.db.Table(n); ok {
+		return tbl, nil
+	}
+	return nil, fmt.Errorf(ErrTableNotFound, n)
+}
+
+func newScan(tbl table.Table) *scan {
+	return &scan{
+		table:   tbl.Name(),
+		columns: columnNames(tbl.Columns()),
+		rows:    tbl.Count(),
+	}
+}
+
+func (s *scan) Columns() column.Names {
+	return s.columns
+}
+
+func (s *scan) String() string {
+	return Explain(s).chain()
+}
+
+func (s *scan) explain(st statistics) *Explanation {
+	return st.describe(s, ScanOp, string(s.table), float64(s.rows))
+}
+
+func (s *scan) open(e *execution) (transaction.Iterator, error) {
+	tbl, err := e.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	return iterate.Map(tbl.Rows(), func(k value.Key, v any) any {
+		return relation.Relation(v.(relation.Row))
+	}), nil
+}
+
+func newFilter(input Plan, e *expr.Expr) (*filter, error) {
+	pred, err := expr.CompilePredicate(e, makeColumns(input.Columns()))
+	if err != nil {
+		return nil, err
+	}
+	return &filter{
+		input: input,
+		expr:  e,
+		pred:  pred,
+	}, nil
+}
+
+func (f *filter) Columns() column.Names {
+	return f.input.Columns()
+}
+
+func (f *filter) String() string {
+	return Explain(f).chain()
+}
+
+func (f *filter) explain(st statistics) *Explanation {
+	in := f.input.explain(st)
+	est := float64(in.Estimated) * selectivity(f.expr)
+	return st.describe(f, FilterOp, f.expr.String(), est, in)
+}
+
+func (f *filter) open(e *execution) (transaction.Iterator, error) {
+	iter, err := e.open(f.input)
+	if err != nil {
+		return nil, err
+	}
+	iter = iterate.While(iter, func(value.Key, any) bool {
+		return e.err == nil
+	})
+	return iterate.Filter(iter, func(_ value.Key, v any) bool {
+		match, err := f.pred(relation.Row(v.(relation.Relation)))
+		if err != nil {
+			e.fail(err)
+		}
+		return match
+	}), nil
+}
+
+func newSorter(input Plan, orders ...Order) (*sorter, error) {
+	names := make(column.Names, len(orders))
+	for i, o := range orders {
+		names[i] = o.Column
+	}
+	off, err := relation.MakeOffsets(makeColumns(input.Columns()), names...)
+	if err != nil {
+		return nil, err
+	}
+	return &sorter{
+		input:  input,
+		orders: orders,
+		offset: off,
+	}, nil
+}
+
+func (s *sorter) Columns() column.Names {
+	return s.input.Columns()
+}
+
+func (s *sorter) String() string {
+	return Explain(s).chain()
+}
+
+func (s *sorter) explain(st statistics) *Explanation {
+	orders := make([]string, len(s.orders))
+	for i, o := range s.orders {
+		orders[i] = string(o.Column)
+		if o.Descending {
+			orders[i] += " DESC"
+		}
+	}
+	in := s.input.explain(st)
+	detail := strings.Join(orders, ", ")
+	return st.describe(s, SortOp, detail, float64(in.Estimated), in)
+}
+
+func (s *sorter) open(e *execution) (transaction.Iterator, error) {
+	iter, err := e.open(s.input)
+	if err != nil {
+		return nil, err
+	}
+	items := collect(iter)
+	sort.SliceStable(items, func(l, r int) bool {
+		return s.less(items[l].rel, items[r].rel)
+	})
+	return sliceIterator(items), nil
+}
+
+func (s *sorter) less(l, r relation.Relation) bool {
+	for i, o := range s.offset {
+		c := compareValues(l[o], r[o])
+		if s.orders[i].Descending {
+			c = -c
+		}
+		if c != value.EqualTo {
+			return c == value.LessThan
+		}
+	}
+	return false
+}
+
+func newLimiter(input Plan, offset, limit int) *limiter {
+	return &limiter{
+		input:  input,
+		offset: offset,
+		limit:  limit,
+	}
+}
+
+func (l *limiter) Columns() column.Names {
+	return l.input.Columns()
+}
+
+func (l *limiter) String() string {
+	return Explain(l).chain()
+}
+
+func (l *limiter) explain(st statistics) *Explanation {
+	in := l.input.explain(st)
+	est := in.Estimated - l.offset
+	if est < 0 {
+		est = 0
+	}
+	if l.limit >= 0 && est > l.limit {
+		est = l.limit
+	}
+	detail := fmt.Sprintf("%d, %d", l.offset, l.limit)
+	return st.describe(l, LimitOp, detail, float64(est), in)
+}
+
+func (l *limiter) open(e *execution) (transaction.Iterator, error) {
+	iter, err := e.open(l.input)
+	if err != nil {
+		return nil, err
+	}
+	iter = iterate.Skip(iter, l.offset)
+	if l.limit >= 0 {
+		iter = iterate.Take(iter, l.limit)
+	}
+	return iter, nil
+}
+
+func newProjection(input Plan, cols ...column.Name) (*projection, error) {
+	sel, err := relation.MakeNamedSelector(makeColumns(input.Columns()), cols...)
+	if err != nil {
+		return nil, err
+	}
+	return &projection{
+		input:    input,
+		columns:  cols,
+		selector: sel,
+	}, nil
+}
+
+func (p *projection) Columns() column.Names {
+	return p.columns
+}
+
+func (p *projection) String() string {
+	return Explain(p).chain()
+}
+
+func (p *projection) explain(st statistics) *Explanation {
+	cols := make([]string, len(p.columns))
+	for i, c := range p.columns {
+		cols[i] = string(c)
+	}
+	in := p.input.explain(st)
+	detail := strings.Join(cols, ", ")
+	return st.describe(p, ProjectOp, detail, float64(in.Estimated), in)
+}
+
+func (p *projection) open(e *execution) (transaction.Iterator, error) {
+	iter, err := e.open(p.input)
+	if err != nil {
+		return nil, err
+	}
+	return iterate.Map(iter, func(_ value.Key, v any) any {
+		return p.selector(relation.Row(v.(relation.Relation)))
+	}), nil
+}
+
+// compareValues orders nil before any other Value, and treats Values that
+// are incomparable as equal
+func compareValues(l, r value.Value) value.Comparison {
+	switch {
+	case l == nil && r == nil:
+		return value.EqualTo
+	case l == nil:
+		return value.LessThan
+	case r == nil:
+		return value.GreaterThan
+	}
+	if c := l.Compare(r); c != value.Incomparable {
+		return c
+	}
+	return value.EqualTo
+}
+
+func columnNames(cols column.Columns) column.Names {
+	res := make(column.Names, len(cols))
+	for i, c := range cols {
+		res[i] = c.Name()
+	}
+	return res
+}
+
+func makeColumns(names column.Names) column.Columns {
+	res := make(column.Columns, len(names))
+	for i, n := range names {
+		res[i] = column.Make(n)
+	}
+	return res
+}
@@ -0,0 +1,675 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// comparison is a conjunct of a Query's predicate that compares a
+	// Column, or a function of Columns, to a constant, making it usable by
+	// an Index. The target is the compared Expr in its String form
+	comparison struct {
+		target string
+		op     expr.Op
+		value  value.Value
+		expr   *expr.Expr
+	}
+
+	// bound limits one end of an Index range
+	bound struct {
+		value     value.Value
+		inclusive bool
+	}
+
+	// access is a candidate path into a Table through one of its Indexes
+	access struct {
+		def    index.Definition
+		eq     []*comparison
+		lower  *comparison
+		upper  *comparison
+		unique bool
+	}
+
+	indexScan struct {
+		table    table.Name
+		index    index.Name
+		columns  column.Names
+		selector relation.Selector
+		prefix   relation.Relation
+		lower    *bound
+		upper    *bound
+		conds    []*expr.Expr
+		used     int
+		exact    bool
+		desc     bool
+		reverse  bool
+		covering bool
+		rows     int
+	}
+)
+
+// orderedIndexes are the Index Types whose entries are sorted by the
+// encoded values of their Columns, and so can answer range predicates
+var orderedIndexes = map[index.TypeName]bool{
+	db.UniqueIndex:   true,
+	db.StandardIndex: true,
+}
+
+// satisfiedBy returns whether the comparison of a Value to another
+// satisfies each comparison Op
+var satisfiedBy = map[expr.Op]func(value.Comparison) bool{
+	expr.EQOp:  func(c value.Comparison) bool { return c == value.EqualTo },
+	expr.LTOp:  func(c value.Comparison) bool { return c == value.LessThan },
+	expr.LTEOp: func(c value.Comparison) bool { return c != value.GreaterThan },
+	expr.GTOp:  func(c value.Comparison) bool { return c == value.GreaterThan },
+	expr.GTEOp: func(c value.Comparison) bool { return c != value.LessThan },
+}
+
+var flippedOps = map[expr.Op]expr.Op{
+	expr.EQOp:  expr.EQOp,
+	expr.LTOp:  expr.GTOp,
+	expr.LTEOp: expr.GTEOp,
+	expr.GTOp:  expr.LTOp,
+	expr.GTEOp: expr.LTEOp,
+}
+
+// planAccess chooses how rows are read from the Table. If one of the
+// Table's Indexes can satisfy some of the conjuncts, an index scan is
+// returned along with the conjuncts that it can't satisfy. Otherwise, the
+// Table is scanned and all conjuncts remain to be filtered
+func planAccess(tbl table.Table, where []*expr.Expr) (Plan, []*expr.Expr) {
+	conj := conjuncts(where)
+	cmps := indexComparisons(conj)
+
+	var best *access
+	for _, n := range tbl.Indexes() {
+		def, ok := tbl.IndexInfo(n)
+		if !ok || !orderedIndexes[def.Type] || !implies(conj, def.Where) {
+			continue
+		}
+		if a := makeAccess(def, cmps); a != nil && a.betterThan(best) {
+			best = a
+		}
+	}
+	if best == nil {
+		return newScan(tbl), conj
+	}
+
+	used := map[*expr.Expr]bool{}
+	for _, c := range best.used() {
+		used[c.expr] = true
+	}
+	var residual []*expr.Expr
+	for _, e := range conj {
+		if !used[e] {
+			residual = append(residual, e)
+		}
+	}
+	return newIndexScan(tbl, best), residual
+}
+
+// conjuncts flattens nested AND Exprs into a list of Exprs that must all
+// be true
+func conjuncts(where []*expr.Expr) []*expr.Expr {
+	var res []*expr.Expr
+	for _, e := range where {
+		if e.Op == expr.AndOp {
+			res = append(res, conjuncts(e.Args)...)
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+func indexComparisons(conj []*expr.Expr) []*comparison {
+	var res []*comparison
+	for _, e := range conj {
+		if c, ok := makeComparison(e); ok {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+func makeComparison(e *expr.Expr) (*comparison, bool) {
+	op, ok := flippedOps[e.Op]
+	if !ok || len(e.Args) != 2 {
+		return nil, false
+	}
+	l, r := e.Args[0], e.Args[1]
+	switch {
+	case isTarget(l) && r.Op == expr.LiteralOp:
+		op = e.Op
+	case l.Op == expr.LiteralOp && isTarget(r):
+		l, r = r, l
+	default:
+		return nil, false
+	}
+	if r.Value == nil || r.Value.Value == nil {
+		return nil, false
+	}
+	return &comparison{
+		target: l.String(),
+		op:     op,
+		value:  r.Value.Value,
+		expr:   e,
+	}, true
+}
+
+// isTarget returns whether an Expr can be matched to an Index Column,
+// either as a Table Column or as the Expr of a computed Column
+func isTarget(e *expr.Expr) bool {
+	return e.Op == expr.ColumnOp || e.Op == expr.CallOp
+}
+
+// completeIndex returns whether an Index is ordered, has an entry for
+// every row of its Table, and is keyed by the Table's own Columns, so that
+// reading it can stand in for a Table scan
+func completeIndex(def index.Definition) bool {
+	return orderedIndexes[def.Type] && !def.IsPartial() && !def.IsComputed()
+}
+
+// implies returns whether the conjuncts of a Query guarantee that a row
+// satisfies the predicate of a partial Index, in which case the Index
+// holds every row that the Query can match. Each conjunct of the
+// predicate must follow from one of the Query's conjuncts
+func implies(conj []*expr.Expr, where *expr.Expr) bool {
+	if where == nil {
+		return true
+	}
+	for _, w := range conjuncts([]*expr.Expr{where}) {
+		if !slices.ContainsFunc(conj, func(e *expr.Expr) bool {
+			return impliesConjunct(e, w)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+func impliesConjunct(e, w *expr.Expr) bool {
+	if e.String() == w.String() {
+		return true
+	}
+	c, ok := makeComparison(e)
+	if !ok {
+		return false
+	}
+	if t, ok := notNullTarget(w); ok {
+		return t == c.target
+	}
+	wc, ok := makeComparison(w)
+	return ok && wc.target == c.target && c.implies(wc)
+}
+
+// notNullTarget matches an Expr of the form NOT (target IS NULL), which is
+// satisfied by any comparison of the target to a constant
+func notNullTarget(e *expr.Expr) (string, bool) {
+	if e.Op != expr.NotOp || len(e.Args) != 1 {
+		return "", false
+	}
+	isNull := e.Args[0]
+	if isNull.Op != expr.IsNullOp || len(isNull.Args) != 1 ||
+		!isTarget(isNull.Args[0]) {
+		return "", false
+	}
+	return isNull.Args[0].String(), true
+}
+
+// implies returns whether every Value that satisfies the comparison also
+// satisfies the other comparison of the same Column
+func (c *comparison) implies(o *comparison) bool {
+	cmp := c.value.Compare(o.value)
+	if cmp == value.Incomparable {
+		return false
+	}
+	switch {
+	case c.op == expr.EQOp:
+		return satisfiedBy[o.op](cmp)
+	case isLower(c.op) && isLower(o.op):
+		return cmp == value.GreaterThan ||
+			cmp == value.EqualTo && (c.op == expr.GTOp || o.op == expr.GTEOp)
+	case isUpper(c.op) && isUpper(o.op):
+		return cmp == value.LessThan ||
+			cmp == value.EqualTo && (c.op == expr.LTOp || o.op == expr.LTEOp)
+	default:
+		return false
+	}
+}
+
+func isLower(op expr.Op) bool {
+	return op == expr.GTOp || op == expr.GTEOp
+}
+
+func isUpper(op expr.Op) bool {
+	return op == expr.LTOp || op == expr.LTEOp
+}
+
+// makeAccess matches comparisons to the leftmost Columns of an Index:
+// equality on as many leading Columns as possible, then a range on the
+// next one. Returns nil if the Index's first Column can't be used
+func makeAccess(def index.Definition, cmps []*comparison) *access {
+	res := &access{
+		def:    def,
+		unique: def.Type == db.UniqueIndex,
+	}
+	for _, e := range def.Exprs() {
+		t := e.String()
+		if c := findComparison(cmps, t, expr.EQOp); c != nil {
+			res.eq = append(res.eq, c)
+			continue
+		}
+		res.lower = findComparison(cmps, t, expr.GTOp, expr.GTEOp)
+		res.upper = findComparison(cmps, t, expr.LTOp, expr.LTEOp)
+		break
+	}
+	if len(res.eq) == 0 && res.lower == nil && res.upper == nil {
+		return nil
+	}
+	return res
+}
+
+func findComparison(
+	cmps []*comparison, target string, ops ...expr.Op,
+) *comparison {
+	for _, c := range cmps {
+		if c.target != target {
+			continue
+		}
+		for _, op := range ops {
+			if c.op == op {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// exact returns whether the access path reads at most one row
+func (a *access) exact() bool {
+	return a.unique && len(a.eq) == len(a.def.Columns)
+}
+
+func (a *access) ranged() int {
+	var res int
+	if a.lower != nil {
+		res++
+	}
+	if a.upper != nil {
+		res++
+	}
+	return res
+}
+
+// betterThan prefers a path that reads a single row, then the one that
+// matches the most leading Columns by equality, then the tighter range,
+// and finally the narrower Index
+func (a *access) betterThan(o *access) bool {
+	switch {
+	case o == nil:
+		return true
+	case a.exact() != o.exact():
+		return a.exact()
+	case len(a.eq) != len(o.eq):
+		return len(a.eq) > len(o.eq)
+	case a.ranged() != o.ranged():
+		return a.ranged() > o.ranged()
+	default:
+		return len(a.def.Columns) < len(o.def.Columns)
+	}
+}
+
+func (a *access) used() []*comparison {
+	res := append([]*comparison{}, a.eq...)
+	if a.lower != nil {
+		res = append(res, a.lower)
+	}
+	if a.upper != nil {
+		res = append(res, a.upper)
+	}
+	return res
+}
+
+func newIndexScan(tbl table.Table, a *access) *indexScan {
+	cols := columnNames(tbl.Columns())
+	used := a.def.Columns[:len(a.eq)]
+	if a.ranged() != 0 {
+		used = a.def.Columns[:len(a.eq)+1]
+	}
+	sel, _ := a.def.Selector(tbl.Columns(), used...)
+
+	res := &indexScan{
+		table:    tbl.Name(),
+		index:    a.def.Name,
+		columns:  cols,
+		selector: sel,
+		used:     len(used),
+		prefix:   make(relation.Relation, len(a.eq)),
+		lower:    makeBound(a.lower),
+		upper:    makeBound(a.upper),
+		exact:    a.exact(),
+		rows:     tbl.Count(),
+	}
+	if a.ranged() != 0 {
+		res.desc = a.def.IsDescending(used[len(a.eq)])
+	}
+	for i, c := range a.eq {
+		res.prefix[i] = c.value
+	}
+	for _, c := range a.used() {
+		res.conds = append(res.conds, c.expr)
+	}
+	return res
+}
+
+func makeBound(c *comparison) *bound {
+	if c == nil {
+		return nil
+	}
+	return &bound{
+		value:     c.value,
+		inclusive: c.op == expr.GTEOp || c.op == expr.LTEOp,
+	}
+}
+
+func (s *indexScan) Columns() column.Names {
+	return s.columns
+}
+
+func (s *indexScan) String() string {
+	return Explain(s).chain()
+}
+
+func (s *indexScan) explain(st statistics) *Explanation {
+	conds := make([]string, len(s.conds))
+	est := float64(s.rows)
+	for i, c := range s.conds {
+		conds[i] = c.String()
+		est *= selectivity(c)
+	}
+	if s.exact && est > 1 {
+		est = 1
+	}
+	detail := fmt.Sprintf("%s.%s", s.table, s.index)
+	if len(conds) != 0 {
+		detail += ": " + strings.Join(conds, " AND ")
+	}
+	if s.reverse {
+		detail += " (reverse)"
+	}
+	if s.covering {
+		detail += " (covering)"
+	}
+	return st.describe(s, IndexScanOp, detail, est)
+}
+
+func (s *indexScan) open(e *execution) (transaction.Iterator, error) {
+	tbl, err := e.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := tbl.Index(s.index)
+	if err != nil {
+		return nil, err
+	}
+	if s.covering {
+		return s.cover(s.entries(idx.Covering())), nil
+	}
+	return s.lookup(tbl, s.entries(idx)), nil
+}
+
+// entries positions an Iterator over the Index at the first entry that
+// may match. Without any restrictions, every entry is read in order. The
+// bounds are swapped when the range Column's entries are stored in
+// descending order, and the Index is read from its end when reversed
+func (s *indexScan) entries(idx index.Query) transaction.Iterator {
+	low, high := s.lower, s.upper
+	if s.desc {
+		low, high = high, low
+	}
+	if s.reverse {
+		idx = idx.Descending()
+		low, high = high, low
+	}
+	switch {
+	case low != nil:
+		r := append(s.prefix[:len(s.prefix):len(s.prefix)], low.value)
+		if s.reverse && low.inclusive {
+			return idx.LTE(r)
+		} else if s.reverse {
+			return idx.LT(r)
+		} else if low.inclusive {
+			return idx.GTE(r)
+		}
+		return idx.GT(r)
+	case len(s.prefix) != 0:
+		return idx.EQ(s.prefix)
+	case high == nil:
+		return idx.GTE(relation.Relation{})
+	case s.reverse && high.inclusive:
+		return idx.GTE(relation.Relation{high.value})
+	case s.reverse:
+		return idx.GT(relation.Relation{high.value})
+	case high.inclusive:
+		return idx.LTE(relation.Relation{high.value})
+	default:
+		return idx.LT(relation.Relation{high.value})
+	}
+}
+
+// lookup retrieves the row for each Index entry, skipping the rows that
+// fall outside the range and ending the iteration once no more can match
+func (s *indexScan) lookup(
+	tbl table.Table, iter transaction.Iterator,
+) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		for _, v, next, ok := iter(); ok; _, v, next, ok = next() {
+			k := v.(value.Key)
+			row, ok := tbl.Select(k)
+			if !ok {
+				continue
+			}
+			match, more := s.check(s.selector(row))
+			if !more {
+				break
+			}
+			if match {
+				return k, relation.Relation(row), s.lookup(tbl, next), true
+			}
+		}
+		return nil, nil, nil, false
+	}
+}
+
+// cover produces the covered Values of each Index entry, without reading
+// the rows, skipping the entries that fall outside the range and ending
+// the iteration once no more can match
+func (s *indexScan) cover(iter transaction.Iterator) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		for _, v, next, ok := iter(); ok; _, v, next, ok = next() {
+			e := v.(index.Entry)
+			match, more := s.check(e.Values[:s.used])
+			if !more {
+				break
+			}
+			if match {
+				return e.Key, e.Values, s.cover(next), true
+			}
+		}
+		return nil, nil, nil, false
+	}
+}
+
+// check compares the Values of the Index Columns that the scan uses to
+// its prefix and bounds
+func (s *indexScan) check(vals relation.Relation) (match bool, more bool) {
+	for i, v := range s.prefix {
+		cell := vals[i]
+		if cell == nil {
+			return false, true
+		}
+		switch cell.Compare(v) {
+		case value.EqualTo:
+		case value.Incomparable:
+			return false, true
+		default:
+			return false, false
+		}
+	}
+	if len(vals) == len(s.prefix) {
+		return true, true
+	}
+	cell := vals[len(s.prefix)]
+	if cell == nil {
+		return false, true
+	}
+	stop, skip := s.upper, s.lower
+	stopDir, skipDir := value.LessThan, value.GreaterThan
+	if s.desc != s.reverse {
+		stop, skip = skip, stop
+		stopDir, skipDir = skipDir, stopDir
+	}
+	if stop != nil && !stop.admits(cell, stopDir) {
+		return false, cell.Compare(stop.value) == value.Incomparable
+	}
+	if skip != nil && !skip.admits(cell, skipDir) {
+		return false, true
+	}
+	return true, true
+}
+
+// admits returns whether the Value compares to the bound in the expected
+// direction, or equals it if the bound is inclusive
+func (b *bound) admits(v value.Value, dir value.Comparison) bool {
+	c := v.Compare(b.value)
+	return c == dir || b.inclusive && c == value.EqualTo
+}
+
+// orderedAccess reads the rows of an ungrouped Query in the order that it
+// requests, if one of the Table's Indexes can produce them that way, so
+// that they don't have to be sorted
+func (q *Query) orderedAccess(tbl table.Table, p Plan) (Plan, bool) {
+	if len(q.orders) == 0 || q.grouped() {
+		return p, false
+	}
+	switch p := p.(type) {
+	case *indexScan:
+		def, _ := tbl.IndexInfo(p.index)
+		reverse, ok := servesOrders(def, len(p.prefix), q.orders)
+		p.reverse = reverse
+		return p, ok
+	case *scan:
+		for _, n := range tbl.Indexes() {
+			def, ok := tbl.IndexInfo(n)
+			if !ok || !completeIndex(def) {
+				continue
+			}
+			if reverse, ok := servesOrders(def, 0, q.orders); ok {
+				res := newIndexScan(tbl, &access{def: def})
+				res.reverse = reverse
+				return res, true
+			}
+		}
+	}
+	return p, false
+}
+
+// servesOrders returns whether the entries of an Index, read forward or in
+// reverse, are in the requested order. Orders on the leading Columns that
+// are fixed by equality are already satisfied, and the rest must follow
+// the Index's Columns, each in the same or each in the opposite direction
+func servesOrders(
+	def index.Definition, fixed int, orders Orders,
+) (reverse bool, ok bool) {
+	targets := make([]string, len(def.Columns))
+	for i, e := range def.Exprs() {
+		targets[i] = e.String()
+	}
+	var rest Orders
+	for _, o := range orders {
+		if !slices.Contains(targets[:fixed], string(o.Column)) {
+			rest = append(rest, o)
+		}
+	}
+	if fixed+len(rest) > len(def.Columns) {
+		return false, false
+	}
+	for i, o := range rest {
+		if string(o.Column) != targets[fixed+i] {
+			return false, false
+		}
+		flip := o.Descending != def.IsDescending(def.Columns[fixed+i])
+		if i == 0 {
+			reverse = flip
+		} else if flip != reverse {
+			return false, false
+		}
+	}
+	return reverse, true
+}
+
+// coveredAccess answers a Query from the entries of its Index scan alone,
+// if the Index stores the Values of every Column that the Query reads
+func (q *Query) coveredAccess(
+	tbl table.Table, p Plan, residual []*expr.Expr,
+) Plan {
+	s, ok := p.(*indexScan)
+	if !ok {
+		return p
+	}
+	def, ok := tbl.IndexInfo(s.index)
+	if !ok || !def.IsCovering() {
+		return p
+	}
+	needed, ok := q.needed(residual)
+	if !ok {
+		return p
+	}
+	covered := def.Covered()
+	for _, n := range needed {
+		if !slices.Contains(covered, n) {
+			return p
+		}
+	}
+	s.covering = true
+	s.columns = covered
+	return s
+}
+
+// needed returns the Names of the Columns that the Query reads from the
+// rows of its Table, or false if it reads all of them
+func (q *Query) needed(residual []*expr.Expr) (column.Names, bool) {
+	var res column.Names
+	if q.grouped() {
+		res = append(res, q.groups...)
+		for _, a := range q.aggs {
+			if a.Column != "" {
+				res = append(res, a.Column)
+			}
+		}
+	} else {
+		if len(q.selected) == 0 {
+			return nil, false
+		}
+		res = append(res, q.selected...)
+		for _, o := range q.orders {
+			res = append(res, o.Column)
+		}
+	}
+	for _, e := range residual {
+		res = append(res, e.Columns()...)
+	}
+	return res, true
+}
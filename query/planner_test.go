@@ -0,0 +1,400 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/query"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func runIndexed(q *query.Query) (query.Plan, *query.Result, error) {
+	var plan query.Plan
+	var res *query.Result
+	_, err := makePeopleDatabase()(func(d database.Database) error {
+		tbl, _ := d.Table("people")
+		if err := tbl.CreateIndex(
+			db.StandardIndex, "by-name", "last_name", "first_name",
+		); err != nil {
+			return err
+		}
+		if err := tbl.CreateIndex(db.StandardIndex, "by-age", "age"); err != nil {
+			return err
+		}
+		if err := tbl.CreateIndex(
+			db.UniqueIndex, "full-name", "first_name", "last_name",
+		); err != nil {
+			return err
+		}
+
+		var err error
+		if plan, err = q.Plan(d); err != nil {
+			return err
+		}
+		res, err = query.Execute(d, plan)
+		return err
+	})
+	return plan, res, err
+}
+
+func runDefined(
+	q *query.Query, defs ...index.Definition,
+) (query.Plan, *query.Result, error) {
+	var plan query.Plan
+	var res *query.Result
+	_, err := makePeopleDatabase()(func(d database.Database) error {
+		tbl, _ := d.Table("people")
+		for _, def := range defs {
+			if err := tbl.DefineIndex(def); err != nil {
+				return err
+			}
+		}
+		var err error
+		if plan, err = q.Plan(d); err != nil {
+			return err
+		}
+		res, err = query.Execute(d, plan)
+		return err
+	})
+	return plan, res, err
+}
+
+func TestPlannerIndexChoice(t *testing.T) {
+	as := assert.New(t)
+
+	plan, res, err := runIndexed(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")).
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- IndexScan(people.by-name: last_name = "Preston")`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{
+		{value.String("Bill")}, {value.String("Joanna")},
+	}, res.Rows)
+
+	plan, res, err = runIndexed(query.From("people").
+		Where("last_name", query.EQ, value.String("Logan")).
+		Where("first_name", query.EQ, value.String("Ted")).
+		Select("age"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(age) <- IndexScan(people.full-name: `+
+			`first_name = "Ted" AND last_name = "Logan")`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{{value.Integer(17)}}, res.Rows)
+}
+
+func TestPlannerRange(t *testing.T) {
+	as := assert.New(t)
+
+	plan, res, err := runIndexed(query.From("people").
+		Where("age", query.GT, value.Integer(17)).
+		Filter(expr.LT(expr.Col("age"), expr.Lit(value.Integer(100)))).
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- IndexScan(people.by-age: age > 17 AND age < 100)`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{
+		{value.String("Elizabeth")}, {value.String("Joanna")},
+	}, res.Rows)
+
+	plan, res, err = runIndexed(query.From("people").
+		Filter(expr.GTE(expr.Lit(value.Integer(24)), expr.Col("age"))).
+		Where("last_name", query.NEQ, value.String("Logan")).
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- Filter(last_name <> "Logan") <- `+
+			`IndexScan(people.by-age: 24 >= age)`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{{value.String("Bill")}}, res.Rows)
+
+	plan, res, err = runIndexed(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")).
+		Where("first_name", query.GTE, value.String("C")).
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- IndexScan(people.by-name: `+
+			`last_name = "Preston" AND first_name >= "C")`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{{value.String("Joanna")}}, res.Rows)
+}
+
+func TestPlannerSortAndScan(t *testing.T) {
+	as := assert.New(t)
+
+	plan, res, err := runIndexed(query.From("people").
+		Where("first_name", query.LT, value.String("F")).
+		OrderBy("first_name").
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- `+
+			`IndexScan(people.full-name: first_name < "F")`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{
+		{value.String("Bill")}, {value.String("Elizabeth")},
+	}, res.Rows)
+
+	plan, res, err = runIndexed(query.From("people").
+		Filter(expr.IsNull(expr.Col("last_name"))).
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- Filter(last_name IS NULL) <- Scan(people)`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{{value.String("Rufus")}}, res.Rows)
+}
+
+func TestPlannerOrderedIndex(t *testing.T) {
+	as := assert.New(t)
+
+	plan, res, err := runIndexed(query.From("people").
+		Where("first_name", query.LT, value.String("F")).
+		OrderByDesc("first_name").
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- `+
+			`IndexScan(people.full-name: first_name < "F" (reverse))`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{
+		{value.String("Elizabeth")}, {value.String("Bill")},
+	}, res.Rows)
+
+	plan, res, err = runIndexed(query.From("people").
+		OrderBy("last_name").
+		OrderBy("first_name").
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- IndexScan(people.by-name)`, plan.String(),
+	)
+	as.Equal([]relation.Relation{
+		{value.String("Rufus")}, {value.String("Elizabeth")},
+		{value.String("Ted")}, {value.String("Bill")},
+		{value.String("Joanna")},
+	}, res.Rows)
+
+	plan, _, err = runIndexed(query.From("people").
+		OrderBy("last_name").
+		OrderByDesc("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(
+		`Sort(last_name, first_name DESC) <- Scan(people)`, plan.String(),
+	)
+}
+
+func TestPlannerDescendingIndex(t *testing.T) {
+	as := assert.New(t)
+
+	latest := index.Definition{
+		Name:       "latest",
+		Type:       db.StandardIndex,
+		Columns:    column.Names{"last_name", "age"},
+		Descending: column.Names{"age"},
+	}
+	run := func(q *query.Query) (query.Plan, *query.Result) {
+		plan, res, err := runDefined(q, latest)
+		as.Nil(err)
+		return plan, res
+	}
+
+	plan, res := run(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")).
+		OrderByDesc("age").
+		Select("first_name"),
+	)
+	as.Equal(
+		`Project(first_name) <- `+
+			`IndexScan(people.latest: last_name = "Preston")`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{
+		{value.String("Joanna")}, {value.String("Bill")},
+	}, res.Rows)
+
+	plan, res = run(query.From("people").
+		Where("last_name", query.EQ, value.String("Logan")).
+		Where("age", query.GTE, value.Integer(17)).
+		Where("age", query.LT, value.Integer(24)).
+		OrderBy("age").
+		Select("first_name"),
+	)
+	as.Equal(
+		`Project(first_name) <- IndexScan(people.latest: `+
+			`last_name = "Logan" AND age >= 17 AND age < 24 (reverse))`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{{value.String("Ted")}}, res.Rows)
+
+	_, res = run(query.From("people").
+		Where("last_name", query.EQ, value.String("Logan")).
+		Where("age", query.GT, value.Integer(17)).
+		Select("first_name"),
+	)
+	as.Equal([]relation.Relation{{value.String("Elizabeth")}}, res.Rows)
+
+	_, res = run(query.From("people").
+		Where("last_name", query.EQ, value.String("Logan")).
+		Where("age", query.LTE, value.Integer(24)).
+		OrderByDesc("age").
+		Select("first_name"),
+	)
+	as.Equal([]relation.Relation{
+		{value.String("Elizabeth")}, {value.String("Ted")},
+	}, res.Rows)
+}
+
+func TestPlannerPartialIndex(t *testing.T) {
+	as := assert.New(t)
+
+	adults := index.Definition{
+		Name:    "adults",
+		Type:    db.StandardIndex,
+		Columns: column.Names{"last_name"},
+		Where:   expr.GTE(expr.Col("age"), expr.Lit(value.Integer(18))),
+	}
+	run := func(q *query.Query) (query.Plan, *query.Result) {
+		plan, res, err := runDefined(q.Select("first_name"), adults)
+		as.Nil(err)
+		return plan, res
+	}
+
+	plan, res := run(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")),
+	)
+	as.Equal(
+		`Project(first_name) <- Filter(last_name = "Preston") <- `+
+			`Scan(people)`,
+		plan.String(),
+	)
+	as.Equal(2, len(res.Rows))
+
+	plan, res = run(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")).
+		Where("age", query.GT, value.Integer(20)),
+	)
+	as.Equal(
+		`Project(first_name) <- Filter(age > 20) <- `+
+			`IndexScan(people.adults: last_name = "Preston")`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{{value.String("Joanna")}}, res.Rows)
+
+	plan, _ = run(query.From("people").
+		Where("last_name", query.EQ, value.String("Logan")).
+		Where("age", query.GTE, value.Integer(18)),
+	)
+	as.Contains(plan.String(), "IndexScan(people.adults")
+
+	plan, _ = run(query.From("people").
+		Where("last_name", query.EQ, value.String("Logan")).
+		Where("age", query.GT, value.Integer(17)),
+	)
+	as.NotContains(plan.String(), "IndexScan")
+
+	plan, _ = run(query.From("people").OrderBy("last_name"))
+	as.Contains(plan.String(), "Sort(last_name)")
+}
+
+func TestPlannerComputedIndex(t *testing.T) {
+	as := assert.New(t)
+
+	lower := expr.Call("lower", expr.Col("last_name"))
+	plan, res, err := runDefined(query.From("people").
+		Filter(expr.EQ(lower, expr.Lit(value.String("logan")))).
+		Where("age", query.GT, value.Integer(20)).
+		Select("first_name"),
+		index.Definition{
+			Name:     "by-lower",
+			Type:     db.StandardIndex,
+			Columns:  column.Names{"last", "age"},
+			Computed: index.Computed{"last": lower},
+		},
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- IndexScan(people.by-lower: `+
+			`lower(last_name) = "logan" AND age > 20)`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{{value.String("Elizabeth")}}, res.Rows)
+}
+
+func TestPlannerCoveringIndex(t *testing.T) {
+	as := assert.New(t)
+
+	byName := index.Definition{
+		Name:    "by-name",
+		Type:    db.StandardIndex,
+		Columns: column.Names{"last_name"},
+		Include: column.Names{"first_name"},
+	}
+
+	notTed := expr.NEQ(expr.Col("first_name"), expr.Lit(value.String("Ted")))
+	plan, res, err := runDefined(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")).
+		Filter(notTed).
+		OrderBy("first_name").
+		Select("first_name"),
+		byName,
+	)
+	as.Nil(err)
+	as.Equal(
+		`Project(first_name) <- Sort(first_name) <- `+
+			`Filter(first_name <> "Ted") <- `+
+			`IndexScan(people.by-name: last_name = "Preston" (covering))`,
+		plan.String(),
+	)
+	as.Equal([]relation.Relation{
+		{value.String("Bill")}, {value.String("Joanna")},
+	}, res.Rows)
+	as.Equal(2, len(res.Keys))
+
+	plan, res, err = runDefined(query.From("people").
+		Where("last_name", query.GTE, value.String("L")).
+		GroupBy("last_name").
+		Aggregate(query.Count("first_name")).
+		OrderBy("last_name"),
+		byName,
+	)
+	as.Nil(err)
+	as.Contains(plan.String(), "(covering)")
+	as.Equal(2, len(res.Rows))
+
+	plan, _, err = runDefined(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")).
+		Select("first_name", "age"),
+		byName,
+	)
+	as.Nil(err)
+	as.NotContains(plan.String(), "(covering)")
+}
@@ -0,0 +1,217 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Op is a comparison operator that can be used in a Where clause
+	Op string
+
+	// Order describes how a Column is sorted
+	Order struct {
+		Column     column.Name
+		Descending bool
+	}
+
+	// Orders are a set of Order, in order of precedence
+	Orders []Order
+
+	// Query is an immutable description of a relational query against a
+	// Table. Each of its methods returns a new Query, leaving the original
+	// untouched
+	Query struct {
+		table    table.Name
+		joins    []Join
+		where    []*expr.Expr
+		groups   column.Names
+		aggs     Aggregates
+		having   []*expr.Expr
+		selected column.Names
+		orders   Orders
+		limit    int
+		offset   int
+		err      error
+	}
+)
+
+// Comparison operators
+const (
+	EQ  Op = "="
+	NEQ Op = "<>"
+	LT  Op = "<"
+	LTE Op = "<="
+	GT  Op = ">"
+	GTE Op = ">="
+)
+
+// Error messages
+const (
+	ErrUnknownOp     = "unknown comparison operator: %s"
+	ErrTableNotFound = "table not found: %s"
+	ErrBadLimit      = "limit must not be negative: %d"
+	ErrBadOffset     = "offset must not be negative: %d"
+)
+
+var comparisons = map[Op]func(l, r *expr.Expr) *expr.Expr{
+	EQ:  expr.EQ,
+	NEQ: expr.NEQ,
+	LT:  expr.LT,
+	LTE: expr.LTE,
+	GT:  expr.GT,
+	GTE: expr.GTE,
+}
+
+// From starts a Query that reads from the named Table
+func From(n table.Name) *Query {
+	return &Query{
+		table: n,
+		limit: -1,
+	}
+}
+
+// Where restricts the Query to rows where the named Column compares to the
+// provided Value using the specified Op. Multiple restrictions must all be
+// satisfied
+func (q *Query) Where(col column.Name, op Op, v value.Value) *Query {
+	cmp, ok := comparisons[op]
+	if !ok {
+		return q.fail(fmt.Errorf(ErrUnknownOp, op))
+	}
+	return q.Filter(cmp(expr.Col(col), expr.Lit(v)))
+}
+
+// Filter restricts the Query to rows for which the provided Expr is true
+func (q *Query) Filter(e *expr.Expr) *Query {
+	res := q.copy()
+	res.where = append(res.where[:len(q.where):len(q.where)], e)
+	return res
+}
+
+// Select chooses the Columns that the Query returns. If no Columns are
+// selected, all of the Table's Columns are returned
+func (q *Query) Select(cols ...column.Name) *Query {
+	res := q.copy()
+	res.selected = cols
+	return res
+}
+
+// OrderBy sorts the Query's results by the named Column, in ascending
+// order. Each call adds a Column of lower precedence
+func (q *Query) OrderBy(col column.Name) *Query {
+	return q.order(Order{Column: col})
+}
+
+// OrderByDesc sorts the Query's results by the named Column, in descending
+// order. Each call adds a Column of lower precedence
+func (q *Query) OrderByDesc(col column.Name) *Query {
+	return q.order(Order{Column: col, Descending: true})
+}
+
+func (q *Query) order(o Order) *Query {
+	res := q.copy()
+	res.orders = append(res.orders[:len(q.orders):len(q.orders)], o)
+	return res
+}
+
+// Limit restricts the Query to returning at most n results
+func (q *Query) Limit(n int) *Query {
+	if n < 0 {
+		return q.fail(fmt.Errorf(ErrBadLimit, n))
+	}
+	res := q.copy()
+	res.limit = n
+	return res
+}
+
+// Offset skips the first n results of the Query
+func (q *Query) Offset(n int) *Query {
+	if n < 0 {
+		return q.fail(fmt.Errorf(ErrBadOffset, n))
+	}
+	res := q.copy()
+	res.offset = n
+	return res
+}
+
+// Plan resolves the Query against the Database, returning a Plan that can
+// be executed. Where possible, one of the Table's Indexes is used to find
+// matching rows, and the remaining restrictions are filtered
+func (q *Query) Plan(d database.Database) (Plan, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	tbl, ok := d.Table(q.table)
+	if !ok {
+		return nil, fmt.Errorf(ErrTableNotFound, q.table)
+	}
+
+	var res Plan
+	var residual []*expr.Expr
+	var ordered, sorted bool
+	var err error
+	if len(q.joins) == 0 {
+		res, residual = planAccess(tbl, q.where)
+		res, ordered = q.groupedAccess(tbl, res)
+		res, sorted = q.orderedAccess(tbl, res)
+		res = q.coveredAccess(tbl, res, residual)
+	} else if res, residual, err = q.planJoins(d, tbl); err != nil {
+		return nil, err
+	}
+	if res, err = filterAll(res, residual); err != nil {
+		return nil, err
+	}
+	if q.grouped() {
+		res, err = newAggregation(res, q.groups, q.aggs, ordered)
+		if err != nil {
+			return nil, err
+		}
+		if res, err = filterAll(res, q.having); err != nil {
+			return nil, err
+		}
+	} else if len(q.having) != 0 {
+		return nil, fmt.Errorf(ErrHavingNotGrouped, q.having[0])
+	}
+	if len(q.orders) != 0 && !sorted {
+		if res, err = newSorter(res, q.orders...); err != nil {
+			return nil, err
+		}
+	}
+	if q.offset != 0 || q.limit >= 0 {
+		res = newLimiter(res, q.offset, q.limit)
+	}
+	if len(q.selected) != 0 {
+		if res, err = newProjection(res, q.selected...); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Run plans and executes the Query against the Database
+func (q *Query) Run(d database.Database) (*Result, error) {
+	p, err := q.Plan(d)
+	if err != nil {
+		return nil, err
+	}
+	return Execute(d, p)
+}
+
+func (q *Query) copy() *Query {
+	res := *q
+	return &res
+}
+
+func (q *Query) fail(err error) *Query {
+	res := q.copy()
+	if res.err == nil {
+		res.err = err
+	}
+	return res
+}
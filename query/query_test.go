@@ -0,0 +1,173 @@
+package query_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/query"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+var people = []relation.Row{
+	{value.String("Bill"), value.String("Preston"), value.Integer(17)},
+	{value.String("Ted"), value.String("Logan"), value.Integer(17)},
+	{value.String("Rufus"), nil, value.Integer(700)},
+	{value.String("Joanna"), value.String("Preston"), value.Integer(25)},
+	{value.String("Elizabeth"), value.String("Logan"), value.Integer(24)},
+}
+
+func makePeopleDatabase() database.Transactor {
+	d, err := db.NewDatabase()(func(d database.Database) error {
+		tbl, err := d.CreateTable("people",
+			column.Make("first_name"),
+			column.Make("last_name"),
+			column.Make("age"),
+		)
+		if err != nil {
+			return err
+		}
+		for _, p := range people {
+			if _, err := tbl.InsertAuto(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func run(q *query.Query) (*query.Result, error) {
+	var res *query.Result
+	_, err := makePeopleDatabase()(func(d database.Database) error {
+		var err error
+		res, err = q.Run(d)
+		return err
+	})
+	return res, err
+}
+
+func TestQueryAll(t *testing.T) {
+	as := assert.New(t)
+
+	res, err := run(query.From("people"))
+	as.Nil(err)
+	as.Equal(column.Names{"first_name", "last_name", "age"}, res.Columns)
+	as.Equal(len(people), len(res.Rows))
+	for _, p := range people {
+		as.Contains(res.Rows, relation.Relation(p))
+	}
+}
+
+func TestQueryWhere(t *testing.T) {
+	as := assert.New(t)
+
+	res, err := run(query.From("people").
+		Where("last_name", query.EQ, value.String("Preston")).
+		Where("age", query.LT, value.Integer(20)).
+		Select("first_name"),
+	)
+	as.Nil(err)
+	as.Equal(column.Names{"first_name"}, res.Columns)
+	as.Equal([]relation.Relation{{value.String("Bill")}}, res.Rows)
+
+	res, err = run(query.From("people").
+		Filter(expr.IsNull(expr.Col("last_name"))).
+		Select("first_name", "age"),
+	)
+	as.Nil(err)
+	as.Equal([]relation.Relation{
+		{value.String("Rufus"), value.Integer(700)},
+	}, res.Rows)
+}
+
+func TestQueryOrderLimit(t *testing.T) {
+	as := assert.New(t)
+
+	q := query.From("people").
+		OrderBy("last_name").
+		OrderByDesc("age").
+		Select("first_name")
+
+	res, err := run(q)
+	as.Nil(err)
+	as.Equal([]relation.Relation{
+		{value.String("Rufus")},
+		{value.String("Elizabeth")},
+		{value.String("Ted")},
+		{value.String("Joanna")},
+		{value.String("Bill")},
+	}, res.Rows)
+
+	res, err = run(q.Offset(1).Limit(3))
+	as.Nil(err)
+	as.Equal([]relation.Relation{
+		{value.String("Elizabeth")},
+		{value.String("Ted")},
+		{value.String("Joanna")},
+	}, res.Rows)
+
+	res, err = run(q.Offset(4).Limit(3))
+	as.Nil(err)
+	as.Equal([]relation.Relation{{value.String("Bill")}}, res.Rows)
+
+	res, err = run(q.Limit(0))
+	as.Nil(err)
+	as.Nil(res.Rows)
+}
+
+func TestQueryImmutable(t *testing.T) {
+	as := assert.New(t)
+
+	base := query.From("people").Where("age", query.EQ, value.Integer(17))
+	bill := base.Where("first_name", query.EQ, value.String("Bill"))
+	ted := base.Where("first_name", query.EQ, value.String("Ted"))
+
+	res, err := run(bill.Select("last_name"))
+	as.Nil(err)
+	as.Equal([]relation.Relation{{value.String("Preston")}}, res.Rows)
+
+	res, err = run(ted.Select("last_name"))
+	as.Nil(err)
+	as.Equal([]relation.Relation{{value.String("Logan")}}, res.Rows)
+
+	res, err = run(base)
+	as.Nil(err)
+	as.Equal(2, len(res.Rows))
+}
+
+func TestQueryErrors(t *testing.T) {
+	as := assert.New(t)
+
+	_, err := run(query.From("missing"))
+	as.EqualError(err, fmt.Sprintf(query.ErrTableNotFound, "missing"))
+
+	_, err = run(query.From("people").Where("age", "~", value.Integer(1)))
+	as.EqualError(err, fmt.Sprintf(query.ErrUnknownOp, "~"))
+
+	_, err = run(query.From("people").Limit(-1))
+	as.EqualError(err, fmt.Sprintf(query.ErrBadLimit, -1))
+
+	_, err = run(query.From("people").Offset(-1))
+	as.EqualError(err, fmt.Sprintf(query.ErrBadOffset, -1))
+
+	_, err = run(query.From("people").Where("missing", query.EQ, nil))
+	as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+	_, err = run(query.From("people").OrderBy("missing"))
+	as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+	_, err = run(query.From("people").Select("missing"))
+	as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "missing"))
+
+	_, err = run(query.From("people").Where("age", query.EQ, value.String("1")))
+	as.EqualError(err, fmt.Sprintf(expr.ErrIncomparable, `age = "1"`))
+}
@@ -16,6 +16,14 @@ type (
 
 	// Selector is a function that takes a Row and returns a Relation
 	Selector func(Row) Relation
+
+	// Computation is a function that derives a Value from a Row, such as
+	// the Value of one of its Columns or the result of an expression
+	Computation func(Row) value.Value
+
+	// Getter is a function that takes a Row and returns the Native Go
+	// value of one of its Columns
+	Getter[T value.Native] func(Row) (T, bool)
 )
 
 // Error messages
@@ -64,6 +72,48 @@ func MakeOffsetSelector(offsets ...column.Offset) Selector {
 	}
 }
 
+// OffsetComputation returns a Computation that produces the Value stored
+// at the specified Offset of a Row
+func OffsetComputation(o column.Offset) Computation {
+	return func(r Row) value.Value {
+		return r[o]
+	}
+}
+
+// MakeComputedSelector returns a Selector whose Relation holds the Values
+// produced by each of the specified Computations
+func MakeComputedSelector(c ...Computation) Selector {
+	l := len(c)
+	return func(r Row) Relation {
+		res := make(Relation, l)
+		for i, fn := range c {
+			res[i] = fn(r)
+		}
+		return res
+	}
+}
+
+// Get returns the Native Go value stored at the Offset of a Relation. If
+// the Value is null or stores a different type, the zero value of T is
+// returned along with false
+func Get[T value.Native](r Relation, off column.Offset) (T, bool) {
+	return value.As[T](r[off])
+}
+
+// MakeGetter takes Columns and a Typed Column and returns a Getter that
+// retrieves that Column's Native Go value from a Row
+func MakeGetter[T value.Native](
+	cols column.Columns, c column.Typed[T],
+) (Getter[T], error) {
+	off, err := MakeOffsets(cols, c.Name())
+	if err != nil {
+		return nil, err
+	}
+	return func(r Row) (T, bool) {
+		return Get[T](Relation(r), off[0])
+	}, nil
+}
+
 // StarSelector is used to retrieve the entire Row as a Relation
 var StarSelector = Selector(func(r Row) Relation {
 	return Relation(r)
@@ -77,6 +77,25 @@ func TestMakeNamedSelector(t *testing.T) {
 	as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "not there"))
 }
 
+func TestMakeComputedSelector(t *testing.T) {
+	as := assert.New(t)
+
+	s := relation.MakeComputedSelector(
+		relation.OffsetComputation(1),
+		func(r relation.Row) value.Value {
+			return value.Integer(len(r))
+		},
+	)
+	r := s(relation.Row{
+		value.String("first"),
+		value.String("second"),
+		value.String("third"),
+	})
+	as.Equal(relation.Relation{
+		value.String("second"), value.Integer(3),
+	}, r)
+}
+
 func TestStarSelector(t *testing.T) {
 	as := assert.New(t)
 
@@ -89,3 +108,28 @@ func TestStarSelector(t *testing.T) {
 	rel := relation.StarSelector(row)
 	as.Equal(relation.Relation(row), rel)
 }
+
+func TestGet(t *testing.T) {
+	as := assert.New(t)
+
+	name := column.Of[string]("name")
+	age := column.Of[int64]("age")
+	c := column.Columns{name, age}
+	row := relation.Row{value.String("Bill"), value.Integer(17)}
+
+	n, ok := relation.Get[string](relation.Relation(row), 0)
+	as.True(ok)
+	as.Equal("Bill", n)
+
+	_, ok = relation.Get[string](relation.Relation(row), 1)
+	as.False(ok)
+
+	getAge, err := relation.MakeGetter(c, age)
+	as.Nil(err)
+	a, ok := getAge(row)
+	as.True(ok)
+	as.Equal(int64(17), a)
+
+	_, err = relation.MakeGetter(c, column.Of[bool]("active"))
+	as.EqualError(err, fmt.Sprintf(relation.ErrColumnNotFound, "active"))
+}
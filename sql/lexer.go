@@ -0,0 +1,158 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type (
+	// tokenType identifies the kind of a lexed token
+	tokenType int
+
+	token struct {
+		typ  tokenType
+		text string
+		pos  int
+	}
+)
+
+// Token types
+const (
+	endToken tokenType = iota
+	identToken
+	keywordToken
+	numberToken
+	stringToken
+	paramToken
+	symbolToken
+)
+
+// Error messages
+const (
+	ErrUnexpectedChar     = "unexpected character: %s"
+	ErrUnterminatedString = "unterminated string at position: %d"
+	ErrUnterminatedIdent  = "unterminated identifier at position: %d"
+)
+
+var keywords = map[string]bool{
+	"ALL": true, "AND": true, "AS": true, "ASC": true, "BY": true,
+	"CREATE": true, "DELETE": true, "DESC": true, "DISTINCT": true,
+	"FALSE": true, "FROM": true, "GROUP": true, "HAVING": true,
+	"INCLUDE": true, "INDEX": true, "INNER": true, "INSERT": true,
+	"INTO": true, "IS": true, "JOIN": true, "LEFT": true, "LIMIT": true,
+	"NOT": true, "NULL": true, "OFFSET": true, "ON": true, "OR": true,
+	"ORDER": true, "OUTER": true, "SELECT": true, "SET": true,
+	"TABLE": true, "TRUE": true, "UNIQUE": true, "UPDATE": true,
+	"VALUES": true, "WHERE": true,
+}
+
+var symbols = []string{
+	"<>", "!=", "<=", ">=", "=", "<", ">", "(", ")", ",", ".", "*", "-", ";",
+}
+
+// lex splits a SQL string into tokens. Keywords are recognized without
+// regard to case, and are reported in upper case
+func lex(s string) ([]token, error) {
+	var res []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '-' && strings.HasPrefix(s[i:], "--"):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case isIdentStart(c):
+			start := i
+			for i < len(s) && isIdentPart(rune(s[i])) {
+				i++
+			}
+			word := s[start:i]
+			if upper := strings.ToUpper(word); keywords[upper] {
+				res = append(res, token{keywordToken, upper, start})
+			} else {
+				res = append(res, token{identToken, word, start})
+			}
+		case unicode.IsDigit(c):
+			start := i
+			for i < len(s) && (unicode.IsDigit(rune(s[i])) || s[i] == '.') {
+				i++
+			}
+			res = append(res, token{numberToken, s[start:i], start})
+		case c == '\'':
+			text, next, err := lexQuoted(s, i, '\'')
+			if err != nil {
+				return nil, fmt.Errorf(ErrUnterminatedString, i)
+			}
+			res = append(res, token{stringToken, text, i})
+			i = next
+		case c == '"':
+			text, next, err := lexQuoted(s, i, '"')
+			if err != nil {
+				return nil, fmt.Errorf(ErrUnterminatedIdent, i)
+			}
+			res = append(res, token{identToken, text, i})
+			i = next
+		case c == '?':
+			res = append(res, token{paramToken, "?", i})
+			i++
+		default:
+			sym, ok := lexSymbol(s[i:])
+			if !ok {
+				return nil, fmt.Errorf(ErrUnexpectedChar, string(c))
+			}
+			res = append(res, token{symbolToken, sym, i})
+			i += len(sym)
+		}
+	}
+	return append(res, token{endToken, "", len(s)}), nil
+}
+
+// lexQuoted reads a quoted string starting at position i. A doubled quote
+// stands for a single quote character
+func lexQuoted(s string, i int, q byte) (string, int, error) {
+	var buf strings.Builder
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != q {
+			buf.WriteByte(s[j])
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == q {
+			buf.WriteByte(q)
+			j++
+			continue
+		}
+		return buf.String(), j + 1, nil
+	}
+	return "", 0, fmt.Errorf(ErrUnterminatedString, i)
+}
+
+func lexSymbol(s string) (string, bool) {
+	for _, sym := range symbols {
+		if strings.HasPrefix(s, sym) {
+			return sym, true
+		}
+	}
+	return "", false
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || unicode.IsDigit(c)
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case endToken:
+		return "end of input"
+	case stringToken:
+		return fmt.Sprintf("'%s'", t.text)
+	default:
+		return t.text
+	}
+}
@@ -0,0 +1,625 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/query"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+	params []*expr.Literal
+}
+
+// Error messages
+const (
+	ErrUnexpectedToken = "unexpected token: %s"
+	ErrBadNumber       = "invalid number: %s"
+)
+
+var comparisons = map[string]func(l, r *expr.Expr) *expr.Expr{
+	"=":  expr.EQ,
+	"<>": expr.NEQ,
+	"!=": expr.NEQ,
+	"<":  expr.LT,
+	"<=": expr.LTE,
+	">":  expr.GT,
+	">=": expr.GTE,
+}
+
+func parse(s string) (statement, []*expr.Literal, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &parser{tokens: tokens}
+	stmt, err := p.statement()
+	if err != nil {
+		return nil, nil, err
+	}
+	p.symbol(";")
+	if err := p.expect(endToken, ""); err != nil {
+		return nil, nil, err
+	}
+	return stmt, p.params, nil
+}
+
+func (p *parser) statement() (statement, error) {
+	switch {
+	case p.keyword("SELECT"):
+		return p.selectStatement()
+	case p.keyword("INSERT"):
+		return p.insertStatement()
+	case p.keyword("UPDATE"):
+		return p.updateStatement()
+	case p.keyword("DELETE"):
+		return p.deleteStatement()
+	case p.keyword("CREATE"):
+		switch {
+		case p.keyword("TABLE"):
+			return p.createTableStatement()
+		case p.keyword("UNIQUE"):
+			if err := p.expectKeyword("INDEX"); err != nil {
+				return nil, err
+			}
+			return p.createIndexStatement(true)
+		case p.keyword("INDEX"):
+			return p.createIndexStatement(false)
+		}
+	}
+	return nil, p.unexpected()
+}
+
+func (p *parser) createTableStatement() (statement, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	cols, err := p.columnList()
+	if err != nil {
+		return nil, err
+	}
+	return &createTableStmt{table: table.Name(name), columns: cols}, nil
+}
+
+func (p *parser) createIndexStatement(unique bool) (statement, error) {
+	name, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("ON"); err != nil {
+		return nil, err
+	}
+	tbl, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(symbolToken, "("); err != nil {
+		return nil, err
+	}
+	res := &createIndexStmt{
+		unique: unique,
+		name:   index.Name(name),
+		table:  table.Name(tbl),
+	}
+	for {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		col := e.Column
+		if e.Op != expr.ColumnOp {
+			col = column.Name(e.String())
+			if res.computed == nil {
+				res.computed = index.Computed{}
+			}
+			res.computed[col] = e
+		}
+		res.columns = append(res.columns, col)
+		if p.keyword("DESC") {
+			res.descending = append(res.descending, col)
+		} else {
+			p.keyword("ASC")
+		}
+		if !p.symbol(",") {
+			break
+		}
+	}
+	if err := p.expect(symbolToken, ")"); err != nil {
+		return nil, err
+	}
+	if p.keyword("INCLUDE") {
+		if res.include, err = p.columnList(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("WHERE") {
+		if res.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (p *parser) insertStatement() (statement, error) {
+	if err := p.expectKeyword("INTO"); err != nil {
+		return nil, err
+	}
+	tbl, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	res := &insertStmt{table: table.Name(tbl)}
+	if p.peekSymbol("(") {
+		if res.columns, err = p.columnList(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expectKeyword("VALUES"); err != nil {
+		return nil, err
+	}
+	for {
+		if err := p.expect(symbolToken, "("); err != nil {
+			return nil, err
+		}
+		row, err := p.exprList()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(symbolToken, ")"); err != nil {
+			return nil, err
+		}
+		res.rows = append(res.rows, row)
+		if !p.symbol(",") {
+			return res, nil
+		}
+	}
+}
+
+func (p *parser) updateStatement() (statement, error) {
+	tbl, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("SET"); err != nil {
+		return nil, err
+	}
+	res := &updateStmt{table: table.Name(tbl)}
+	for {
+		col, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(symbolToken, "="); err != nil {
+			return nil, err
+		}
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		res.sets = append(res.sets, assignment{
+			column: column.Name(col),
+			expr:   e,
+		})
+		if !p.symbol(",") {
+			break
+		}
+	}
+	res.where, err = p.where()
+	return res, err
+}
+
+func (p *parser) deleteStatement() (statement, error) {
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	tbl, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	res := &deleteStmt{table: table.Name(tbl)}
+	res.where, err = p.where()
+	return res, err
+}
+
+func (p *parser) selectStatement() (statement, error) {
+	res := &selectStmt{}
+	var err error
+	if res.items, err = p.selectItems(); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	from, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	res.from = table.Name(from)
+	if res.joins, err = p.joins(); err != nil {
+		return nil, err
+	}
+	if res.where, err = p.where(); err != nil {
+		return nil, err
+	}
+	if p.keyword("GROUP") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		if res.groups, err = p.exprList(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("HAVING") {
+		if res.having, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		if res.orders, err = p.orderItems(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("LIMIT") {
+		if res.limit, err = p.primary(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("OFFSET") {
+		if res.offset, err = p.primary(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (p *parser) selectItems() ([]selectItem, error) {
+	var res []selectItem
+	for {
+		if p.symbol("*") {
+			res = append(res, selectItem{star: true})
+		} else {
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			item := selectItem{expr: e}
+			if p.keyword("AS") {
+				alias, err := p.ident()
+				if err != nil {
+					return nil, err
+				}
+				item.alias = column.Name(alias)
+			}
+			res = append(res, item)
+		}
+		if !p.symbol(",") {
+			return res, nil
+		}
+	}
+}
+
+func (p *parser) joins() ([]joinClause, error) {
+	var res []joinClause
+	for {
+		typ := query.Inner
+		switch {
+		case p.keyword("LEFT"):
+			p.keyword("OUTER")
+			typ = query.LeftOuter
+			if err := p.expectKeyword("JOIN"); err != nil {
+				return nil, err
+			}
+		case p.keyword("INNER"):
+			if err := p.expectKeyword("JOIN"); err != nil {
+				return nil, err
+			}
+		case p.keyword("JOIN"):
+		default:
+			return res, nil
+		}
+		tbl, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("ON"); err != nil {
+			return nil, err
+		}
+		on, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, joinClause{
+			typ:   typ,
+			table: table.Name(tbl),
+			on:    on,
+		})
+	}
+}
+
+func (p *parser) where() (*expr.Expr, error) {
+	if p.keyword("WHERE") {
+		return p.expr()
+	}
+	return nil, nil
+}
+
+func (p *parser) orderItems() ([]orderItem, error) {
+	var res []orderItem
+	for {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		item := orderItem{expr: e}
+		if p.keyword("DESC") {
+			item.desc = true
+		} else {
+			p.keyword("ASC")
+		}
+		res = append(res, item)
+		if !p.symbol(",") {
+			return res, nil
+		}
+	}
+}
+
+func (p *parser) columnList() (column.Names, error) {
+	if err := p.expect(symbolToken, "("); err != nil {
+		return nil, err
+	}
+	var res column.Names
+	for {
+		col, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, column.Name(col))
+		if !p.symbol(",") {
+			break
+		}
+	}
+	if err := p.expect(symbolToken, ")"); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *parser) exprList() ([]*expr.Expr, error) {
+	var res []*expr.Expr
+	for {
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+		if !p.symbol(",") {
+			return res, nil
+		}
+	}
+}
+
+func (p *parser) expr() (*expr.Expr, error) {
+	l, err := p.andExpr()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		r, err := p.andExpr()
+		if err != nil {
+			return nil, err
+		}
+		l = expr.Or(l, r)
+	}
+	return l, nil
+}
+
+func (p *parser) andExpr() (*expr.Expr, error) {
+	l, err := p.notExpr()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		r, err := p.notExpr()
+		if err != nil {
+			return nil, err
+		}
+		l = expr.And(l, r)
+	}
+	return l, nil
+}
+
+func (p *parser) notExpr() (*expr.Expr, error) {
+	if p.keyword("NOT") {
+		e, err := p.notExpr()
+		if err != nil {
+			return nil, err
+		}
+		return expr.Not(e), nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (*expr.Expr, error) {
+	l, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("IS") {
+		negate := p.keyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		if negate {
+			return expr.Not(expr.IsNull(l)), nil
+		}
+		return expr.IsNull(l), nil
+	}
+	t := p.peek()
+	if cmp, ok := comparisons[t.text]; ok && t.typ == symbolToken {
+		p.pos++
+		r, err := p.primary()
+		if err != nil {
+			return nil, err
+		}
+		return cmp(l, r), nil
+	}
+	return l, nil
+}
+
+func (p *parser) primary() (*expr.Expr, error) {
+	t := p.next()
+	switch t.typ {
+	case numberToken:
+		return number(t.text)
+	case stringToken:
+		return expr.Lit(value.String(t.text)), nil
+	case paramToken:
+		lit := &expr.Literal{}
+		p.params = append(p.params, lit)
+		return &expr.Expr{Op: expr.LiteralOp, Value: lit}, nil
+	case keywordToken:
+		switch t.text {
+		case "NULL":
+			return expr.Lit(nil), nil
+		case "TRUE":
+			return expr.Lit(value.Bool(true)), nil
+		case "FALSE":
+			return expr.Lit(value.Bool(false)), nil
+		}
+	case identToken:
+		if p.symbol("(") {
+			return p.call(t.text)
+		}
+		if p.symbol(".") {
+			col, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			return expr.Col(column.Name(t.text + "." + col)), nil
+		}
+		return expr.Col(column.Name(t.text)), nil
+	case symbolToken:
+		switch t.text {
+		case "(":
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expect(symbolToken, ")")
+		case "-":
+			if n := p.peek(); n.typ == numberToken {
+				p.pos++
+				return number("-" + n.text)
+			}
+		}
+	}
+	return nil, fmt.Errorf(ErrUnexpectedToken, t)
+}
+
+// call parses the arguments of a function call. The aggregate forms
+// COUNT(*) and COUNT(DISTINCT col) are parsed as calls to the count and
+// countDistinct functions
+func (p *parser) call(name string) (*expr.Expr, error) {
+	fn := expr.FuncName(strings.ToLower(name))
+	if p.symbol(")") {
+		return expr.Call(fn), nil
+	}
+	if fn == expr.FuncName(query.CountFunc) {
+		if p.symbol("*") {
+			return expr.Call(fn), p.expect(symbolToken, ")")
+		}
+		if p.keyword("DISTINCT") {
+			fn = expr.FuncName(query.CountDistinctFunc)
+		}
+	}
+	args, err := p.exprList()
+	if err != nil {
+		return nil, err
+	}
+	return expr.Call(fn, args...), p.expect(symbolToken, ")")
+}
+
+func number(s string) (*expr.Expr, error) {
+	if strings.Contains(s, ".") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf(ErrBadNumber, s)
+		}
+		return expr.Lit(value.Float(f)), nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf(ErrBadNumber, s)
+	}
+	return expr.Lit(value.Integer(i)), nil
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.next()
+	if t.typ != identToken {
+		return "", fmt.Errorf(ErrUnexpectedToken, t)
+	}
+	return t.text, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != endToken {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(k string) bool {
+	if t := p.peek(); t.typ == keywordToken && t.text == k {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) symbol(s string) bool {
+	if p.peekSymbol(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) peekSymbol(s string) bool {
+	t := p.peek()
+	return t.typ == symbolToken && t.text == s
+}
+
+func (p *parser) expectKeyword(k string) error {
+	return p.expect(keywordToken, k)
+}
+
+func (p *parser) expect(typ tokenType, text string) error {
+	t := p.peek()
+	if t.typ != typ || t.text != text {
+		return p.unexpected()
+	}
+	p.pos++
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	return fmt.Errorf(ErrUnexpectedToken, p.peek())
+}
@@ -0,0 +1,157 @@
+// Package sql parses and executes a practical subset of SQL against a
+// Database. Statements may contain ? placeholders, which are bound to
+// arguments when the statement is executed rather than being spliced into
+// its text
+package sql
+
+import (
+	"fmt"
+
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Statement is a parsed SQL statement that can be executed any number
+	// of times with different arguments
+	Statement struct {
+		stmt   statement
+		params []*expr.Literal
+	}
+
+	// Result is the outcome of executing a Statement. Queries report
+	// Columns and Rows, while mutations report the number of rows they
+	// affected. Inserts also report the Keys of the rows they created
+	Result struct {
+		Columns      column.Names
+		Rows         []relation.Relation
+		Keys         []value.Key
+		RowsAffected int
+	}
+
+	statement interface {
+		exec(database.Database, binder) (*Result, error)
+	}
+
+	// binder maps the placeholders of a Statement to their arguments
+	binder map[*expr.Literal]value.Value
+)
+
+// Error messages
+const (
+	ErrParamCount      = "wrong number of parameters, expected: %d"
+	ErrUnsupportedType = "unsupported parameter type: %T"
+)
+
+// Prepare parses a SQL statement so that it can be executed later
+func Prepare(s string) (*Statement, error) {
+	stmt, params, err := parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Statement{stmt: stmt, params: params}, nil
+}
+
+// Exec parses a SQL statement and executes it against the Database, binding
+// its placeholders to the provided arguments
+func Exec(d database.Database, s string, args ...any) (*Result, error) {
+	stmt, err := Prepare(s)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Exec(d, args...)
+}
+
+// Exec executes the Statement against the Database, binding its
+// placeholders to the provided arguments in order
+func (s *Statement) Exec(d database.Database, args ...any) (*Result, error) {
+	if len(args) != len(s.params) {
+		return nil, fmt.Errorf(ErrParamCount, len(s.params))
+	}
+	b := make(binder, len(args))
+	for i, a := range args {
+		v, err := toValue(a)
+		if err != nil {
+			return nil, err
+		}
+		b[s.params[i]] = v
+	}
+	return s.stmt.exec(d, b)
+}
+
+// toValue converts a Go argument to the Value it represents
+func toValue(a any) (value.Value, error) {
+	switch a := a.(type) {
+	case nil:
+		return nil, nil
+	case value.Value:
+		return a, nil
+	case bool:
+		return value.Bool(a), nil
+	case string:
+		return value.String(a), nil
+	case []byte:
+		return value.Key(a), nil
+	case int:
+		return value.Integer(a), nil
+	case int8:
+		return value.Integer(a), nil
+	case int16:
+		return value.Integer(a), nil
+	case int32:
+		return value.Integer(a), nil
+	case int64:
+		return value.Integer(a), nil
+	case uint8:
+		return value.Integer(a), nil
+	case uint16:
+		return value.Integer(a), nil
+	case uint32:
+		return value.Integer(a), nil
+	case float32:
+		return value.Float(a), nil
+	case float64:
+		return value.Float(a), nil
+	default:
+		return nil, fmt.Errorf(ErrUnsupportedType, a)
+	}
+}
+
+// bind returns a copy of the Expr with its placeholders replaced by their
+// arguments
+func (b binder) bind(e *expr.Expr) (*expr.Expr, error) {
+	return rewrite(e, func(e *expr.Expr) (*expr.Expr, error) {
+		if e.Op != expr.LiteralOp {
+			return e, nil
+		}
+		if v, ok := b[e.Value]; ok {
+			return expr.Lit(v), nil
+		}
+		return e, nil
+	})
+}
+
+// rewrite copies the Expr from the bottom up, giving the provided function
+// the chance to replace each of its nodes
+func rewrite(
+	e *expr.Expr, fn func(*expr.Expr) (*expr.Expr, error),
+) (*expr.Expr, error) {
+	if e == nil {
+		return nil, nil
+	}
+	res := *e
+	if len(e.Args) > 0 {
+		res.Args = make([]*expr.Expr, len(e.Args))
+		for i, a := range e.Args {
+			r, err := rewrite(a, fn)
+			if err != nil {
+				return nil, err
+			}
+			res.Args[i] = r
+		}
+	}
+	return fn(&res)
+}
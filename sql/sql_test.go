@@ -0,0 +1,257 @@
+package sql_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/query"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/sql"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(as *assert.Assertions) database.Transactor {
+	d, err := db.NewDatabase()(func(d database.Database) error {
+		for _, s := range []string{
+			`CREATE TABLE users (id, name, age)`,
+			`CREATE UNIQUE INDEX by_id ON users (id)`,
+			`CREATE TABLE orders (user_id, total)`,
+			`CREATE INDEX by_user ON orders (user_id)`,
+			`INSERT INTO users VALUES
+				(1, 'Bill', 17), (2, 'Ted', 18), (3, 'Rufus', 700)`,
+			`INSERT INTO orders (user_id, total) VALUES
+				(1, 10), (1, 20), (2, 5)`,
+		} {
+			if _, err := sql.Exec(d, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	as.Nil(err)
+	return d
+}
+
+func exec(
+	d *database.Transactor, s string, args ...any,
+) (*sql.Result, error) {
+	var res *sql.Result
+	next, err := (*d)(func(d database.Database) error {
+		var err error
+		res, err = sql.Exec(d, s, args...)
+		return err
+	})
+	if err == nil {
+		*d = next
+	}
+	return res, err
+}
+
+func TestSelect(t *testing.T) {
+	as := assert.New(t)
+	d := setup(as)
+
+	res, err := exec(&d, `select * from users where age < ? order by name`, 100)
+	as.Nil(err)
+	as.Equal(column.Names{"id", "name", "age"}, res.Columns)
+	as.Equal([]relation.Relation{
+		{value.Integer(1), value.String("Bill"), value.Integer(17)},
+		{value.Integer(2), value.String("Ted"), value.Integer(18)},
+	}, res.Rows)
+
+	res, err = exec(&d, `
+		SELECT name AS who FROM users
+		WHERE NOT (name = 'Ted') AND age IS NOT NULL
+		ORDER BY who DESC LIMIT ? OFFSET 1 -- skip Rufus`, 5,
+	)
+	as.Nil(err)
+	as.Equal(column.Names{"who"}, res.Columns)
+	as.Equal([]relation.Relation{{value.String("Bill")}}, res.Rows)
+
+	name := "x' OR '1' = '1"
+	res, err = exec(&d, `SELECT id FROM users WHERE name = ?`, name)
+	as.Nil(err)
+	as.Empty(res.Rows)
+}
+
+func TestSelectJoinAndGroup(t *testing.T) {
+	as := assert.New(t)
+	d := setup(as)
+
+	res, err := exec(&d, `
+		SELECT users.name, total FROM users
+		JOIN orders ON users.id = orders.user_id
+		ORDER BY name, total`,
+	)
+	as.Nil(err)
+	as.Equal(column.Names{"users.name", "orders.total"}, res.Columns)
+	as.Equal([]relation.Relation{
+		{value.String("Bill"), value.Integer(10)},
+		{value.String("Bill"), value.Integer(20)},
+		{value.String("Ted"), value.Integer(5)},
+	}, res.Rows)
+
+	res, err = exec(&d, `
+		SELECT name, COUNT(total) AS orders FROM users
+		LEFT JOIN orders ON user_id = id
+		GROUP BY name HAVING count(total) < ? ORDER BY name`, 2,
+	)
+	as.Nil(err)
+	as.Equal(column.Names{"users.name", "orders"}, res.Columns)
+	as.Equal([]relation.Relation{
+		{value.String("Rufus"), value.Integer(0)},
+		{value.String("Ted"), value.Integer(1)},
+	}, res.Rows)
+
+	_, err = exec(&d, `CREATE INDEX big ON orders (total) WHERE total > ?`, 8)
+	as.Nil(err)
+	res, err = exec(&d, `SELECT total FROM orders WHERE total > 15`)
+	as.Nil(err)
+	as.Equal([]relation.Relation{{value.Integer(20)}}, res.Rows)
+
+	_, err = exec(&d, `CREATE INDEX by_name ON users (name) INCLUDE (age)`)
+	as.Nil(err)
+	res, err = exec(&d, `SELECT age FROM users WHERE name = 'Ted'`)
+	as.Nil(err)
+	as.Equal([]relation.Relation{{value.Integer(18)}}, res.Rows)
+
+	res, err = exec(&d,
+		`SELECT total FROM orders WHERE user_id = 1 ORDER BY total DESC`,
+	)
+	as.Nil(err)
+	as.Equal([]relation.Relation{
+		{value.Integer(20)}, {value.Integer(10)},
+	}, res.Rows)
+
+	res, err = exec(&d,
+		`SELECT count(*), count(DISTINCT user_id), max(total) FROM orders`,
+	)
+	as.Nil(err)
+	as.Equal([]relation.Relation{
+		{value.Integer(3), value.Integer(2), value.Integer(20)},
+	}, res.Rows)
+}
+
+func TestMutations(t *testing.T) {
+	as := assert.New(t)
+	d := setup(as)
+
+	stmt, err := sql.Prepare(`UPDATE users SET age = ?, name = ? WHERE id = ?`)
+	as.Nil(err)
+	d, err = d(func(d database.Database) error {
+		res, err := stmt.Exec(d, int64(18), "William", 1)
+		as.Equal(1, res.RowsAffected)
+		return err
+	})
+	as.Nil(err)
+
+	res, err := exec(&d, `SELECT name FROM users WHERE age = 18 ORDER BY id`)
+	as.Nil(err)
+	as.Equal([]relation.Relation{
+		{value.String("William")}, {value.String("Ted")},
+	}, res.Rows)
+
+	res, err = exec(&d, `DELETE FROM users WHERE age >= 18;`)
+	as.Nil(err)
+	as.Equal(3, res.RowsAffected)
+
+	res, err = exec(&d,
+		`INSERT INTO users (id, name) VALUES (4, ?), (5, NULL)`, "Ruth",
+	)
+	as.Nil(err)
+	as.Equal(2, res.RowsAffected)
+	as.Equal(2, len(res.Keys))
+
+	_, err = exec(&d, `CREATE UNIQUE INDEX by_name ON users (LOWER(name))`)
+	as.Nil(err)
+	_, err = exec(&d, `INSERT INTO users (id, name) VALUES (6, 'RUTH')`)
+	as.ErrorContains(err, "unique constraint failed")
+	res, err = exec(&d, `SELECT id FROM users WHERE lower(name) = 'ruth'`)
+	as.Nil(err)
+	as.Equal([]relation.Relation{{value.Integer(4)}}, res.Rows)
+
+	res, err = exec(&d, `SELECT count(*), count(name) FROM users`)
+	as.Nil(err)
+	as.Equal([]relation.Relation{
+		{value.Integer(2), value.Integer(1)},
+	}, res.Rows)
+}
+
+func TestDeleteCascade(t *testing.T) {
+	as := assert.New(t)
+
+	d, err := db.NewDatabase()(func(d database.Database) error {
+		for _, s := range []string{
+			`CREATE TABLE n (id, parent)`,
+			`CREATE UNIQUE INDEX by_id ON n (id)`,
+		} {
+			if _, err := sql.Exec(d, s); err != nil {
+				return err
+			}
+		}
+		tbl, _ := d.Table("n")
+		err := tbl.AddForeignKey(
+			column.Names{"parent"}, "n", "by_id", table.Cascade,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = sql.Exec(d, `INSERT INTO n VALUES (1, NULL), (2, 1)`)
+		return err
+	})
+	as.Nil(err)
+
+	res, err := exec(&d, `DELETE FROM n`)
+	as.Nil(err)
+	as.Equal(2, res.RowsAffected)
+	res, err = exec(&d, `SELECT count(*) FROM n`)
+	as.Nil(err)
+	as.Equal([]relation.Relation{{value.Integer(0)}}, res.Rows)
+}
+
+func TestErrors(t *testing.T) {
+	as := assert.New(t)
+	d := setup(as)
+
+	_, err := sql.Prepare(`SELECT FROM users`)
+	as.EqualError(err, fmt.Sprintf(sql.ErrUnexpectedToken, "FROM"))
+
+	_, err = sql.Prepare(`SELECT * FROM users WHERE name = 'Bill`)
+	as.EqualError(err, fmt.Sprintf(sql.ErrUnterminatedString, 33))
+
+	_, err = sql.Prepare(`SELECT * FROM users WHERE id = #`)
+	as.EqualError(err, fmt.Sprintf(sql.ErrUnexpectedChar, "#"))
+
+	_, err = exec(&d, `SELECT * FROM users WHERE id = ?`)
+	as.EqualError(err, fmt.Sprintf(sql.ErrParamCount, 1))
+
+	_, err = exec(&d, `SELECT * FROM users WHERE id = ?`, struct{}{})
+	as.EqualError(err, fmt.Sprintf(sql.ErrUnsupportedType, struct{}{}))
+
+	_, err = exec(&d, `SELECT * FROM missing`)
+	as.EqualError(err, fmt.Sprintf(query.ErrTableNotFound, "missing"))
+
+	_, err = exec(&d, `INSERT INTO users (id, name) VALUES (1)`)
+	as.EqualError(err, fmt.Sprintf(sql.ErrValueCount, 2))
+
+	_, err = exec(&d, `SELECT * FROM users WHERE count(*) > 1`)
+	as.EqualError(err, fmt.Sprintf(sql.ErrAggregateNotHere, "count()"))
+
+	_, err = exec(&d, `SELECT * FROM users LIMIT -1`)
+	as.EqualError(err, fmt.Sprintf(sql.ErrBadLimit, "-1"))
+
+	_, err = exec(&d, `SELECT users.missing FROM users`)
+	as.EqualError(err,
+		fmt.Sprintf(relation.ErrColumnNotFound, "users.missing"),
+	)
+
+	_, err = exec(&d, `SELECT * FROM users JOIN orders ON id < user_id`)
+	as.EqualError(err,
+		fmt.Sprintf(sql.ErrJoinCondition, "users.id < orders.user_id"),
+	)
+}
@@ -0,0 +1,654 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/caravan/db"
+	"github.com/caravan/db/column"
+	"github.com/caravan/db/database"
+	"github.com/caravan/db/expr"
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/query"
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+type (
+	createTableStmt struct {
+		table   table.Name
+		columns column.Names
+	}
+
+	createIndexStmt struct {
+		unique     bool
+		name       index.Name
+		table      table.Name
+		columns    column.Names
+		descending column.Names
+		computed   index.Computed
+		include    column.Names
+		where      *expr.Expr
+	}
+
+	insertStmt struct {
+		table   table.Name
+		columns column.Names
+		rows    [][]*expr.Expr
+	}
+
+	updateStmt struct {
+		table table.Name
+		sets  []assignment
+		where *expr.Expr
+	}
+
+	deleteStmt struct {
+		table table.Name
+		where *expr.Expr
+	}
+
+	selectStmt struct {
+		items  []selectItem
+		from   table.Name
+		joins  []joinClause
+		where  *expr.Expr
+		groups []*expr.Expr
+		having *expr.Expr
+		orders []orderItem
+		limit  *expr.Expr
+		offset *expr.Expr
+	}
+
+	assignment struct {
+		column column.Name
+		expr   *expr.Expr
+	}
+
+	selectItem struct {
+		expr  *expr.Expr
+		alias column.Name
+		star  bool
+	}
+
+	joinClause struct {
+		typ   query.JoinType
+		table table.Name
+		on    *expr.Expr
+	}
+
+	orderItem struct {
+		expr *expr.Expr
+		desc bool
+	}
+
+	// scope resolves the Column references of a statement against the
+	// Tables it reads from. Once a statement joins Tables, references are
+	// qualified with the name of their Table
+	scope struct {
+		tables  []table.Table
+		joined  bool
+		aliases map[column.Name]column.Name
+	}
+
+	// aggregates collects the Aggregates referenced by a select statement
+	aggregates struct {
+		list  []query.Aggregate
+		names map[column.Name]column.Name
+	}
+)
+
+// Error messages
+const (
+	ErrValueCount       = "wrong number of values, expected: %d"
+	ErrNotColumn        = "expression must be a column reference: %s"
+	ErrStarWithColumns  = "* can't be combined with other columns of: %s"
+	ErrAggregateNotHere = "aggregate functions are not allowed here: %s"
+	ErrAggregateArgs    = "aggregate requires a single column: %s"
+	ErrJoinCondition    = "join condition must equate two columns: %s"
+	ErrBadLimit         = "limit and offset must be non-negative integers: %s"
+)
+
+var aggregateFuncs = map[expr.FuncName]func(column.Name) query.Aggregate{
+	expr.FuncName(query.SumFunc):           query.Sum,
+	expr.FuncName(query.MinFunc):           query.Min,
+	expr.FuncName(query.MaxFunc):           query.Max,
+	expr.FuncName(query.AvgFunc):           query.Avg,
+	expr.FuncName(query.CountDistinctFunc): query.CountDistinct,
+	expr.FuncName(query.CountFunc): func(c column.Name) query.Aggregate {
+		return query.Count(c)
+	},
+}
+
+func (s *createTableStmt) exec(d database.Database, _ binder) (*Result, error) {
+	cols := make(column.Columns, len(s.columns))
+	for i, n := range s.columns {
+		cols[i] = column.Make(n)
+	}
+	if _, err := d.CreateTable(s.table, cols...); err != nil {
+		return nil, err
+	}
+	return &Result{}, nil
+}
+
+func (s *createIndexStmt) exec(d database.Database, b binder) (*Result, error) {
+	tbl, err := lookupTable(d, s.table)
+	if err != nil {
+		return nil, err
+	}
+	var where *expr.Expr
+	if s.where != nil {
+		if where, err = b.bind(s.where); err != nil {
+			return nil, err
+		}
+	}
+	typ := db.StandardIndex
+	if s.unique {
+		typ = db.UniqueIndex
+	}
+	if err := tbl.DefineIndex(index.Definition{
+		Name:       s.name,
+		Type:       typ,
+		Columns:    s.columns,
+		Descending: s.descending,
+		Computed:   s.computed,
+		Include:    s.include,
+		Where:      where,
+	}); err != nil {
+		return nil, err
+	}
+	return &Result{}, nil
+}
+
+func (s *insertStmt) exec(d database.Database, b binder) (*Result, error) {
+	tbl, err := lookupTable(d, s.table)
+	if err != nil {
+		return nil, err
+	}
+	cols := s.columns
+	if len(cols) == 0 {
+		cols = tableColumns(tbl)
+	}
+	off, err := relation.MakeOffsets(tbl.Columns(), cols...)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	for _, r := range s.rows {
+		if len(r) != len(off) {
+			return nil, fmt.Errorf(ErrValueCount, len(off))
+		}
+		row := make(relation.Row, len(tbl.Columns()))
+		for i, e := range r {
+			if row[off[i]], err = constant(e, b); err != nil {
+				return nil, err
+			}
+		}
+		k, err := tbl.InsertAuto(row)
+		if err != nil {
+			return nil, err
+		}
+		res.Keys = append(res.Keys, k)
+		res.RowsAffected++
+	}
+	return res, nil
+}
+
+func (s *updateStmt) exec(d database.Database, b binder) (*Result, error) {
+	tbl, err := lookupTable(d, s.table)
+	if err != nil {
+		return nil, err
+	}
+	sc := &scope{tables: []table.Table{tbl}}
+	names := make(column.Names, len(s.sets))
+	evals := make([]expr.Evaluator, len(s.sets))
+	for i, set := range s.sets {
+		names[i] = set.column
+		e, err := sc.expr(set.expr, b, nil)
+		if err != nil {
+			return nil, err
+		}
+		if evals[i], err = expr.Compile(e, tbl.Columns()); err != nil {
+			return nil, err
+		}
+	}
+	off, err := relation.MakeOffsets(tbl.Columns(), names...)
+	if err != nil {
+		return nil, err
+	}
+	matched, err := sc.matching(d, s.where, b)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range matched.Rows {
+		row := make(relation.Row, len(r))
+		copy(row, r)
+		for j, eval := range evals {
+			if row[off[j]], err = eval(relation.Row(r)); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := tbl.Update(matched.Keys[i], row); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{RowsAffected: len(matched.Rows)}, nil
+}
+
+func (s *deleteStmt) exec(d database.Database, b binder) (*Result, error) {
+	tbl, err := lookupTable(d, s.table)
+	if err != nil {
+		return nil, err
+	}
+	sc := &scope{tables: []table.Table{tbl}}
+	matched, err := sc.matching(d, s.where, b)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range matched.Keys {
+		// A cascading ForeignKey may already have deleted the row, in
+		// which case Delete does nothing
+		if _, err := tbl.Delete(k); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{RowsAffected: len(matched.Keys)}, nil
+}
+
+func (s *selectStmt) exec(d database.Database, b binder) (*Result, error) {
+	sc, err := s.scope(d)
+	if err != nil {
+		return nil, err
+	}
+	q := query.From(s.from)
+	for _, j := range s.joins {
+		on, err := sc.joinOn(j, b)
+		if err != nil {
+			return nil, err
+		}
+		q = q.JoinWith(query.Join{Type: j.typ, Table: j.table, On: on})
+	}
+	if s.where != nil {
+		w, err := sc.expr(s.where, b, nil)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Filter(w)
+	}
+
+	aggs := &aggregates{names: map[column.Name]column.Name{}}
+	sel, aliases, err := s.selected(sc, b, aggs)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := sc.columns(s.groups, b, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) > 0 {
+		q = q.GroupBy(groups...)
+	}
+	if s.having != nil {
+		h, err := sc.expr(s.having, b, aggs)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Having(h)
+	}
+	for _, o := range s.orders {
+		col, err := sc.column(o.expr, b, aggs)
+		if err != nil {
+			return nil, err
+		}
+		if o.desc {
+			q = q.OrderByDesc(col)
+		} else {
+			q = q.OrderBy(col)
+		}
+	}
+	if len(aggs.list) > 0 {
+		q = q.Aggregate(aggs.list...)
+	}
+	if q, err = s.paginate(q, b); err != nil {
+		return nil, err
+	}
+	if len(sel) > 0 {
+		q = q.Select(sel...)
+	}
+
+	res, err := q.Run(d)
+	if err != nil {
+		return nil, err
+	}
+	cols := make(column.Names, len(res.Columns))
+	copy(cols, res.Columns)
+	for i, a := range aliases {
+		if a != "" {
+			cols[i] = a
+		}
+	}
+	return &Result{Columns: cols, Rows: res.Rows}, nil
+}
+
+func (s *selectStmt) scope(d database.Database) (*scope, error) {
+	from, err := lookupTable(d, s.from)
+	if err != nil {
+		return nil, err
+	}
+	res := &scope{
+		tables:  []table.Table{from},
+		joined:  len(s.joins) > 0,
+		aliases: map[column.Name]column.Name{},
+	}
+	for _, j := range s.joins {
+		tbl, err := lookupTable(d, j.table)
+		if err != nil {
+			return nil, err
+		}
+		res.tables = append(res.tables, tbl)
+	}
+	return res, nil
+}
+
+// selected resolves the select list, returning the Columns to project and
+// the names they should be reported under. A lone * selects every Column
+func (s *selectStmt) selected(
+	sc *scope, b binder, aggs *aggregates,
+) (column.Names, column.Names, error) {
+	if len(s.items) == 1 && s.items[0].star {
+		return nil, nil, nil
+	}
+	cols := make(column.Names, len(s.items))
+	aliases := make(column.Names, len(s.items))
+	for i, item := range s.items {
+		if item.star {
+			return nil, nil, fmt.Errorf(ErrStarWithColumns, s.from)
+		}
+		if a, ok, err := sc.aggregate(item.expr, b); err != nil {
+			return nil, nil, err
+		} else if ok {
+			cols[i] = aggs.add(a, item.alias)
+			continue
+		}
+		col, err := sc.column(item.expr, b, aggs)
+		if err != nil {
+			return nil, nil, err
+		}
+		cols[i] = col
+		if item.alias != "" {
+			aliases[i] = item.alias
+			sc.aliases[item.alias] = col
+		}
+	}
+	return cols, aliases, nil
+}
+
+func (s *selectStmt) paginate(q *query.Query, b binder) (*query.Query, error) {
+	if s.limit != nil {
+		n, err := count(s.limit, b)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Limit(n)
+	}
+	if s.offset != nil {
+		n, err := count(s.offset, b)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Offset(n)
+	}
+	return q, nil
+}
+
+// matching returns the Rows of the scope's only Table that satisfy the
+// where clause, along with their Keys
+func (s *scope) matching(
+	d database.Database, where *expr.Expr, b binder,
+) (*query.Result, error) {
+	q := query.From(s.tables[0].Name())
+	if where != nil {
+		w, err := s.expr(where, b, nil)
+		if err != nil {
+			return nil, err
+		}
+		q = q.Filter(w)
+	}
+	return q.Run(d)
+}
+
+// joinOn converts a join's condition into the pairs of Columns it equates
+func (s *scope) joinOn(j joinClause, b binder) ([]query.JoinOn, error) {
+	on, err := s.expr(j.on, b, nil)
+	if err != nil {
+		return nil, err
+	}
+	var res []query.JoinOn
+	pfx := string(j.table) + "."
+	var collect func(e *expr.Expr) error
+	collect = func(e *expr.Expr) error {
+		if e.Op == expr.AndOp {
+			for _, a := range e.Args {
+				if err := collect(a); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if e.Op != expr.EQOp || len(e.Args) != 2 ||
+			e.Args[0].Op != expr.ColumnOp || e.Args[1].Op != expr.ColumnOp {
+			return fmt.Errorf(ErrJoinCondition, e)
+		}
+		l, r := string(e.Args[0].Column), string(e.Args[1].Column)
+		if strings.HasPrefix(l, pfx) && !strings.HasPrefix(r, pfx) {
+			l, r = r, l
+		}
+		if !strings.HasPrefix(r, pfx) || strings.HasPrefix(l, pfx) {
+			return fmt.Errorf(ErrJoinCondition, e)
+		}
+		res = append(res, query.On(
+			column.Name(l), column.Name(strings.TrimPrefix(r, pfx)),
+		))
+		return nil
+	}
+	return res, collect(on)
+}
+
+// expr binds the Expr's placeholders and resolves its Column references.
+// Aggregate calls are replaced by references to the Columns they produce,
+// unless no aggregates are allowed
+func (s *scope) expr(
+	e *expr.Expr, b binder, aggs *aggregates,
+) (*expr.Expr, error) {
+	e, err := b.bind(e)
+	if err != nil {
+		return nil, err
+	}
+	return rewrite(e, func(e *expr.Expr) (*expr.Expr, error) {
+		switch e.Op {
+		case expr.ColumnOp:
+			n, err := s.resolve(e.Column)
+			if err != nil {
+				return nil, err
+			}
+			return expr.Col(n), nil
+		case expr.CallOp:
+			a, ok, err := toAggregate(e)
+			if err != nil || !ok {
+				return e, err
+			}
+			if aggs == nil {
+				return nil, fmt.Errorf(ErrAggregateNotHere, e)
+			}
+			return expr.Col(aggs.add(a, "")), nil
+		default:
+			return e, nil
+		}
+	})
+}
+
+// aggregate reports whether the Expr is an aggregate call, returning it
+// with its Column resolved
+func (s *scope) aggregate(
+	e *expr.Expr, b binder,
+) (query.Aggregate, bool, error) {
+	if e.Op != expr.CallOp {
+		return query.Aggregate{}, false, nil
+	}
+	if _, ok := aggregateFuncs[e.Func]; !ok {
+		return query.Aggregate{}, false, nil
+	}
+	args := make([]*expr.Expr, len(e.Args))
+	for i, a := range e.Args {
+		r, err := s.expr(a, b, nil)
+		if err != nil {
+			return query.Aggregate{}, false, err
+		}
+		args[i] = r
+	}
+	return toAggregate(expr.Call(e.Func, args...))
+}
+
+func (s *scope) column(
+	e *expr.Expr, b binder, aggs *aggregates,
+) (column.Name, error) {
+	r, err := s.expr(e, b, aggs)
+	if err != nil {
+		return "", err
+	}
+	if r.Op != expr.ColumnOp {
+		return "", fmt.Errorf(ErrNotColumn, e)
+	}
+	return r.Column, nil
+}
+
+func (s *scope) columns(
+	e []*expr.Expr, b binder, aggs *aggregates,
+) (column.Names, error) {
+	res := make(column.Names, len(e))
+	for i, c := range e {
+		col, err := s.column(c, b, aggs)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = col
+	}
+	return res, nil
+}
+
+// resolve finds the Column that a name refers to. Names that don't refer
+// to a Table's Column are left as they are, since they may refer to the
+// aliases and aggregates of a select statement
+func (s *scope) resolve(n column.Name) (column.Name, error) {
+	if t, c, ok := strings.Cut(string(n), "."); ok {
+		for _, tbl := range s.tables {
+			if string(tbl.Name()) == t && hasColumn(tbl, column.Name(c)) {
+				return s.qualify(tbl, column.Name(c)), nil
+			}
+		}
+		return "", fmt.Errorf(relation.ErrColumnNotFound, n)
+	}
+	if a, ok := s.aliases[n]; ok {
+		return a, nil
+	}
+	var res column.Name
+	for _, tbl := range s.tables {
+		if !hasColumn(tbl, n) {
+			continue
+		}
+		if res != "" {
+			return "", fmt.Errorf(query.ErrAmbiguousColumn, n)
+		}
+		res = s.qualify(tbl, n)
+	}
+	if res == "" {
+		return n, nil
+	}
+	return res, nil
+}
+
+func (s *scope) qualify(tbl table.Table, n column.Name) column.Name {
+	if !s.joined {
+		return n
+	}
+	return column.Name(string(tbl.Name()) + "." + string(n))
+}
+
+func (a *aggregates) add(agg query.Aggregate, alias column.Name) column.Name {
+	if n, ok := a.names[agg.Name]; ok {
+		return n
+	}
+	def := agg.Name
+	if alias != "" {
+		agg = agg.As(alias)
+	}
+	a.list = append(a.list, agg)
+	a.names[def] = agg.Name
+	return agg.Name
+}
+
+// toAggregate converts a call to an aggregate function, whose arguments
+// have already been resolved, into an Aggregate
+func toAggregate(e *expr.Expr) (query.Aggregate, bool, error) {
+	agg, ok := aggregateFuncs[e.Func]
+	if !ok {
+		return query.Aggregate{}, false, nil
+	}
+	if len(e.Args) == 0 && e.Func == expr.FuncName(query.CountFunc) {
+		return query.Count(), true, nil
+	}
+	if len(e.Args) != 1 || e.Args[0].Op != expr.ColumnOp {
+		return query.Aggregate{}, false, fmt.Errorf(ErrAggregateArgs, e)
+	}
+	return agg(e.Args[0].Column), true, nil
+}
+
+func lookupTable(d database.Database, n table.Name) (table.Table, error) {
+	if tbl, ok := d.Table(n); ok {
+		return tbl, nil
+	}
+	return nil, fmt.Errorf(query.ErrTableNotFound, n)
+}
+
+func tableColumns(tbl table.Table) column.Names {
+	cols := tbl.Columns()
+	res := make(column.Names, len(cols))
+	for i, c := range cols {
+		res[i] = c.Name()
+	}
+	return res
+}
+
+func hasColumn(tbl table.Table, n column.Name) bool {
+	for _, c := range tbl.Columns() {
+		if c.Name() == n {
+			return true
+		}
+	}
+	return false
+}
+
+// constant evaluates an Expr that doesn't refer to any Columns
+func constant(e *expr.Expr, b binder) (value.Value, error) {
+	e, err := b.bind(e)
+	if err != nil {
+		return nil, err
+	}
+	eval, err := expr.Compile(e, nil)
+	if err != nil {
+		return nil, err
+	}
+	return eval(nil)
+}
+
+func count(e *expr.Expr, b binder) (int, error) {
+	v, err := constant(e, b)
+	if err != nil {
+		return 0, err
+	}
+	if n, ok := v.(value.Integer); ok && n >= 0 {
+		return int(n), nil
+	}
+	return 0, fmt.Errorf(ErrBadLimit, e)
+}
@@ -0,0 +1,36 @@
+package table
+
+import (
+	"fmt"
+
+	"github.com/caravan/db/expr"
+)
+
+type (
+	// CheckName identifies a Check
+	CheckName string
+
+	// Check is the serializable description of a row-level invariant. A
+	// row violates the Check if its Expr evaluates to false. A null result
+	// does not violate the Check
+	Check struct {
+		Name CheckName  `json:"name"`
+		Expr *expr.Expr `json:"expr"`
+	}
+
+	// Checks are a set of Check
+	Checks []Check
+
+	// CheckViolation is the error returned when a row violates a Check
+	CheckViolation struct {
+		Table Name
+		Check CheckName
+	}
+)
+
+// ErrCheckViolation is the message reported by a CheckViolation
+const ErrCheckViolation = "check constraint failed in table %s: %s"
+
+func (e *CheckViolation) Error() string {
+	return fmt.Sprintf(ErrCheckViolation, e.Table, e.Check)
+}
@@ -0,0 +1,31 @@
+package table
+
+import "github.com/caravan/db/column"
+
+type (
+	// KeyType identifies how a Table generates Keys for InsertAuto
+	KeyType string
+
+	// KeyStrategy is the serializable description of how a Table generates
+	// Keys for InsertAuto. Only CompositeKeys makes use of Columns
+	KeyStrategy struct {
+		Type    KeyType      `json:"type"`
+		Columns column.Names `json:"columns,omitempty"`
+	}
+)
+
+// Key generation strategies
+const (
+	// RandomKeys generates random, unordered Keys, as value.NewKey does
+	RandomKeys KeyType = "random"
+
+	// SequenceKeys generates monotonically increasing integer Keys
+	SequenceKeys KeyType = "sequence"
+
+	// ULIDKeys generates unique Keys that are ordered by creation time
+	ULIDKeys KeyType = "ulid"
+
+	// CompositeKeys derives Keys from the values of a row's Columns, which
+	// can't be changed by an update
+	CompositeKeys KeyType = "composite"
+)
@@ -1,9 +1,13 @@
 package table
 
 import (
+	"iter"
+
 	"github.com/caravan/db/column"
+	"github.com/caravan/db/expr"
 	"github.com/caravan/db/index"
 	"github.com/caravan/db/relation"
+	"github.com/caravan/db/transaction"
 	"github.com/caravan/db/value"
 )
 
@@ -14,20 +18,84 @@ type (
 	// Names are a set of Name
 	Names []Name
 
+	// Definition is the serializable description of a Table, its Columns,
+	// its KeyStrategy, its Indexes, and its constraints
+	Definition struct {
+		Name        Name              `json:"name"`
+		Columns     column.Names      `json:"columns"`
+		Types       column.Types      `json:"types,omitempty"`
+		Keys        KeyStrategy       `json:"keys"`
+		Indexes     index.Definitions `json:"indexes,omitempty"`
+		ForeignKeys ForeignKeys       `json:"foreignKeys,omitempty"`
+		Checks      Checks            `json:"checks,omitempty"`
+	}
+
+	// Definitions are a set of Definition
+	Definitions []Definition
+
+	// Action determines what happens to the rows that reference a parent
+	// row through a ForeignKey when that parent row is deleted
+	Action string
+
+	// ForeignKey is the serializable description of a reference from a
+	// Table's Columns to a tuple in a parent Table's unique Index. The
+	// referenced tuple of a parent row can't be updated while it is
+	// still referenced
+	ForeignKey struct {
+		Columns  column.Names `json:"columns"`
+		Table    Name         `json:"table"`
+		Index    index.Name   `json:"index"`
+		OnDelete Action       `json:"onDelete"`
+	}
+
+	// ForeignKeys are a set of ForeignKey
+	ForeignKeys []ForeignKey
+
 	// Table is an interface that associates a Key with a Row and provides
 	// additional capabilities around this association
 	Table interface {
 		Name() Name
 		Columns() column.Columns
+		AddColumn(column.Column, value.Value) error
+		DropColumn(column.Name) error
+		RenameColumn(from, to column.Name) error
 
 		Indexes() index.Names
-		CreateIndex(index.Type, index.Name, ...column.Name) error
+		IndexInfo(index.Name) (index.Definition, bool)
+		Index(index.Name) (index.Query, error)
+		CreateIndex(index.TypeName, index.Name, ...column.Name) error
+		DefineIndex(index.Definition) error
+
+		AddForeignKey(column.Names, Name, index.Name, Action) error
+		AddCheck(CheckName, *expr.Expr) error
+
+		KeyStrategy() KeyStrategy
+		SetKeyStrategy(KeyStrategy) error
 
 		Insert(value.Key, relation.Row) error
+		InsertAuto(relation.Row) (value.Key, error)
 		Update(value.Key, relation.Row) (relation.Row, error)
-		Delete(value.Key) (relation.Row, bool)
-		Truncate()
+		Delete(value.Key) (relation.Row, error)
+		Truncate() error
 
 		Select(value.Key) (relation.Row, bool)
+		Rows() transaction.Iterator
+		RowsAfter(value.Key) transaction.Iterator
+		Scan() iter.Seq2[value.Key, relation.Row]
+		Lookup(transaction.Iterator) iter.Seq2[value.Key, relation.Row]
+		Count() int
 	}
 )
+
+// Actions that can be performed when a referenced row is deleted
+const (
+	// Restrict refuses to delete a row that is still referenced
+	Restrict Action = "restrict"
+
+	// Cascade deletes the rows that reference a deleted row
+	Cascade Action = "cascade"
+
+	// SetNull clears the referencing Columns of the rows that reference a
+	// deleted row
+	SetNull Action = "set null"
+)
@@ -0,0 +1,139 @@
+// Package text provides the analysis and search interfaces of full-text
+// Indexes. An Analyzer splits a string into Tokens, and a Searcher finds
+// and ranks the rows whose indexed Columns contain them
+package text
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type (
+	// AnalyzerName identifies a registered Analyzer
+	AnalyzerName string
+
+	// Token is a term produced by an Analyzer, along with its position in
+	// the analyzed string. Positions of Tokens that were removed by a
+	// Filter are skipped, so that phrases don't match across them
+	Token struct {
+		Term     string
+		Position int
+	}
+
+	// Analyzer splits a string into the Tokens that are indexed or
+	// searched for
+	Analyzer func(string) []Token
+
+	// Filter transforms a Token, or reports that it should be dropped
+	Filter func(Token) (Token, bool)
+)
+
+// Registered AnalyzerNames
+const (
+	// StandardAnalyzer lowercases, removes stop words, and stems
+	StandardAnalyzer AnalyzerName = "standard"
+
+	// SimpleAnalyzer only lowercases
+	SimpleAnalyzer AnalyzerName = "simple"
+)
+
+// AnalyzerOption is the index.Options entry that names the Analyzer of a
+// full-text Index. The StandardAnalyzer is used if it isn't provided
+const AnalyzerOption = "analyzer"
+
+// Error messages
+const (
+	ErrAnalyzerAlreadyRegistered = "analyzer already registered: %s"
+	ErrAnalyzerNotRegistered     = "analyzer not registered: %s"
+)
+
+// StopWords are the common English words removed by the StandardAnalyzer
+var StopWords = []string{
+	"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "if",
+	"in", "into", "is", "it", "no", "not", "of", "on", "or", "such",
+	"that", "the", "their", "then", "there", "these", "they", "this",
+	"to", "was", "will", "with",
+}
+
+var analyzers = struct {
+	sync.RWMutex
+	registered map[AnalyzerName]Analyzer
+}{
+	registered: map[AnalyzerName]Analyzer{
+		StandardAnalyzer: NewAnalyzer(Lowercase, Stop(StopWords...), Stem),
+		SimpleAnalyzer:   NewAnalyzer(Lowercase),
+	},
+}
+
+// RegisterAnalyzer associates an Analyzer with the provided AnalyzerName
+// so that full-text Indexes can refer to it
+func RegisterAnalyzer(n AnalyzerName, a Analyzer) error {
+	analyzers.Lock()
+	defer analyzers.Unlock()
+	if _, ok := analyzers.registered[n]; ok {
+		return fmt.Errorf(ErrAnalyzerAlreadyRegistered, n)
+	}
+	analyzers.registered[n] = a
+	return nil
+}
+
+// LookupAnalyzer returns the Analyzer that was registered with the
+// provided AnalyzerName
+func LookupAnalyzer(n AnalyzerName) (Analyzer, error) {
+	analyzers.RLock()
+	defer analyzers.RUnlock()
+	if a, ok := analyzers.registered[n]; ok {
+		return a, nil
+	}
+	return nil, fmt.Errorf(ErrAnalyzerNotRegistered, n)
+}
+
+// NewAnalyzer returns an Analyzer that splits a string into words made of
+// letters and digits, and passes each of them through the Filters in turn
+func NewAnalyzer(filters ...Filter) Analyzer {
+	return func(s string) []Token {
+		var res []Token
+		words := strings.FieldsFunc(s, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+	next:
+		for pos, w := range words {
+			t := Token{Term: w, Position: pos}
+			for _, f := range filters {
+				var ok bool
+				if t, ok = f(t); !ok {
+					continue next
+				}
+			}
+			res = append(res, t)
+		}
+		return res
+	}
+}
+
+// Lowercase is a Filter that converts a Token's Term to lower case
+func Lowercase(t Token) (Token, bool) {
+	t.Term = strings.ToLower(t.Term)
+	return t, true
+}
+
+// Stop returns a Filter that drops the Tokens whose Term is one of the
+// provided words
+func Stop(words ...string) Filter {
+	stop := make(map[string]bool, len(words))
+	for _, w := range words {
+		stop[w] = true
+	}
+	return func(t Token) (Token, bool) {
+		return t, !stop[t.Term]
+	}
+}
+
+// Stem is a Filter that reduces a Token's Term to its English stem, so
+// that "connected" and "connecting" both become "connect"
+func Stem(t Token) (Token, bool) {
+	t.Term = stem(t.Term)
+	return t, true
+}
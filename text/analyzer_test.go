@@ -0,0 +1,67 @@
+package text_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/caravan/db/text"
+	"github.com/stretchr/testify/assert"
+)
+
+func terms(tokens []text.Token) []string {
+	res := make([]string, len(tokens))
+	for i, t := range tokens {
+		res[i] = t.Term
+	}
+	return res
+}
+
+func TestStandardAnalyzer(t *testing.T) {
+	as := assert.New(t)
+
+	a, err := text.LookupAnalyzer(text.StandardAnalyzer)
+	as.Nil(err)
+	tokens := a("The Connected, connecting CONNECTIONS of a network")
+	as.Equal([]string{
+		"connect", "connect", "connect", "network",
+	}, terms(tokens))
+	as.Equal(1, tokens[0].Position)
+	as.Equal(6, tokens[3].Position)
+
+	as.Equal([]string{
+		"caress", "poni", "relat", "hope", "gener", "adjust", "café",
+	}, terms(a("caresses ponies relational hopeful generalization "+
+		"adjustment café"),
+	))
+}
+
+func TestSimpleAnalyzer(t *testing.T) {
+	as := assert.New(t)
+
+	a, err := text.LookupAnalyzer(text.SimpleAnalyzer)
+	as.Nil(err)
+	as.Equal([]string{"the", "connected", "things", "42"},
+		terms(a("The Connected-Things: 42")),
+	)
+}
+
+func TestRegisterAnalyzer(t *testing.T) {
+	as := assert.New(t)
+
+	upper := text.NewAnalyzer(func(t text.Token) (text.Token, bool) {
+		t.Term = strings.ToUpper(t.Term)
+		return t, true
+	})
+	as.Nil(text.RegisterAnalyzer("upper", upper))
+	as.EqualError(text.RegisterAnalyzer("upper", upper),
+		fmt.Sprintf(text.ErrAnalyzerAlreadyRegistered, "upper"),
+	)
+
+	a, err := text.LookupAnalyzer("upper")
+	as.Nil(err)
+	as.Equal([]string{"HELLO"}, terms(a("hello")))
+
+	_, err = text.LookupAnalyzer("missing")
+	as.EqualError(err, fmt.Sprintf(text.ErrAnalyzerNotRegistered, "missing"))
+}
@@ -0,0 +1,70 @@
+package text
+
+import (
+	"fmt"
+
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Searcher is implemented by full-text Indexes. Each of its methods
+	// finds the rows whose indexed Columns contain the searched text, and
+	// ranks them by their BM25 score, highest first
+	Searcher interface {
+		// Match finds the rows that contain any of the Terms of the
+		// analyzed text
+		Match(string) Matches
+
+		// Term finds the rows that contain every Term of the analyzed
+		// text, in any order
+		Term(string) Matches
+
+		// Phrase finds the rows that contain the Terms of the analyzed
+		// text, one after the other
+		Phrase(string) Matches
+
+		// StartsWith finds the rows that contain a Term starting with
+		// the provided text, after it has been converted to lower case.
+		// The text isn't otherwise analyzed, so it's matched against the
+		// Terms as the Index stored them: "connect" finds "connecting"
+		// under the StandardAnalyzer, but "connecti" doesn't
+		StartsWith(string) Matches
+	}
+
+	// Match is a row found by a Searcher, identified by its Key
+	Match struct {
+		Key   value.Key
+		Score float64
+	}
+
+	// Matches are a set of Match
+	Matches []Match
+)
+
+// Error messages
+const (
+	ErrNotSearchable = "index is not a full-text index: %s"
+)
+
+// Search returns the Searcher of a Table's full-text Index
+func Search(tbl table.Table, n index.Name) (Searcher, error) {
+	idx, err := tbl.Index(n)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := idx.(Searcher); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf(ErrNotSearchable, n)
+}
+
+// Keys returns the Keys of the Matches, in order
+func (m Matches) Keys() []value.Key {
+	res := make([]value.Key, len(m))
+	for i, e := range m {
+		res[i] = e.Key
+	}
+	return res
+}
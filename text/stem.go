@@ -0,0 +1,225 @@
+package text
+
+import "strings"
+
+type (
+	// stemmer applies the Porter stemming algorithm to a lowercase ASCII
+	// word. Words containing any other characters are left untouched
+	stemmer struct {
+		b []byte
+	}
+
+	rule struct {
+		suffix, replacement string
+	}
+)
+
+var step2Rules = []rule{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"},
+	{"anci", "ance"}, {"izer", "ize"}, {"bli", "ble"}, {"alli", "al"},
+	{"entli", "ent"}, {"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"},
+	{"ation", "ate"}, {"ator", "ate"}, {"alism", "al"},
+	{"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"},
+	{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}, {"logi", "log"},
+}
+
+var step3Rules = []rule{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement",
+	"ment", "ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func stem(w string) string {
+	if len(w) <= 2 {
+		return w
+	}
+	for i := 0; i < len(w); i++ {
+		if w[i] < 'a' || w[i] > 'z' {
+			return w
+		}
+	}
+	s := &stemmer{b: []byte(w)}
+	s.step1()
+	s.step2()
+	s.step3()
+	s.step4()
+	s.step5()
+	return string(s.b)
+}
+
+// consonant returns whether the letter at i is a consonant. A 'y' is a
+// consonant when it starts the word or follows a vowel
+func (s *stemmer) consonant(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.consonant(i-1)
+	default:
+		return true
+	}
+}
+
+// measure counts the vowel-consonant sequences in the first n letters
+func (s *stemmer) measure(n int) int {
+	res := 0
+	i := 0
+	for i < n && s.consonant(i) {
+		i++
+	}
+	for i < n {
+		for i < n && !s.consonant(i) {
+			i++
+		}
+		if i == n {
+			break
+		}
+		res++
+		for i < n && s.consonant(i) {
+			i++
+		}
+	}
+	return res
+}
+
+func (s *stemmer) hasVowel(n int) bool {
+	for i := 0; i < n; i++ {
+		if !s.consonant(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleConsonant returns whether the first n letters end with a double
+// consonant
+func (s *stemmer) doubleConsonant(n int) bool {
+	return n >= 2 && s.b[n-1] == s.b[n-2] && s.consonant(n-1)
+}
+
+// cvc returns whether the first n letters end with consonant, vowel,
+// consonant, where the last consonant isn't a 'w', 'x', or 'y'
+func (s *stemmer) cvc(n int) bool {
+	if n < 3 || !s.consonant(n-1) || s.consonant(n-2) || !s.consonant(n-3) {
+		return false
+	}
+	switch s.b[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *stemmer) endsWith(suffix string) bool {
+	return strings.HasSuffix(string(s.b), suffix)
+}
+
+// stemLen returns the length of the word before the suffix
+func (s *stemmer) stemLen(suffix string) int {
+	return len(s.b) - len(suffix)
+}
+
+func (s *stemmer) replace(suffix, replacement string) {
+	s.b = append(s.b[:s.stemLen(suffix)], replacement...)
+}
+
+// applyRules replaces the first matching suffix if the measure of what
+// precedes it is greater than min
+func (s *stemmer) applyRules(rules []rule, min int) {
+	for _, r := range rules {
+		if s.endsWith(r.suffix) {
+			if s.measure(s.stemLen(r.suffix)) > min {
+				s.replace(r.suffix, r.replacement)
+			}
+			return
+		}
+	}
+}
+
+func (s *stemmer) step1() {
+	switch {
+	case s.endsWith("sses"):
+		s.replace("sses", "ss")
+	case s.endsWith("ies"):
+		s.replace("ies", "i")
+	case s.endsWith("ss"):
+	case s.endsWith("s"):
+		s.replace("s", "")
+	}
+
+	switch {
+	case s.endsWith("eed"):
+		if s.measure(s.stemLen("eed")) > 0 {
+			s.replace("eed", "ee")
+		}
+	case s.endsWith("ed") && s.hasVowel(s.stemLen("ed")):
+		s.replace("ed", "")
+		s.step1b()
+	case s.endsWith("ing") && s.hasVowel(s.stemLen("ing")):
+		s.replace("ing", "")
+		s.step1b()
+	}
+
+	if s.endsWith("y") && s.hasVowel(s.stemLen("y")) {
+		s.replace("y", "i")
+	}
+}
+
+func (s *stemmer) step1b() {
+	n := len(s.b)
+	switch {
+	case s.endsWith("at"), s.endsWith("bl"), s.endsWith("iz"):
+		s.b = append(s.b, 'e')
+	case s.doubleConsonant(n):
+		switch s.b[n-1] {
+		case 'l', 's', 'z':
+		default:
+			s.b = s.b[:n-1]
+		}
+	case s.measure(n) == 1 && s.cvc(n):
+		s.b = append(s.b, 'e')
+	}
+}
+
+func (s *stemmer) step2() {
+	s.applyRules(step2Rules, 0)
+}
+
+func (s *stemmer) step3() {
+	s.applyRules(step3Rules, 0)
+}
+
+func (s *stemmer) step4() {
+	for _, suffix := range step4Suffixes {
+		if !s.endsWith(suffix) {
+			continue
+		}
+		n := s.stemLen(suffix)
+		if suffix == "ion" && (n == 0 || s.b[n-1] != 's' && s.b[n-1] != 't') {
+			continue
+		}
+		if s.measure(n) > 1 {
+			s.b = s.b[:n]
+		}
+		return
+	}
+}
+
+func (s *stemmer) step5() {
+	n := len(s.b)
+	if s.b[n-1] == 'e' {
+		m := s.measure(n - 1)
+		if m > 1 || m == 1 && !s.cvc(n-1) {
+			s.b = s.b[:n-1]
+			n--
+		}
+	}
+	if s.b[n-1] == 'l' && s.doubleConsonant(n) && s.measure(n) > 1 {
+		s.b = s.b[:n-1]
+	}
+}
@@ -0,0 +1,56 @@
+package transaction
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/caravan/db/value"
+)
+
+// Cursor marks the last Key reported by an Iterator so that a later
+// iteration can resume after it. Resuming is purely Key-based: the later
+// iteration reads the current state of the Database, so it includes
+// changes that follow the Key and misses changes that precede it. The
+// Version identifies the state of the Database that the Cursor was
+// produced from, allowing a client to detect such changes with Stale
+type Cursor struct {
+	Key     value.Key
+	Version uint64
+}
+
+// Error messages
+const (
+	ErrInvalidCursor = "invalid cursor: %s"
+)
+
+var cursorEncoding = base64.RawURLEncoding
+
+// String returns an opaque token for the Cursor that can be handed to a
+// client and later parsed with ParseCursor
+func (c Cursor) String() string {
+	buf := binary.AppendUvarint(nil, c.Version)
+	return cursorEncoding.EncodeToString(append(buf, c.Key...))
+}
+
+// ParseCursor parses a token that was produced by a Cursor
+func ParseCursor(s string) (Cursor, error) {
+	buf, err := cursorEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf(ErrInvalidCursor, s)
+	}
+	version, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return Cursor{}, fmt.Errorf(ErrInvalidCursor, s)
+	}
+	return Cursor{
+		Key:     value.Key(buf[n:]),
+		Version: version,
+	}, nil
+}
+
+// Stale returns whether the Database has changed since the Cursor was
+// produced, given the Database's current Version
+func (c Cursor) Stale(version uint64) bool {
+	return c.Version != version
+}
@@ -0,0 +1,216 @@
+package iterate
+
+import (
+	"bytes"
+
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Mapper is called to convert the value of a key/value pair
+	Mapper func(value.Key, any) any
+
+	// Pair is a key/value pair that has been gathered from an Iterator
+	Pair struct {
+		Key   value.Key
+		Value any
+	}
+)
+
+// Filter returns an Iterator that reports only the pairs satisfying the
+// provided Predicate
+func Filter(iter transaction.Iterator, fn Predicate) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		for k, v, next, ok := iter(); ok; k, v, next, ok = next() {
+			if fn(k, v) {
+				return k, v, Filter(next, fn), true
+			}
+		}
+		return nil, nil, nil, false
+	}
+}
+
+// Map returns an Iterator that reports the pairs of another Iterator with
+// their values converted by the provided Mapper
+func Map(iter transaction.Iterator, fn Mapper) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		if k, v, next, ok := iter(); ok {
+			return k, fn(k, v), Map(next, fn), true
+		}
+		return nil, nil, nil, false
+	}
+}
+
+// Take returns an Iterator that reports at most n pairs
+func Take(iter transaction.Iterator, n int) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		if n <= 0 {
+			return nil, nil, nil, false
+		}
+		if k, v, next, ok := iter(); ok {
+			return k, v, Take(next, n-1), true
+		}
+		return nil, nil, nil, false
+	}
+}
+
+// Skip returns an Iterator that passes over the first n pairs
+func Skip(iter transaction.Iterator, n int) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		k, v, next, ok := iter()
+		for i := 0; ok && i < n; i++ {
+			k, v, next, ok = next()
+		}
+		return k, v, next, ok
+	}
+}
+
+// Concat returns an Iterator that reports the pairs of each Iterator in
+// turn
+func Concat(iters ...transaction.Iterator) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		for i, iter := range iters {
+			if k, v, next, ok := iter(); ok {
+				rest := append([]transaction.Iterator{next}, iters[i+1:]...)
+				return k, v, Concat(rest...), true
+			}
+		}
+		return nil, nil, nil, false
+	}
+}
+
+// Merge returns the ordered union of two Iterators that are ordered by
+// Key. When both report the same Key, only the left pair is reported
+func Merge(l, r transaction.Iterator) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		lk, lv, ln, lok := l()
+		rk, rv, rn, rok := r()
+		switch {
+		case !lok && !rok:
+			return nil, nil, nil, false
+		case !rok:
+			return lk, lv, ln, true
+		case !lok:
+			return rk, rv, rn, true
+		}
+		switch bytes.Compare(lk, rk) {
+		case -1:
+			return lk, lv, Merge(ln, r), true
+		case 1:
+			return rk, rv, Merge(l, rn), true
+		default:
+			return lk, lv, Merge(ln, rn), true
+		}
+	}
+}
+
+// Intersect returns an Iterator that reports the left pairs whose Keys
+// are also reported by the right. Both Iterators must be ordered by Key
+func Intersect(l, r transaction.Iterator) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		lk, lv, ln, lok := l()
+		rk, _, rn, rok := r()
+		for lok && rok {
+			switch bytes.Compare(lk, rk) {
+			case -1:
+				lk, lv, ln, lok = ln()
+			case 1:
+				rk, _, rn, rok = rn()
+			default:
+				return lk, lv, Intersect(ln, rn), true
+			}
+		}
+		return nil, nil, nil, false
+	}
+}
+
+// Difference returns an Iterator that reports the left pairs whose Keys
+// aren't reported by the right. Both Iterators must be ordered by Key
+func Difference(l, r transaction.Iterator) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		lk, lv, ln, lok := l()
+		rk, _, rn, rok := r()
+		for lok && rok {
+			switch bytes.Compare(lk, rk) {
+			case -1:
+				return lk, lv, Difference(ln, r), true
+			case 1:
+				r = rn
+				rk, _, rn, rok = rn()
+			default:
+				l = ln
+				lk, lv, ln, lok = ln()
+			}
+		}
+		return lk, lv, ln, lok
+	}
+}
+
+// Distinct returns an Iterator that passes over any pair whose Key is the
+// same as the Key of the pair before it. For an Iterator that is ordered
+// by Key, this removes all duplicate Keys
+func Distinct(iter transaction.Iterator) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		if k, v, next, ok := iter(); ok {
+			return k, v, Distinct(skipKey(next, k)), true
+		}
+		return nil, nil, nil, false
+	}
+}
+
+func skipKey(iter transaction.Iterator, key value.Key) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		k, v, next, ok := iter()
+		for ok && bytes.Equal(k, key) {
+			k, v, next, ok = next()
+		}
+		return k, v, next, ok
+	}
+}
+
+// Chunk returns an Iterator that gathers the pairs of another into groups
+// of at most n Pairs. Each group is reported with the Key of its first
+// Pair, and the group itself as a []Pair
+func Chunk(iter transaction.Iterator, n int) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		var chunk []Pair
+		rest := iter
+		for len(chunk) < n {
+			k, v, next, ok := rest()
+			if !ok {
+				break
+			}
+			chunk = append(chunk, Pair{Key: k, Value: v})
+			rest = next
+		}
+		if len(chunk) == 0 {
+			return nil, nil, nil, false
+		}
+		return chunk[0].Key, chunk, Chunk(rest, n), true
+	}
+}
+
+// Collect gathers the pairs of an Iterator into a slice
+func Collect(iter transaction.Iterator) []Pair {
+	var res []Pair
+	for k, v, next, ok := iter(); ok; k, v, next, ok = next() {
+		res = append(res, Pair{Key: k, Value: v})
+	}
+	return res
+}
+
+// First returns the first pair of an Iterator, if there is one
+func First(iter transaction.Iterator) (value.Key, any, bool) {
+	k, v, _, ok := iter()
+	return k, v, ok
+}
+
+// Count returns the number of pairs that an Iterator reports
+func Count(iter transaction.Iterator) int {
+	res := 0
+	for _, _, next, ok := iter(); ok; _, _, next, ok = next() {
+		res++
+	}
+	return res
+}
@@ -0,0 +1,91 @@
+package iterate_test
+
+import (
+	"testing"
+
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/transaction/iterate"
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeKeyed(keys ...byte) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		if len(keys) == 0 {
+			return nil, nil, nil, false
+		}
+		return value.Key{keys[0]}, int(keys[0]), makeKeyed(keys[1:]...), true
+	}
+}
+
+func values(iter transaction.Iterator) []any {
+	res := []any{}
+	for _, p := range iterate.Collect(iter) {
+		res = append(res, p.Value)
+	}
+	return res
+}
+
+func TestFilterMapTakeSkip(t *testing.T) {
+	as := assert.New(t)
+
+	even := iterate.Filter(makeSequence(0), func(_ value.Key, v any) bool {
+		return v.(int)%2 == 0
+	})
+	doubled := iterate.Map(even, func(_ value.Key, v any) any {
+		return v.(int) * 2
+	})
+	iter := iterate.Take(iterate.Skip(doubled, 1), 3)
+	as.Equal([]any{4, 8, 12}, values(iter))
+	as.Equal([]any{4, 8, 12}, values(iter))
+	as.Equal(3, iterate.Count(iter))
+
+	as.Equal([]any{}, values(iterate.Take(makeSequence(0), 0)))
+	as.Equal([]any{}, values(iterate.Skip(makeKeyed(1, 2), 5)))
+
+	_, v, ok := iterate.First(iter)
+	as.True(ok)
+	as.Equal(4, v)
+	_, _, ok = iterate.First(makeKeyed())
+	as.False(ok)
+}
+
+func TestConcat(t *testing.T) {
+	as := assert.New(t)
+
+	iter := iterate.Concat(makeKeyed(1, 2), makeKeyed(), makeKeyed(3))
+	as.Equal([]any{1, 2, 3}, values(iter))
+	as.Equal([]any{}, values(iterate.Concat()))
+}
+
+func TestSetOperations(t *testing.T) {
+	as := assert.New(t)
+
+	l := makeKeyed(1, 3, 5, 7)
+	r := makeKeyed(2, 3, 4, 7, 9)
+	as.Equal([]any{1, 2, 3, 4, 5, 7, 9}, values(iterate.Merge(l, r)))
+	as.Equal([]any{3, 7}, values(iterate.Intersect(l, r)))
+	as.Equal([]any{1, 5}, values(iterate.Difference(l, r)))
+	as.Equal([]any{2, 4, 9}, values(iterate.Difference(r, l)))
+	as.Equal([]any{}, values(iterate.Intersect(l, makeKeyed())))
+	as.Equal([]any{1, 3, 5, 7}, values(iterate.Difference(l, makeKeyed())))
+
+	dupes := makeKeyed(1, 1, 2, 3, 3, 3, 4)
+	as.Equal([]any{1, 2, 3, 4}, values(iterate.Distinct(dupes)))
+}
+
+func TestChunk(t *testing.T) {
+	as := assert.New(t)
+
+	chunks := iterate.Collect(iterate.Chunk(makeKeyed(1, 2, 3, 4, 5), 2))
+	as.Equal(3, len(chunks))
+	as.Equal(value.Key{3}, chunks[1].Key)
+	as.Equal([]iterate.Pair{
+		{Key: value.Key{3}, Value: 3}, {Key: value.Key{4}, Value: 4},
+	}, chunks[1].Value)
+	as.Equal([]iterate.Pair{
+		{Key: value.Key{5}, Value: 5},
+	}, chunks[2].Value)
+
+	as.Equal(0, iterate.Count(iterate.Chunk(makeKeyed(1), 0)))
+}
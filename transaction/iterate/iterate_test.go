@@ -4,6 +4,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/caravan/db/relation"
 	"github.com/caravan/db/transaction"
 	"github.com/caravan/db/transaction/iterate"
 	"github.com/caravan/db/value"
@@ -59,3 +60,51 @@ func TestWhile(t *testing.T) {
 	as.Nil(v)
 	as.Nil(next)
 }
+
+func TestSeq(t *testing.T) {
+	as := assert.New(t)
+
+	var res []any
+	for _, v := range iterate.Seq(makeSequence(0)) {
+		if v.(int) > 3 {
+			break
+		}
+		res = append(res, v)
+	}
+	as.Equal([]any{0, 1, 2, 3}, res)
+
+	iter := iterate.FromSeq(iterate.Seq(
+		iterate.While(makeSequence(5), func(_ value.Key, v any) bool {
+			return v.(int) < 7
+		}),
+	))
+	_, v, next, ok := iter()
+	as.True(ok)
+	as.Equal(5, v)
+	_, v, _, ok = next()
+	as.True(ok)
+	as.Equal(6, v)
+	_, v, next, _ = next()
+	as.Equal(6, v)
+	_, _, _, ok = next()
+	as.False(ok)
+}
+
+func TestRows(t *testing.T) {
+	as := assert.New(t)
+
+	rows := []relation.Row{{value.Integer(1)}, {value.Integer(2)}}
+	iter := iterate.FromSeq(func(yield func(value.Key, any) bool) {
+		for i, r := range rows {
+			if !yield(value.Key{byte(i)}, r) {
+				return
+			}
+		}
+	})
+	var res []relation.Row
+	for k, r := range iterate.Rows(iter) {
+		as.Equal(value.Key{byte(len(res))}, k)
+		res = append(res, r)
+	}
+	as.Equal(rows, res)
+}
@@ -0,0 +1,81 @@
+package iterate
+
+import (
+	"bytes"
+
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// after is a view of an ascending Iterable that only reports pairs
+	// whose Keys follow a Key
+	after struct {
+		transaction.Iterable
+		key value.Key
+	}
+
+	// before is a view of a descending Iterable that only reports pairs
+	// whose Keys precede a Key
+	before struct {
+		transaction.Iterable
+		key value.Key
+	}
+)
+
+// After returns a view of an ascending Iterable that only reports the
+// pairs whose Keys follow the provided Key. Because the view seeks to its
+// Key, resuming an iteration this way doesn't revisit earlier pairs
+func After(it transaction.Iterable, k value.Key) transaction.Iterable {
+	return &after{Iterable: it, key: k}
+}
+
+func (a *after) All() transaction.Iterator {
+	return skipKey(a.Iterable.From(a.key), a.key)
+}
+
+func (a *after) From(k value.Key) transaction.Iterator {
+	if bytes.Compare(k, a.key) <= 0 {
+		return a.All()
+	}
+	return a.Iterable.From(k)
+}
+
+// Before returns a view of a descending Iterable that only reports the
+// pairs whose Keys precede the provided Key
+func Before(it transaction.Iterable, k value.Key) transaction.Iterable {
+	return &before{Iterable: it, key: k}
+}
+
+func (b *before) All() transaction.Iterator {
+	return skipKey(b.Iterable.From(b.key), b.key)
+}
+
+func (b *before) From(k value.Key) transaction.Iterator {
+	if bytes.Compare(k, b.key) >= 0 {
+		return b.All()
+	}
+	return b.Iterable.From(k)
+}
+
+// Page gathers at most n pairs from an Iterator. If more pairs remain, a
+// Cursor is returned that identifies the last pair of the page and the
+// provided Database version
+func Page(
+	iter transaction.Iterator, n int, version uint64,
+) ([]Pair, *transaction.Cursor) {
+	if n <= 0 {
+		return nil, nil
+	}
+	var res []Pair
+	for k, v, next, ok := iter(); ok; k, v, next, ok = next() {
+		if len(res) == n {
+			return res, &transaction.Cursor{
+				Key:     res[n-1].Key,
+				Version: version,
+			}
+		}
+		res = append(res, Pair{Key: k, Value: v})
+	}
+	return res, nil
+}
@@ -0,0 +1,55 @@
+package iterate
+
+import (
+	"iter"
+
+	"github.com/caravan/db/relation"
+	"github.com/caravan/db/transaction"
+	"github.com/caravan/db/value"
+)
+
+// Seq adapts a transaction.Iterator so that it can be used in a range
+// statement
+func Seq(it transaction.Iterator) iter.Seq2[value.Key, any] {
+	return func(yield func(value.Key, any) bool) {
+		for k, v, next, ok := it(); ok; k, v, next, ok = next() {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// FromSeq adapts a sequence to a transaction.Iterator. Because an Iterator
+// is stateless and can be resumed from any of its steps, the sequence's
+// pairs are gathered up front
+func FromSeq(seq iter.Seq2[value.Key, any]) transaction.Iterator {
+	var keys []value.Key
+	var values []any
+	for k, v := range seq {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	return fromSlices(keys, values)
+}
+
+// Rows adapts a transaction.Iterator over a Table's Rows to a typed
+// sequence
+func Rows(it transaction.Iterator) iter.Seq2[value.Key, relation.Row] {
+	return func(yield func(value.Key, relation.Row) bool) {
+		for k, v, next, ok := it(); ok; k, v, next, ok = next() {
+			if !yield(k, v.(relation.Row)) {
+				return
+			}
+		}
+	}
+}
+
+func fromSlices(keys []value.Key, values []any) transaction.Iterator {
+	return func() (value.Key, any, transaction.Iterator, bool) {
+		if len(keys) == 0 {
+			return nil, nil, nil, false
+		}
+		return keys[0], values[0], fromSlices(keys[1:], values[1:]), true
+	}
+}
@@ -0,0 +1,53 @@
+package value
+
+// Native is the set of Go types that are stored as a Value
+type Native interface {
+	[]byte | bool | string | int64 | float64
+}
+
+// Of returns the Value that stores the provided Go value
+func Of[T Native](v T) Value {
+	switch v := any(v).(type) {
+	case []byte:
+		return Key(v)
+	case bool:
+		return Bool(v)
+	case string:
+		return String(v)
+	case int64:
+		return Integer(v)
+	default:
+		return Float(any(v).(float64))
+	}
+}
+
+// As returns the Go value stored by a Value. If the Value is null or
+// stores a different type, the zero value of T is returned along with
+// false
+func As[T Native](v Value) (T, bool) {
+	var res T
+	var ok bool
+	switch p := any(&res).(type) {
+	case *[]byte:
+		var k Key
+		k, ok = v.(Key)
+		*p = k
+	case *bool:
+		var b Bool
+		b, ok = v.(Bool)
+		*p = bool(b)
+	case *string:
+		var s String
+		s, ok = v.(String)
+		*p = string(s)
+	case *int64:
+		var i Integer
+		i, ok = v.(Integer)
+		*p = int64(i)
+	case *float64:
+		var f Float
+		f, ok = v.(Float)
+		*p = float64(f)
+	}
+	return res, ok
+}
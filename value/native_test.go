@@ -0,0 +1,50 @@
+package value_test
+
+import (
+	"testing"
+
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOf(t *testing.T) {
+	as := assert.New(t)
+
+	as.Equal(value.Key{1, 2}, value.Of([]byte{1, 2}))
+	as.Equal(value.Bool(true), value.Of(true))
+	as.Equal(value.String("hello"), value.Of("hello"))
+	as.Equal(value.Integer(42), value.Of(int64(42)))
+	as.Equal(value.Float(4.2), value.Of(4.2))
+}
+
+func TestAs(t *testing.T) {
+	as := assert.New(t)
+
+	i, ok := value.As[int64](value.Integer(42))
+	as.True(ok)
+	as.Equal(int64(42), i)
+
+	s, ok := value.As[string](value.String("hello"))
+	as.True(ok)
+	as.Equal("hello", s)
+
+	b, ok := value.As[bool](value.Bool(true))
+	as.True(ok)
+	as.True(b)
+
+	f, ok := value.As[float64](value.Float(4.2))
+	as.True(ok)
+	as.Equal(4.2, f)
+
+	k, ok := value.As[[]byte](value.Key{1, 2})
+	as.True(ok)
+	as.Equal([]byte{1, 2}, k)
+
+	i, ok = value.As[int64](value.String("42"))
+	as.False(ok)
+	as.Equal(int64(0), i)
+
+	s, ok = value.As[string](nil)
+	as.False(ok)
+	as.Equal("", s)
+}
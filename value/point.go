@@ -0,0 +1,72 @@
+package value
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// Point is a Value that represents a stored geographic location, in
+// degrees of latitude and longitude
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// Compare returns a Comparison between this Point and another Value.
+// Points are ordered by their Bytes, which follow a Z-order curve
+func (l Point) Compare(r Value) Comparison {
+	if r, ok := r.(Point); ok {
+		switch bytes.Compare(l.Bytes(), r.Bytes()) {
+		case 0:
+			return EqualTo
+		case -1:
+			return LessThan
+		default:
+			return GreaterThan
+		}
+	}
+	return Incomparable
+}
+
+// Bytes returns a byte-array representation of this Point. The first 8
+// bytes hold its Code, so that Points that are close to each other tend
+// to sort close to each other. The exact latitude and longitude follow
+func (l Point) Bytes() []byte {
+	res := make([]byte, 8, 24)
+	binary.BigEndian.PutUint64(res, l.Code())
+	res = append(res, Float(l.Lat).Bytes()...)
+	return append(res, Float(l.Lon).Bytes()...)
+}
+
+// Cell returns the coordinates of the cell that contains the Point, in a
+// grid that divides the longitude and latitude into 2^32 steps each
+func (l Point) Cell() (x, y uint32) {
+	return gridStep(l.Lon, 180), gridStep(l.Lat, 90)
+}
+
+// Code returns the position of the Point's Cell along a Z-order curve
+func (l Point) Code() uint64 {
+	return CellCode(l.Cell())
+}
+
+// CellCode returns the position of a Cell along a Z-order curve. The bits
+// of its coordinates are interleaved, beginning with the highest bit of x
+func CellCode(x, y uint32) uint64 {
+	return spread(x)<<1 | spread(y)
+}
+
+func gridStep(deg, limit float64) uint32 {
+	step := math.Floor((deg + limit) / (2 * limit) * (1 << 32))
+	return uint32(math.Max(0, math.Min(step, math.MaxUint32)))
+}
+
+// spread moves each bit of a uint32 to twice its position
+func spread(v uint32) uint64 {
+	res := uint64(v)
+	res = (res | res<<16) & 0x0000FFFF0000FFFF
+	res = (res | res<<8) & 0x00FF00FF00FF00FF
+	res = (res | res<<4) & 0x0F0F0F0F0F0F0F0F
+	res = (res | res<<2) & 0x3333333333333333
+	return (res | res<<1) & 0x5555555555555555
+}
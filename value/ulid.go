@@ -0,0 +1,53 @@
+package value
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// ulidState tracks the most recent ULID so that Keys generated within the
+// same millisecond remain in ascending order
+var ulidState struct {
+	sync.Mutex
+	last [16]byte
+}
+
+// NewULID returns a new unique database Key whose ordering follows the
+// time it was generated. The first 6 bytes hold a millisecond timestamp,
+// and the remaining 10 bytes are random. If a Key is generated within the
+// same millisecond as the previous one, or the clock has moved backward,
+// it is the previous Key incremented by one
+func NewULID() Key {
+	return newULID(time.Now())
+}
+
+func newULID(now time.Time) Key {
+	var res [16]byte
+	ms := uint64(now.UnixMilli())
+	binary.BigEndian.PutUint16(res[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(res[2:6], uint32(ms))
+
+	ulidState.Lock()
+	defer ulidState.Unlock()
+	last := ulidState.last
+	if bytes.Compare(res[:6], last[:6]) <= 0 {
+		res = last
+		incrementULID(&res)
+	} else if _, err := rand.Read(res[6:]); err != nil {
+		panic(err)
+	}
+	ulidState.last = res
+	return res[:]
+}
+
+func incrementULID(u *[16]byte) {
+	for i := len(u) - 1; i >= 6; i-- {
+		u[i]++
+		if u[i] != 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package value_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caravan/db/value"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewULID(t *testing.T) {
+	as := assert.New(t)
+
+	k1 := value.NewULID()
+	k2 := value.NewULID()
+	time.Sleep(2 * time.Millisecond)
+	k3 := value.NewULID()
+
+	as.Equal(16, len(k1))
+	as.Equal(value.LessThan, k1.Compare(k2))
+	as.Equal(value.LessThan, k2.Compare(k3))
+	as.NotEqual(k2[:6], k3[:6])
+
+	ms := time.Now().UnixMilli()
+	ts := int64(k3[0])<<40 | int64(k3[1])<<32 | int64(k3[2])<<24 |
+		int64(k3[3])<<16 | int64(k3[4])<<8 | int64(k3[5])
+	as.InDelta(ms, ts, 1000)
+}
+
+func TestULIDOrdering(t *testing.T) {
+	as := assert.New(t)
+
+	prev := value.NewULID()
+	for i := 0; i < 1000; i++ {
+		next := value.NewULID()
+		as.Equal(value.LessThan, prev.Compare(next))
+		prev = next
+	}
+}
@@ -9,7 +9,8 @@ import (
 )
 
 type (
-	// Value is a placeholder for what will eventually be a generic
+	// Value is implemented by every type that can be stored. The Native Go
+	// type a Value stores can be retrieved with As
 	Value interface {
 		Compare(Value) Comparison
 		Bytes() []byte
@@ -153,20 +154,17 @@ func (l Integer) Compare(r Value) Comparison {
 	return Incomparable
 }
 
-// Bytes returns a byte-array representation of this Integer
+// Bytes returns a byte-array representation of this Integer: a sign byte
+// followed by the Integer's big-endian two's complement. The bytes of
+// Integers sort in the same order as the Integers themselves, which Index
+// range lookups depend on
 func (l Integer) Bytes() []byte {
-	var buf bytes.Buffer
-	holder := make([]byte, 8)
+	res := make([]byte, 9)
 	if l >= 0 {
-		buf.WriteByte(1)
-		binary.BigEndian.PutUint64(holder, uint64(l))
-		buf.Write(holder)
-	} else {
-		buf.WriteByte(0)
-		binary.BigEndian.PutUint64(holder, uint64(-l))
-		buf.Write(holder)
+		res[0] = 1
 	}
-	return buf.Bytes()
+	binary.BigEndian.PutUint64(res[1:], uint64(l))
+	return res
 }
 
 // Compare returns a Comparison between this Float and another Value
@@ -184,15 +182,18 @@ func (l Float) Compare(r Value) Comparison {
 	return Incomparable
 }
 
-// Bytes returns a byte-array representation of this Float
+// Bytes returns a byte-array representation of this Float: its big-endian
+// IEEE 754 bits, with the sign bit flipped for positive Floats and every
+// bit flipped for negative ones. The bytes of Floats sort in the same
+// order as the Floats themselves, which Index range lookups depend on
 func (l Float) Bytes() []byte {
-	var buf bytes.Buffer
-	i := int64(l)
-	buf.Write(Integer(i).Bytes())
-
-	u := math.Float64bits(float64(l) - float64(i))
-	holder := make([]byte, 8)
-	binary.BigEndian.PutUint64(holder, u)
-	buf.Write(holder)
-	return buf.Bytes()
+	u := math.Float64bits(float64(l))
+	if u&(1<<63) != 0 {
+		u = ^u
+	} else {
+		u |= 1 << 63
+	}
+	res := make([]byte, 8)
+	binary.BigEndian.PutUint64(res, u)
+	return res
 }
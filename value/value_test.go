@@ -1,6 +1,8 @@
 package value_test
 
 import (
+	"bytes"
+	"math"
 	"testing"
 
 	"github.com/caravan/db/value"
@@ -101,10 +103,12 @@ func TestInteger(t *testing.T) {
 	as.NotEqual(i1, i2)
 	as.NotEqual(b1, b2)
 
-	as.Equal([]byte{0, 0, 0, 0, 0, 0, 0, 0, 1}, b1)
+	as.Equal([]byte{0, 255, 255, 255, 255, 255, 255, 255, 255}, b1)
 	as.Equal([]byte{1, 0, 0, 0, 0, 0, 0, 0, 1}, b2)
 
 	ni := value.Integer(-100)
+	as.Equal(-1, bytes.Compare(ni.Bytes(), b1))
+	as.Equal(-1, bytes.Compare(b1, b2))
 	as.Equal(value.GreaterThan, i1.Compare(ni))
 	as.Equal(value.LessThan, ni.Compare(i1))
 	as.Equal(value.EqualTo, i1.Compare(i1))
@@ -122,10 +126,67 @@ func TestFloat(t *testing.T) {
 
 	as.NotEqual(f1, f2)
 	as.NotEqual(b1, b2)
+	as.Equal([]byte{0xC0, 0x45, 0, 0, 0, 0, 0, 0}, b1)
+	as.Equal([]byte{0x3F, 0xBA, 255, 255, 255, 255, 255, 255},
+		value.Float(-42).Bytes(),
+	)
 
 	nf := value.Float(0)
+	neg := value.Float(-1.5)
+	as.Equal(-1, bytes.Compare(value.Float(-2.5).Bytes(), neg.Bytes()))
+	as.Equal(-1, bytes.Compare(neg.Bytes(), nf.Bytes()))
+	as.Equal(-1, bytes.Compare(nf.Bytes(), b1))
+	as.Equal(-1, bytes.Compare(b1, b2))
 	as.Equal(value.GreaterThan, f1.Compare(nf))
 	as.Equal(value.LessThan, nf.Compare(f1))
 	as.Equal(value.EqualTo, f1.Compare(f1))
 	as.Equal(value.Incomparable, f1.Compare(value.NewKey()))
 }
+
+func TestPoint(t *testing.T) {
+	as := assert.New(t)
+
+	london := value.Point{Lat: 51.5, Lon: -0.12}
+	paris := value.Point{Lat: 48.85, Lon: 2.35}
+	sydney := value.Point{Lat: -33.87, Lon: 151.21}
+
+	as.Equal(24, len(london.Bytes()))
+	as.Equal(value.EqualTo, london.Compare(london))
+	as.Equal(value.Incomparable, london.Compare(value.Float(51.5)))
+	as.Equal(value.LessThan, london.Compare(sydney))
+	as.Equal(value.GreaterThan, paris.Compare(sydney))
+
+	x, y := value.Point{Lat: -90, Lon: -180}.Cell()
+	as.Equal(uint32(0), x)
+	as.Equal(uint32(0), y)
+	x, y = value.Point{Lat: 90, Lon: 180}.Cell()
+	as.Equal(uint32(math.MaxUint32), x)
+	as.Equal(uint32(math.MaxUint32), y)
+	x, y = value.Point{}.Cell()
+	as.Equal(uint32(1<<31), x)
+	as.Equal(uint32(1<<31), y)
+
+	as.Equal(uint64(0b10), value.CellCode(1, 0))
+	as.Equal(uint64(0b01), value.CellCode(0, 1))
+	as.Equal(uint64(0b1101), value.CellCode(2, 3))
+	as.Equal(value.CellCode(london.Cell()), london.Code())
+}
+
+func TestVector(t *testing.T) {
+	as := assert.New(t)
+
+	v1 := value.Vector{1, -2.5, 3}
+	v2 := value.Vector{1, 0, -4}
+	short := value.Vector{1, -2.5}
+
+	as.Equal(12, len(v1.Bytes()))
+	as.Equal(value.EqualTo, v1.Compare(value.Vector{1, -2.5, 3}))
+	as.Equal(value.LessThan, v1.Compare(v2))
+	as.Equal(value.GreaterThan, v2.Compare(v1))
+	as.Equal(value.LessThan, short.Compare(v1))
+	as.Equal(value.GreaterThan, v1.Compare(short))
+	as.Equal(value.Incomparable, v1.Compare(value.Float(1)))
+
+	as.Equal(-1, bytes.Compare(v1.Bytes(), v2.Bytes()))
+	as.Equal(-1, bytes.Compare(short.Bytes(), v1.Bytes()))
+}
@@ -0,0 +1,50 @@
+package value
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Vector is a Value that represents a stored embedding
+type Vector []float32
+
+// Compare returns a Comparison between this Vector and another Value.
+// Vectors are compared element by element, and a Vector that is a prefix
+// of another is less than it
+func (l Vector) Compare(r Value) Comparison {
+	if r, ok := r.(Vector); ok {
+		for i := 0; i < len(l) && i < len(r); i++ {
+			switch {
+			case l[i] < r[i]:
+				return LessThan
+			case l[i] > r[i]:
+				return GreaterThan
+			}
+		}
+		switch {
+		case len(l) == len(r):
+			return EqualTo
+		case len(l) < len(r):
+			return LessThan
+		default:
+			return GreaterThan
+		}
+	}
+	return Incomparable
+}
+
+// Bytes returns a byte-array representation of this Vector. Each element
+// is encoded in 4 bytes that sort in the same order as the elements
+func (l Vector) Bytes() []byte {
+	res := make([]byte, 4*len(l))
+	for i, f := range l {
+		u := math.Float32bits(f)
+		if u&(1<<31) != 0 {
+			u = ^u
+		} else {
+			u |= 1 << 31
+		}
+		binary.BigEndian.PutUint32(res[4*i:], u)
+	}
+	return res
+}
@@ -0,0 +1,157 @@
+// Package vector provides the search interface of vector Indexes, which
+// find the rows whose embedding Vectors are nearest to a query Vector
+package vector
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/caravan/db/index"
+	"github.com/caravan/db/table"
+	"github.com/caravan/db/value"
+)
+
+type (
+	// Metric names the measure of distance between Vectors
+	Metric string
+
+	// Mode names the way a vector Index finds the nearest Vectors
+	Mode string
+
+	// Searcher is implemented by vector Indexes
+	Searcher interface {
+		// Nearest finds up to k rows whose Vectors are nearest to the
+		// query, nearest first. The search is approximate if the Index
+		// uses HNSW Mode
+		Nearest(q value.Vector, k int) Matches
+
+		// Exact finds up to k rows whose Vectors are nearest to the
+		// query, nearest first, by comparing the query to every Vector
+		Exact(q value.Vector, k int) Matches
+	}
+
+	// Match is a row found by a Searcher, identified by its Key. Distance
+	// is measured from the query by the Index's Metric
+	Match struct {
+		Key      value.Key
+		Distance float64
+	}
+
+	// Matches are a set of Match
+	Matches []Match
+)
+
+// Metrics
+const (
+	// Cosine measures one minus the cosine similarity of two Vectors
+	Cosine Metric = "cosine"
+
+	// Euclidean measures the straight-line distance between two Vectors
+	Euclidean Metric = "l2"
+
+	// DotProduct measures the negated dot product of two Vectors, so that
+	// more similar Vectors are nearer
+	DotProduct Metric = "dot"
+)
+
+// Modes
+const (
+	// ExactMode compares the query to every Vector in the Index
+	ExactMode Mode = "exact"
+
+	// HNSWMode searches a Hierarchical Navigable Small World graph of the
+	// Vectors in the Index, trading accuracy for speed
+	HNSWMode Mode = "hnsw"
+)
+
+// index.Options entries of vector Indexes. The Metric defaults to Cosine
+// and the Mode to ExactMode. If Dimensions is provided, every Vector must
+// have that length. Neighbors is the number of links that each Vector
+// keeps in an HNSW graph, and Candidates is the number of candidates that
+// an HNSW search considers
+const (
+	MetricOption     = "metric"
+	ModeOption       = "mode"
+	DimensionsOption = "dimensions"
+	NeighborsOption  = "neighbors"
+	CandidatesOption = "candidates"
+)
+
+// Error messages
+const (
+	ErrUnknownMetric  = "unknown vector metric: %s"
+	ErrUnknownMode    = "unknown vector index mode: %s"
+	ErrNotVectorIndex = "index is not a vector index: %s"
+)
+
+// Search returns the Searcher of a Table's vector Index
+func Search(tbl table.Table, n index.Name) (Searcher, error) {
+	idx, err := tbl.Index(n)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := idx.(Searcher); ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf(ErrNotVectorIndex, n)
+}
+
+// Validate returns an error if the Metric isn't one of the Metrics
+func (m Metric) Validate() error {
+	switch m {
+	case Cosine, Euclidean, DotProduct:
+		return nil
+	default:
+		return fmt.Errorf(ErrUnknownMetric, m)
+	}
+}
+
+// Distance returns the distance between two Vectors, or false if their
+// lengths differ
+func (m Metric) Distance(a, b value.Vector) (float64, bool) {
+	if len(a) != len(b) {
+		return 0, false
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		switch m {
+		case Euclidean:
+			dot += (x - y) * (x - y)
+		default:
+			dot += x * y
+			na += x * x
+			nb += y * y
+		}
+	}
+	switch m {
+	case Euclidean:
+		return math.Sqrt(dot), true
+	case DotProduct:
+		return -dot, true
+	default:
+		if na == 0 || nb == 0 {
+			return 1, true
+		}
+		return 1 - dot/math.Sqrt(na*nb), true
+	}
+}
+
+// Validate returns an error if the Mode isn't one of the Modes
+func (m Mode) Validate() error {
+	switch m {
+	case ExactMode, HNSWMode:
+		return nil
+	default:
+		return fmt.Errorf(ErrUnknownMode, m)
+	}
+}
+
+// Keys returns the Keys of the Matches, in order
+func (m Matches) Keys() []value.Key {
+	res := make([]value.Key, len(m))
+	for i, e := range m {
+		res[i] = e.Key
+	}
+	return res
+}
@@ -0,0 +1,49 @@
+package vector_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/caravan/db/value"
+	"github.com/caravan/db/vector"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	as := assert.New(t)
+
+	a := value.Vector{1, 0}
+	b := value.Vector{0, 2}
+	c := value.Vector{3, 0}
+
+	d, ok := vector.Cosine.Distance(a, b)
+	as.True(ok)
+	as.InDelta(1, d, 1e-9)
+	d, _ = vector.Cosine.Distance(a, c)
+	as.InDelta(0, d, 1e-9)
+	d, _ = vector.Cosine.Distance(a, value.Vector{0, 0})
+	as.Equal(1.0, d)
+
+	d, _ = vector.Euclidean.Distance(b, c)
+	as.InDelta(math.Sqrt(13), d, 1e-9)
+
+	d, _ = vector.DotProduct.Distance(a, c)
+	as.Equal(-3.0, d)
+
+	_, ok = vector.Euclidean.Distance(a, value.Vector{1, 2, 3})
+	as.False(ok)
+}
+
+func TestValidate(t *testing.T) {
+	as := assert.New(t)
+
+	as.Nil(vector.Euclidean.Validate())
+	as.EqualError(vector.Metric("manhattan").Validate(),
+		fmt.Sprintf(vector.ErrUnknownMetric, "manhattan"),
+	)
+	as.Nil(vector.HNSWMode.Validate())
+	as.EqualError(vector.Mode("ivf").Validate(),
+		fmt.Sprintf(vector.ErrUnknownMode, "ivf"),
+	)
+}